@@ -3,7 +3,9 @@ package bot
 import "log"
 
 import "fmt"
+import "sync"
 import "time"
+import "strings"
 
 import "math"
 import "gopkg.in/telegram-bot-api.v4"
@@ -16,13 +18,33 @@ type ownerReminder struct {
 	ownerId budget.OwnerId
 }
 
+// rescheduleQueueSize limits how many owners could wait for rescheduling before handlers block
+const rescheduleQueueSize = 100
+
+// ReminderScheduler updates daily reminder of the owner after its notification time, time zone or wallets have changed
+type ReminderScheduler interface {
+	Reschedule(owner budget.OwnerId)
+}
+
+// DailyReminder is a background handler sending daily reminders which could be rescheduled by other handlers
+type DailyReminder interface {
+	tgbotbase.BackgroundMessageHandler
+	ReminderScheduler
+}
+
 type dailyReminderHandler struct {
 	baseHandler
-	cron tgbotbase.Cron
+	cron       tgbotbase.Cron
+	reschedule chan budget.OwnerId
+
+	jobsLock sync.Mutex
+	jobs     map[budget.OwnerId]*dailyReminderJob // the only job of each owner which is still in effect
 }
 
-func NewDailyReminder(storage budget.Storage) tgbotbase.BackgroundMessageHandler {
-	r := &dailyReminderHandler{}
+func NewDailyReminder(storage budget.Storage) DailyReminder {
+	r := &dailyReminderHandler{
+		reschedule: make(chan budget.OwnerId, rescheduleQueueSize),
+		jobs:       make(map[budget.OwnerId]*dailyReminderJob)}
 	r.storage = storage
 	return r
 }
@@ -36,9 +58,18 @@ func (d *dailyReminderHandler) Name() string {
 }
 
 func (d *dailyReminderHandler) Run() {
-	d.cron = tgbotbase.NewCron()
+	if d.cron == nil {
+		d.cron = tgbotbase.NewCron()
+	}
 	d.initialLoad()
-	// TODO: add channels for add/remove of new reminders
+	for owner := range d.reschedule {
+		d.rescheduleOwner(owner, time.Now())
+	}
+}
+
+// Reschedule queues the owner, so that its reminder is scheduled again using its current settings
+func (d *dailyReminderHandler) Reschedule(owner budget.OwnerId) {
+	d.reschedule <- owner
 }
 
 func (d *dailyReminderHandler) initialLoad() {
@@ -49,57 +80,161 @@ func (d *dailyReminderHandler) initialLoad() {
 	log.Printf("Starting daily reminder using a map of %d wallet owners", len(ownerDataMap))
 
 	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 	for id, data := range ownerDataMap {
 		if data.DailyReminderTime == nil {
 			log.Printf("Owner %d doesn't have reminder settings; it will not be added into notificaiton list", id)
 			continue
 		}
-		reminderTime := startOfDay.Add(*data.DailyReminderTime)
-		if reminderTime.Before(now) {
-			reminderTime = reminderTime.Add(24 * time.Hour)
-		}
-		job := &dailyReminderJob{}
-		job.OutMsgCh = d.OutMsgCh
-		job.storage = d.storage
-		job.ownerID = id
-		job.ownerData = data
-		d.cron.AddJob(reminderTime, job)
+		d.schedule(id, *data.DailyReminderTime, data.Location(), now)
+	}
+}
+
+// rescheduleOwner replaces a job of the owner with the one following its current settings
+func (d *dailyReminderHandler) rescheduleOwner(owner budget.OwnerId, now time.Time) {
+	notifTime, err := d.storage.GetOwnerDailyNotificationTime(owner)
+	if err != nil {
+		log.Printf("Could not get notification time of owner %d, its reminder is kept as is; error: %s", owner, err)
+		return
+	}
+	if notifTime == nil {
+		log.Printf("Daily reminder of owner %d is disabled", owner)
+		d.jobsLock.Lock()
+		delete(d.jobs, owner)
+		d.jobsLock.Unlock()
+		return
+	}
+	d.schedule(owner, *notifTime, budget.OwnerLocation(owner, d.storage), now)
+}
+
+// schedule adds a job sending reminders to the owner; a job added earlier for the owner stops on its next run
+func (d *dailyReminderHandler) schedule(owner budget.OwnerId, notifTime time.Duration, loc *time.Location, now time.Time) {
+	job := &dailyReminderJob{reminder: d}
+	job.OutMsgCh = d.OutMsgCh
+	job.storage = d.storage
+	job.ownerID = owner
+	job.notifTime = notifTime
+	job.location = loc
+
+	d.jobsLock.Lock()
+	d.jobs[owner] = job
+	d.jobsLock.Unlock()
+	d.cron.AddJob(nextReminderTime(now, notifTime, loc), job)
+}
+
+// isActual tells whether the job has not been replaced by rescheduling
+func (d *dailyReminderHandler) isActual(job *dailyReminderJob) bool {
+	d.jobsLock.Lock()
+	defer d.jobsLock.Unlock()
+	return d.jobs[job.ownerID] == job
+}
+
+// rescheduleReminder notifies scheduler about changes of the owner, if there is any
+func rescheduleReminder(scheduler ReminderScheduler, owner budget.OwnerId) {
+	if scheduler != nil {
+		scheduler.Reschedule(owner)
 	}
 }
 
+// nextReminderTime returns the first moment after t when notifTime passes since midnight in location loc
+func nextReminderTime(t time.Time, notifTime time.Duration, loc *time.Location) time.Time {
+	local := t.In(loc)
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	reminderTime := startOfDay.Add(notifTime)
+	if !reminderTime.After(t) {
+		reminderTime = startOfDay.AddDate(0, 0, 1).Add(notifTime)
+	}
+	return reminderTime
+}
+
 type dailyReminderJob struct {
 	baseHandler
+	reminder  *dailyReminderHandler
 	ownerID   budget.OwnerId
-	ownerData budget.OwnerData
+	notifTime time.Duration
+	location  *time.Location
 }
 
 func (job *dailyReminderJob) Do(scheduledWhen time.Time, cron tgbotbase.Cron) {
-	wallet, err := budget.GetWalletForOwner(job.ownerID, false, job.storage)
-	if err != nil {
-		log.Printf("Could not get wallet for owner %d with error: %s", job.ownerID, err)
+	if !job.reminder.isActual(job) {
+		log.Printf("Reminder of owner %d scheduled at %s has been replaced, dropping it", job.ownerID, scheduledWhen)
 		return
 	}
 
+	wallets, err := job.storage.GetOwnerWallets(job.ownerID)
+	if err != nil {
+		log.Printf("Could not get wallets for owner %d with error: %s", job.ownerID, err)
+	}
+
+	for _, wallet := range wallets {
+		wallet.Location = job.location
+		replies := prepareWalletReminder(job.storage, job.ownerID, wallet, wallet.LocalTime(scheduledWhen))
+		for _, txt := range replies {
+			if len(wallets) > 1 {
+				txt = fmt.Sprintf("Wallet '%s': %s", wallet.Name, txt)
+			}
+			job.OutMsgCh <- tgbotapi.NewMessage(int64(job.ownerID), txt)
+		}
+	}
+
+	cron.AddJob(nextReminderTime(scheduledWhen, job.notifTime, job.location), job)
+}
+
+// prepareWalletReminder returns messages about the wallet which should be sent at time t: a monthly summary if a new month
+// has started, notices about retired regular transactions and a daily notification
+func prepareWalletReminder(storage budget.Storage, owner budget.OwnerId, wallet *budget.Wallet, t time.Time) []string {
 	replies := make([]string, 0, 2)
-	if wallet.MonthStart == scheduledWhen.Day() {
-		if reply, err := prepareMonthlySummary(job.ownerID, wallet, scheduledWhen.Add(time.Hour*-24)); err == nil && len(reply) > 0 {
+	if wallet.Period(t).DaysSpent(t) == 0 {
+		if reply, err := prepareMonthlySummary(storage, owner, wallet, t.Add(time.Hour*-24)); err == nil && len(reply) > 0 {
+			if carry, err := describeCarry(wallet, t); err != nil {
+				log.Printf("Could not get leftover carried for wallet '%s' due to error: %s", wallet.ID, err)
+			} else if carry != "" {
+				reply = fmt.Sprintf("%s\n%s", reply, carry)
+			}
 			replies = append(replies, reply)
 		}
 	}
-	if dailyMsgs := prepareDailyNotification(job.ownerID, wallet, scheduledWhen, job.ownerData); len(dailyMsgs) > 0 {
+	retired, err := wallet.RetireFinishedRegularTransactions(t)
+	if err != nil {
+		log.Printf("Could not retire finished regular transactions for wallet '%s' due to error: %s", wallet.ID, err)
+	}
+	for _, tx := range retired {
+		replies = append(replies, fmt.Sprintf("Regular transaction %s labeled '%s' (%s) has ended and has been removed", formatAmount(tx.Value, wallet.Decimals, tx.Currency), tx.Label, tx.Schedule()))
+	}
+	regularTxs, err := storage.GetRegularTransactions(wallet.ID)
+	if err != nil {
+		log.Printf("Could not get regular transactions for wallet '%s' due to error: %s", wallet.ID, err)
+		// let's move forward to complete at least what we have
+	}
+	if dailyMsgs := prepareDailyNotification(owner, wallet, t, regularTxs); len(dailyMsgs) > 0 {
 		replies = append(replies, dailyMsgs...)
 	}
+	return replies
+}
 
-	for _, txt := range replies {
-		job.OutMsgCh <- tgbotapi.NewMessage(int64(job.ownerID), txt)
+// describeCarry tells what has happened with leftover of the month preceding the month of t; empty if nothing has been carried
+func describeCarry(wallet *budget.Wallet, t time.Time) (string, error) {
+	carry, err := wallet.GetCarry(t)
+	if err != nil {
+		return "", err
 	}
-
-	nextNotifTime := scheduledWhen.Add(time.Duration(24) * time.Hour)
-	cron.AddJob(nextNotifTime, job)
+	switch {
+	case carry.ToGoal > 0:
+		policy, err := wallet.GetCarryOver()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Surplus of last month %s has been put aside for goal '%s'", formatAmount(carry.ToGoal, wallet.Decimals, wallet.Currency), policy.Goal), nil
+	case carry.Carried < 0:
+		return fmt.Sprintf("Overspend of last month %s has been carried into this month", formatAmount(-carry.Carried, wallet.Decimals, wallet.Currency)), nil
+	case carry.Carried < carry.Leftover:
+		return fmt.Sprintf("Surplus of last month %s has been carried into this month up to the cap: %s", formatAmount(carry.Leftover, wallet.Decimals, wallet.Currency), formatAmount(carry.Carried, wallet.Decimals, wallet.Currency)), nil
+	case carry.Carried > 0:
+		return fmt.Sprintf("Surplus of last month %s has been carried into this month", formatAmount(carry.Carried, wallet.Decimals, wallet.Currency)), nil
+	}
+	return "", nil
 }
 
-func prepareDailyNotification(owner budget.OwnerId, wallet *budget.Wallet, t time.Time, ownerData budget.OwnerData) []string {
+func prepareDailyNotification(owner budget.OwnerId, wallet *budget.Wallet, t time.Time, regularTxs []budget.RegularTransaction) []string {
 	log.Printf("Preparing daily available balance to owner %d with wallet '%s'", owner, wallet.ID)
 	msgs := make([]string, 0, 3)
 	availMoney, err := wallet.GetBalance(t)
@@ -107,26 +242,35 @@ func prepareDailyNotification(owner budget.OwnerId, wallet *budget.Wallet, t tim
 		log.Printf("Could not get balance for wallet '%s' due to error: %s", wallet.ID, err)
 		return msgs
 	}
-	monthSplit := budget.SplitWalletMonth(t, wallet.MonthStart)
-	msgs = append(msgs, fmt.Sprintf("New day has come! Currently available money: %d; there are %d days till month end", availMoney, monthSplit.DaysRemaining))
+	period := wallet.Period(t)
+	msgs = append(msgs, fmt.Sprintf("New day has come! Currently available money: %s; there are %d days till month end", formatAmount(availMoney, wallet.Decimals, wallet.Currency), period.DaysRemaining(t)))
+	if goals, err := describeGoals(wallet, t); err != nil {
+		log.Printf("Could not get goals for wallet '%s' due to error: %s", wallet.ID, err)
+	} else if len(goals) > 0 {
+		msgs = append(msgs, "Savings goals:\n"+strings.Join(goals, "\n"))
+	}
 	if availMoney < 0 {
 		// TODO: consider not only planned, but 'actual' income for current month
-		plannedIncome, err := wallet.GetPlannedMonthlyIncome()
+		plannedIncome, err := wallet.GetPlannedMonthlyIncome(t)
 		if err != nil {
 			log.Printf("Could not get planned income for wallet '%s' due to error: %s", wallet.ID, err)
 			return msgs
 		}
-		plannedDailyIncome := plannedIncome / monthSplit.DaysInCurMonth
+		plannedDailyIncome := plannedIncome / period.Days()
 		daysTillPositive := int(math.Ceil(math.Abs(float64(availMoney) / float64(plannedDailyIncome))))
-		msgs = append(msgs, fmt.Sprintf("In order to make positive balance with current daily income %d, you should not spend any money for %d days", plannedDailyIncome, daysTillPositive))
+		msgs = append(msgs, fmt.Sprintf("In order to make positive balance with current daily income %s, you should not spend any money for %d days", formatAmount(plannedDailyIncome, wallet.Decimals, wallet.Currency), daysTillPositive))
 	}
 
-	log.Printf("Checking and sending reminders for regular transactions for current day for owner %d with wallet '%s' (has %d dates for reminding)", owner, wallet.ID, len(ownerData.RegularTxs))
-	if txs, found := ownerData.RegularTxs[t.Day()]; found {
-		msg := fmt.Sprintf("You have the following regular transactions to be fulfilled today:")
-		for _, tx := range txs {
-			msg = fmt.Sprintf("%s\n%d labeled by '%s'", msg, tx.Value, tx.Label)
+	log.Printf("Checking and sending reminders for regular transactions for current day for owner %d with wallet '%s' (has %d regular transactions)", owner, wallet.ID, len(regularTxs))
+	msg := fmt.Sprintf("You have the following regular transactions to be fulfilled today:")
+	found := false
+	for _, tx := range regularTxs {
+		if tx.DueOn(t) {
+			msg = fmt.Sprintf("%s\n%s labeled by '%s'", msg, formatAmount(tx.Value, wallet.Decimals, tx.Currency), tx.Label)
+			found = true
 		}
+	}
+	if found {
 		msgs = append(msgs, msg)
 	}
 
@@ -44,3 +44,141 @@ func TestOneReminder(t *testing.T) {
 	}
 }
 */
+
+import "time"
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+func TestNextReminderTime(t *testing.T) {
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati") // UTC+14
+	if err != nil {
+		t.FailNow()
+	}
+	pagoPago, err := time.LoadLocation("Pacific/Pago_Pago") // UTC-11
+	if err != nil {
+		t.FailNow()
+	}
+	now := time.Date(2018, 6, 30, 20, 0, 0, 0, time.UTC)
+	cases := []struct {
+		loc      *time.Location
+		expected time.Time
+	}{
+		{time.UTC, time.Date(2018, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{kiritimati, time.Date(2018, 7, 2, 9, 0, 0, 0, kiritimati)}, // it is 10:00 of July 1 there, reminder has been sent today
+		{pagoPago, time.Date(2018, 6, 30, 9, 0, 0, 0, pagoPago)},    // it is 9:00 of June 30 there, reminder is due right now
+	}
+	for _, c := range cases {
+		after := now
+		if c.loc == pagoPago {
+			after = now.Add(-time.Second)
+		}
+		if next := nextReminderTime(after, 9*time.Hour, c.loc); !next.Equal(c.expected) {
+			t.Errorf("Next reminder in %s is at %s instead of %s", c.loc, next, c.expected)
+		}
+	}
+	if next := nextReminderTime(now, 9*time.Hour, kiritimati); !nextReminderTime(next, 9*time.Hour, kiritimati).Equal(next.AddDate(0, 0, 1)) {
+		t.Errorf("Reminders must be sent daily")
+	}
+}
+
+func TestMonthlySummaryCarry(t *testing.T) {
+	s := budget.NewRamStorage()
+	w, err := s.CreateWalletOwner(budget.OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*budget.NewRegularTransaction(310000, 1, "salary")); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*budget.NewActualTransaction(-60000, time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), "", "")); err != nil {
+		t.FailNow()
+	}
+	if err := w.SetCarryOver(budget.CarryOver{Mode: budget.CarryOverCapped, Cap: 100000}, time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.FailNow()
+	}
+
+	replies := prepareWalletReminder(s, budget.OwnerId(1), w, time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC))
+	if len(replies) == 0 || !strings.HasSuffix(replies[0], "\nSurplus of last month 2500.00 has been carried into this month up to the cap: 1000.00") {
+		t.Errorf("Monthly summary does not state what has been carried: %v", replies)
+	}
+	if replies := prepareWalletReminder(s, budget.OwnerId(1), w, time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)); strings.Contains(strings.Join(replies, "\n"), "carried") {
+		t.Errorf("Carry is reported in the middle of a month: %v", replies)
+	}
+}
+
+type scheduledJob struct {
+	t   time.Time
+	job tgbotbase.CronJob
+}
+
+// testCron passes added jobs to the test instead of running them
+type testCron struct {
+	added chan scheduledJob
+}
+
+func (c *testCron) AddJob(t time.Time, job tgbotbase.CronJob) {
+	c.added <- scheduledJob{t: t, job: job}
+}
+
+func receiveJob(t *testing.T, c *testCron) scheduledJob {
+	select {
+	case j := <-c.added:
+		return j
+	case <-time.After(time.Second):
+		t.Fatalf("No reminder has been scheduled")
+	}
+	return scheduledJob{}
+}
+
+func TestDailyReminderReschedule(t *testing.T) {
+	s := budget.NewRamStorage()
+	if _, err := s.CreateWalletOwner(budget.OwnerId(1)); err != nil {
+		t.FailNow()
+	}
+	notifTime := 9 * time.Hour
+	if err := s.SetOwnerDailyNotificationTime(budget.OwnerId(1), &notifTime); err != nil {
+		t.FailNow()
+	}
+
+	outCh := make(chan tgbotapi.Chattable, 10)
+	cron := &testCron{added: make(chan scheduledJob, 10)}
+	reminder := NewDailyReminder(s)
+	reminder.(*dailyReminderHandler).cron = cron
+	reminder.Init(outCh, nil)
+	settings := NewWalletSettingsHandler(s, nil, reminder)
+	settings.Init(outCh, nil)
+	go reminder.Run()
+
+	first := receiveJob(t, cron)
+	if first.t.Location() != time.UTC || first.t.Hour() != 9 || first.t.Minute() != 0 {
+		t.Errorf("Unexpected first reminder time %s", first.t)
+	}
+
+	settings.HandleOne(newTestCommand(1, "/settings tz Asia/Tokyo"))
+	<-outCh
+	now := time.Now()
+	second := receiveJob(t, cron)
+	if second.t.Location().String() != "Asia/Tokyo" || second.t.Hour() != 9 || second.t.Minute() != 0 || !second.t.After(now) || second.t.Sub(now) > 24*time.Hour {
+		t.Errorf("Unexpected reminder time %s after time zone change", second.t)
+	}
+
+	// replaced job neither sends reminders nor schedules itself again
+	first.job.Do(first.t, cron)
+	select {
+	case msg := <-outCh:
+		t.Errorf("Replaced reminder has been sent: %+v", msg)
+	case j := <-cron.added:
+		t.Errorf("Replaced reminder has been scheduled at %s", j.t)
+	default:
+	}
+
+	settings.HandleOne(newTestCommand(1, "/settings notifTime 21:30"))
+	<-outCh
+	if third := receiveJob(t, cron); third.t.Location().String() != "Asia/Tokyo" || third.t.Hour() != 21 || third.t.Minute() != 30 {
+		t.Errorf("Unexpected reminder time %s after notification time change", third.t)
+	}
+}
@@ -0,0 +1,148 @@
+package bot
+
+import "log"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbotbase"
+
+// handlerQueueSize is how many updates could wait for a busy handler before the dispatcher blocks
+const handlerQueueSize = 100
+
+// Sender delivers messages to Telegram; it is implemented by tgbotapi.BotAPI
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	AnswerCallbackQuery(config tgbotapi.CallbackConfig) (tgbotapi.APIResponse, error)
+}
+
+// dispatchedHandler processes updates matching its trigger in its own goroutine like tgbotbase dealers do,
+// so a slow handler delays neither the others nor receiving of updates
+type dispatchedHandler struct {
+	handler tgbotbase.IncomingMessageHandler
+	trigger tgbotbase.HandlerTrigger
+	updates chan tgbotapi.Update
+}
+
+func newDispatchedHandler(h tgbotbase.IncomingMessageHandler, outMsgCh chan<- tgbotapi.Chattable) *dispatchedHandler {
+	d := &dispatchedHandler{handler: h, trigger: h.Init(outMsgCh, nil), updates: make(chan tgbotapi.Update, handlerQueueSize)}
+	go d.run()
+	return d
+}
+
+func (d *dispatchedHandler) run() {
+	for update := range d.updates {
+		switch {
+		case update.Message != nil:
+			d.handler.HandleOne(*update.Message)
+		case update.EditedMessage != nil:
+			d.handler.HandleOne(*update.EditedMessage)
+		case update.CallbackQuery != nil:
+			d.handler.(CallbackQueryHandler).HandleCallback(*update.CallbackQuery)
+		}
+	}
+}
+
+// Dispatcher delivers Telegram updates to handlers. Unlike tgbotbase.Bot, which passes only new messages,
+// it passes edited messages to handlers registered for them and presses of inline buttons to handlers which process them.
+// Each handler gets updates one by one in order of arrival
+type Dispatcher struct {
+	sender     Sender
+	outMsgCh   chan tgbotapi.Chattable
+	handlers   []*dispatchedHandler
+	edited     []*dispatchedHandler
+	callbacks  []*dispatchedHandler
+	background []tgbotbase.BackgroundMessageHandler
+}
+
+func NewDispatcher(sender Sender) *Dispatcher {
+	return &Dispatcher{sender: sender, outMsgCh: make(chan tgbotapi.Chattable, 100)}
+}
+
+// AddHandler registers a handler of new messages matching its trigger; presses of buttons are passed to it as well if it is a CallbackQueryHandler
+func (d *Dispatcher) AddHandler(h tgbotbase.IncomingMessageHandler) {
+	dispatched := newDispatchedHandler(h, d.outMsgCh)
+	d.handlers = append(d.handlers, dispatched)
+	if _, ok := h.(CallbackQueryHandler); ok {
+		d.callbacks = append(d.callbacks, dispatched)
+	}
+	log.Printf("Handler '%s' has been added", h.Name())
+}
+
+// AddEditedMessageHandler registers a handler of edited messages matching its trigger
+func (d *Dispatcher) AddEditedMessageHandler(h tgbotbase.IncomingMessageHandler) {
+	d.edited = append(d.edited, newDispatchedHandler(h, d.outMsgCh))
+	log.Printf("Handler '%s' has been added for edited messages", h.Name())
+}
+
+// AddBackgroundHandler registers a handler which sends messages on its own, it is run once the dispatcher starts
+func (d *Dispatcher) AddBackgroundHandler(h tgbotbase.BackgroundMessageHandler) {
+	h.Init(d.outMsgCh, nil)
+	d.background = append(d.background, h)
+	log.Printf("Background handler '%s' has been added", h.Name())
+}
+
+// Start runs background handlers and dispatches updates till the channel is closed
+func (d *Dispatcher) Start(updates <-chan tgbotapi.Update) {
+	go d.send()
+	for _, h := range d.background {
+		go h.Run()
+	}
+	for update := range updates {
+		d.Dispatch(update)
+	}
+}
+
+// Dispatch queues a single update to the handlers it is meant for
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) {
+	switch {
+	case update.Message != nil:
+		dispatchMessage(d.handlers, *update.Message, update)
+	case update.EditedMessage != nil:
+		dispatchMessage(d.edited, *update.EditedMessage, update)
+	case update.CallbackQuery != nil:
+		d.dispatchCallback(update)
+	}
+}
+
+// dispatchCallback passes the query to all callback handlers, each of them checks data of the query on its own;
+// the query is answered at once as Telegram clients show progress on the button till then
+func (d *Dispatcher) dispatchCallback(update tgbotapi.Update) {
+	for _, h := range d.callbacks {
+		h.updates <- update
+	}
+	go d.answerCallback(update.CallbackQuery.ID)
+}
+
+func (d *Dispatcher) answerCallback(id string) {
+	if _, err := d.sender.AnswerCallbackQuery(tgbotapi.NewCallback(id, "")); err != nil {
+		log.Printf("Could not answer callback query '%s' due to error: %s", id, err)
+	}
+}
+
+func dispatchMessage(handlers []*dispatchedHandler, msg tgbotapi.Message, update tgbotapi.Update) {
+	for _, h := range handlers {
+		if triggerMatches(h.trigger, msg) {
+			h.updates <- update
+		}
+	}
+}
+
+// triggerMatches checks whether msg is either one of commands of trigger or a text matching its expression
+func triggerMatches(trigger tgbotbase.HandlerTrigger, msg tgbotapi.Message) bool {
+	if msg.IsCommand() {
+		for _, cmd := range trigger.Cmds {
+			if msg.Command() == cmd {
+				return true
+			}
+		}
+		return false
+	}
+	return trigger.Re != nil && trigger.Re.MatchString(msg.Text)
+}
+
+func (d *Dispatcher) send() {
+	for c := range d.outMsgCh {
+		if _, err := d.sender.Send(c); err != nil {
+			log.Printf("Could not send a message due to error: %s", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package bot
+
+import "time"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+type testSender struct {
+	answered chan string
+}
+
+func (s *testSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return tgbotapi.Message{}, nil
+}
+
+func (s *testSender) AnswerCallbackQuery(config tgbotapi.CallbackConfig) (tgbotapi.APIResponse, error) {
+	s.answered <- config.CallbackQueryID
+	return tgbotapi.APIResponse{Ok: true}, nil
+}
+
+// receive waits for a message sent by one of handlers of the dispatcher
+func receive(t *testing.T, d *Dispatcher) tgbotapi.Chattable {
+	select {
+	case c := <-d.outMsgCh:
+		return c
+	case <-time.After(time.Second):
+		t.Fatalf("No message has been sent by handlers")
+	}
+	return nil
+}
+
+// expectSilence fails if handlers of the dispatcher send anything shortly
+func expectSilence(t *testing.T, d *Dispatcher) {
+	select {
+	case c := <-d.outMsgCh:
+		t.Errorf("Unexpected message: %+v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_EditedMessages(t *testing.T) {
+	s := budget.NewRamStorage()
+	d := NewDispatcher(&testSender{})
+	d.AddHandler(NewTransactionHandler(s))
+	d.AddHandler(NewStatsHandler(s))
+	d.AddEditedMessageHandler(NewEditedMessageHandler(s))
+
+	msg := newTestMessage(1, 7, "500 #food", false)
+	d.Dispatch(tgbotapi.Update{Message: &msg})
+	receive(t, d)
+	w, err := s.GetWalletForOwner(budget.OwnerId(1), false)
+	if err != nil {
+		t.FailNow()
+	}
+
+	edited := newTestMessage(1, 7, "50 #food", true)
+	d.Dispatch(tgbotapi.Update{EditedMessage: &edited})
+	receive(t, d)
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 7); err != nil || tx.RawText != "50 #food" {
+		t.Errorf("Transaction has not been amended after message edit: %+v", tx)
+	}
+	now := time.Now()
+	if txs, _ := s.GetActualTransactions(w.ID, now.Add(-time.Hour), now); len(txs) != 1 {
+		t.Errorf("Unexpected transactions after edit: %+v", txs)
+	}
+
+	// commands are delivered by their names only
+	cmd := newTestMessage(1, 8, "/stats", false)
+	cmd.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(cmd.Text)}}
+	d.Dispatch(tgbotapi.Update{Message: &cmd})
+	if _, ok := receive(t, d).(tgbotapi.MessageConfig); !ok {
+		t.Errorf("Stats have not been sent")
+	}
+	expectSilence(t, d)
+}
+
+func TestDispatcher_CallbackQueries(t *testing.T) {
+	s := budget.NewRamStorage()
+	if _, err := s.CreateWalletOwner(budget.OwnerId(1)); err != nil {
+		t.FailNow()
+	}
+	sender := &testSender{answered: make(chan string, 2)}
+	d := NewDispatcher(sender)
+	d.AddHandler(NewTransactionHandler(s))
+	d.AddHandler(NewStatsHandler(s))
+
+	cmd := newTestCommand(1, "/stats prev")
+	d.Dispatch(tgbotapi.Update{Message: &cmd})
+	reply := receive(t, d).(tgbotapi.MessageConfig)
+	keyboard := reply.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+
+	msg := newTestMessage(1, 42, reply.Text, false)
+	d.Dispatch(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{ID: "cb1", Message: &msg, Data: *keyboard.InlineKeyboard[0][0].CallbackData}})
+	if edit, ok := receive(t, d).(tgbotapi.EditMessageTextConfig); !ok || edit.MessageID != 42 {
+		t.Errorf("Summary has not been edited after button press: %+v", edit)
+	}
+	if id := <-sender.answered; id != "cb1" {
+		t.Errorf("Callback query has not been answered, got answer for '%s'", id)
+	}
+
+	// queries of unknown buttons are answered as well
+	d.Dispatch(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{ID: "cb2", Message: &msg, Data: "unknown"}})
+	if id := <-sender.answered; id != "cb2" {
+		t.Errorf("Unknown callback query has not been answered, got answer for '%s'", id)
+	}
+	expectSilence(t, d)
+}
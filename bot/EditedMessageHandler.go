@@ -0,0 +1,76 @@
+package bot
+
+import "log"
+import "fmt"
+import "regexp"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+// anything could be edited, including transaction turned into a non-transaction text
+var anyTextRe *regexp.Regexp = regexp.MustCompile("(?s).*")
+
+// editedMessageHandler keeps transactions in sync with edited telegram messages they have been parsed from
+type editedMessageHandler struct {
+	baseHandler
+}
+
+func NewEditedMessageHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
+	h := &editedMessageHandler{}
+	h.storage = storage
+	return h
+}
+
+func (h *editedMessageHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(anyTextRe, nil)
+}
+
+func (h *editedMessageHandler) Name() string {
+	return "edited message"
+}
+
+func (h *editedMessageHandler) HandleOne(msg tgbotapi.Message) {
+	if msg.EditDate == 0 {
+		return // new messages are handled elsewhere
+	}
+	log.Printf("Edited message %d received from %s; text: %s", msg.MessageID, dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+
+	// transaction could be addressed to any wallet of the owner via '@name'
+	wallets, err := h.storage.GetOwnerWallets(budget.OwnerId(chatId))
+	if err != nil {
+		log.Printf("Could not get wallets for %s, edited message is ignored: %s", dumpMsgUserInfo(msg), err)
+		return
+	}
+	var wallet *budget.Wallet
+	var transaction *budget.ActualTransaction
+	for _, w := range wallets {
+		if transaction, err = h.storage.GetActualTransactionByMessage(w.ID, chatId, msg.MessageID); err == nil {
+			wallet = w
+			break
+		}
+	}
+	if wallet == nil {
+		log.Printf("Edited message %d is not linked to any transaction of %s", msg.MessageID, dumpMsgUserInfo(msg))
+		return
+	}
+	wallet.Location = budget.OwnerLocation(budget.OwnerId(chatId), h.storage)
+	text, _ := splitWalletOverride(msg.Text)
+
+	var replyMsg string
+	if _, _, _, parseErr := parseTransactionText(text, wallet.Decimals); parseErr != nil {
+		log.Printf("Edited message %d does not contain a transaction anymore, removing transaction '%s'", msg.MessageID, transaction.ID)
+		replyMsg, err = deleteTransaction(wallet, *transaction)
+	} else {
+		replyMsg, err = amendTransaction(wallet, *transaction, text)
+	}
+	if err != nil {
+		log.Printf("Could not apply edited message %d to transaction '%s' in wallet '%s' due to error: %s", msg.MessageID, transaction.ID, wallet.ID, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not apply your edit to the transaction: %s", err))
+		return
+	}
+
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("%s\n%s", replyMsg, constructBalanceMessage(wallet)))
+}
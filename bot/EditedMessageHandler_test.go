@@ -0,0 +1,51 @@
+package bot
+
+import "time"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func newTestMessage(chatId int64, messageId int, text string, edited bool) tgbotapi.Message {
+	msg := tgbotapi.Message{
+		MessageID: messageId,
+		Chat:      &tgbotapi.Chat{ID: chatId},
+		From:      &tgbotapi.User{ID: int(chatId)},
+		Text:      text}
+	if edited {
+		msg.EditDate = int(time.Now().Unix())
+	}
+	return msg
+}
+
+func TestEditedMessageHandler(t *testing.T) {
+	s := budget.NewRamStorage()
+	outCh := make(chan tgbotapi.Chattable, 10)
+	transactions := NewTransactionHandler(s)
+	transactions.Init(outCh, nil)
+	edits := NewEditedMessageHandler(s)
+	edits.Init(outCh, nil)
+
+	transactions.HandleOne(newTestMessage(1, 7, "500 #food", false))
+	edits.HandleOne(newTestMessage(1, 7, "500 #food", false)) // not an edit, must be ignored
+	w, err := s.GetWalletForOwner(budget.OwnerId(1), false)
+	if err != nil {
+		t.FailNow()
+	}
+
+	edits.HandleOne(newTestMessage(1, 7, "50 #food", true))
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 7); err != nil || tx.Value != -5000 || tx.RawText != "50 #food" {
+		t.Errorf("Transaction has not been amended after message edit: %+v", tx)
+	}
+
+	transactions.HandleOne(newTestMessage(1, 7, "70 #food", true)) // edits must not add new transactions
+	now := time.Now()
+	if txs, _ := s.GetActualTransactions(w.ID, now.Add(-time.Hour), now); len(txs) != 1 {
+		t.Errorf("Unexpected transactions after edit: %+v", txs)
+	}
+
+	edits.HandleOne(newTestMessage(1, 7, "oops, not a transaction", true))
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 7); err == nil {
+		t.Errorf("Transaction has not been removed after its message became unparseable: %+v", tx)
+	}
+}
@@ -0,0 +1,163 @@
+package bot
+
+import "log"
+import "fmt"
+import "time"
+import "regexp"
+import "strconv"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const goalCmd = "goal"
+
+var goalTargetRe *regexp.Regexp = regexp.MustCompile("^new (\\S+) (\\d+(?:[.,]\\d+)?) by (\\S+)$")
+var goalPercentRe *regexp.Regexp = regexp.MustCompile("^new (\\S+) (\\d{1,3})%$")
+var goalTopUpRe *regexp.Regexp = regexp.MustCompile("^topup (\\S+) (\\d+(?:[.,]\\d+)?)$")
+var goalCloseRe *regexp.Regexp = regexp.MustCompile("^close (\\S+)$")
+
+const goalUsage = "Usage: '/" + goalCmd + " new bike 30000 by march' (or 'by 2027-03-15'), '/" + goalCmd + " new reserve 10%', " +
+	"'/" + goalCmd + " topup bike 500', '/" + goalCmd + " close bike' or '/" + goalCmd + " list'"
+
+// goalHandler manages savings goals of active wallet: money for them is put aside every day and is not available for spending
+type goalHandler struct {
+	baseHandler
+	admins ChatAdminChecker
+}
+
+func NewGoalHandler(storage budget.Storage, admins ChatAdminChecker) tgbotbase.IncomingMessageHandler {
+	h := &goalHandler{admins: admins}
+	h.storage = storage
+	return h
+}
+
+func (h *goalHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{goalCmd})
+}
+
+func (h *goalHandler) Name() string {
+	return "savings goals"
+}
+
+func (h *goalHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Goal request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	args := strings.Join(strings.Fields(msg.CommandArguments()), " ")
+
+	wallet, err := budget.GetWalletForOwner(budget.OwnerId(chatId), true, h.storage)
+	if err != nil {
+		log.Printf("Could not get wallet for %s due to error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not process /%s: %s", goalCmd, err))
+		return
+	}
+
+	var reply string
+	if args == "" || args == "list" {
+		reply, err = h.list(wallet)
+	} else if err = checkChangeAllowed(h.storage, h.admins, msg); err == nil {
+		reply, err = h.change(wallet, args)
+	}
+	if err != nil {
+		log.Printf("Could not process goal request of %s due to error: %s", dumpMsgUserInfo(msg), err)
+		reply = fmt.Sprintf("Could not process /%s: %s", goalCmd, err)
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, reply)
+}
+
+func (h *goalHandler) list(wallet *budget.Wallet) (string, error) {
+	lines, err := describeGoals(wallet, wallet.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf("There are no goals yet. %s", goalUsage), nil
+	}
+	return "Your goals:\n" + strings.Join(lines, "\n"), nil
+}
+
+func (h *goalHandler) change(wallet *budget.Wallet, args string) (string, error) {
+	now := wallet.Now()
+	if matches := goalTargetRe.FindStringSubmatch(args); len(matches) > 0 {
+		target, err := budget.ParseAmount(matches[2], wallet.Decimals)
+		if err != nil {
+			return "", err
+		}
+		deadline, err := parseDeadline(matches[3], now)
+		if err != nil {
+			return "", err
+		}
+		if err := wallet.AddGoal(*budget.NewTargetGoal(matches[1], target, now, deadline)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Goal '%s' has been created, money for it is put aside every day till %s\n%s", matches[1], deadline.Format("2006-01-02"), constructBalanceMessage(wallet)), nil
+	}
+	if matches := goalPercentRe.FindStringSubmatch(args); len(matches) > 0 {
+		percent, _ := strconv.Atoi(matches[2]) // digits are guaranteed by the expression
+		if err := wallet.AddGoal(*budget.NewPercentGoal(matches[1], percent, now)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Goal '%s' has been created, %d%% of income is put aside for it\n%s", matches[1], percent, constructBalanceMessage(wallet)), nil
+	}
+	if matches := goalTopUpRe.FindStringSubmatch(args); len(matches) > 0 {
+		amount, err := budget.ParseAmount(matches[2], wallet.Decimals)
+		if err != nil {
+			return "", err
+		}
+		if err := wallet.TopUpGoal(matches[1], amount, now); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Goal '%s' has been topped up by %s\n%s", matches[1], formatAmount(amount, wallet.Decimals, wallet.Currency), constructBalanceMessage(wallet)), nil
+	}
+	if matches := goalCloseRe.FindStringSubmatch(args); len(matches) > 0 {
+		if _, err := wallet.CloseGoal(matches[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Goal '%s' has been closed\n%s", matches[1], constructBalanceMessage(wallet)), nil
+	}
+	return goalUsage, nil
+}
+
+// parseDeadline accepts a date like '2027-03-15', a month like '2027-03' or a month name like 'march';
+// the last day of a month is used as the deadline, a month name means its nearest occurrence not earlier than now
+func parseDeadline(text string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", text); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01", text); err == nil {
+		return t.AddDate(0, 1, -1), nil
+	}
+	text = strings.ToLower(text)
+	for month := time.January; month <= time.December; month++ {
+		name := strings.ToLower(month.String())
+		if text != name && text != name[:3] {
+			continue
+		}
+		year := now.Year()
+		if month < now.Month() {
+			year++
+		}
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("Deadline '%s' should be a date like 2027-03-15, a month like 2027-03 or a month name", text)
+}
+
+// describeGoals returns a progress line for every goal of the wallet at time t
+func describeGoals(wallet *budget.Wallet, t time.Time) ([]string, error) {
+	statuses, err := wallet.GetGoalStatuses(t)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(statuses))
+	for _, g := range statuses {
+		if g.Percent > 0 {
+			lines = append(lines, fmt.Sprintf("%s (%d%% of income): %s put aside this month", g.Name, g.Percent, formatAmount(g.Saved, wallet.Decimals, wallet.Currency)))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s of %s saved (%d%%) by %s", g.Name, formatAmount(g.Saved, wallet.Decimals, wallet.Currency),
+			formatAmount(g.Target, wallet.Decimals, wallet.Currency), g.Saved*100/g.Target, g.Deadline.Format("2006-01-02")))
+	}
+	return lines, nil
+}
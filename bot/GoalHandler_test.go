@@ -0,0 +1,52 @@
+package bot
+
+import "time"
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2027-03-15": time.Date(2027, 3, 15, 0, 0, 0, 0, time.UTC),
+		"2027-02":    time.Date(2027, 2, 28, 0, 0, 0, 0, time.UTC),
+		"march":      time.Date(2027, 3, 31, 0, 0, 0, 0, time.UTC),
+		"Dec":        time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		"october":    time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for text, expected := range cases {
+		if deadline, err := parseDeadline(text, now); err != nil || !deadline.Equal(expected) {
+			t.Errorf("'%s' parsed as %s instead of %s (error: %v)", text, deadline, expected, err)
+		}
+	}
+	if _, err := parseDeadline("soon", now); err == nil {
+		t.Errorf("Unknown deadline has been accepted")
+	}
+}
+
+func TestGoalCommands(t *testing.T) {
+	s := budget.NewRamStorage()
+	outCh := make(chan tgbotapi.Chattable, 10)
+	goals := NewGoalHandler(s, nil)
+	goals.Init(outCh, nil)
+
+	for _, cmd := range []string{"/goal new bike 300 by 2099-12-31", "/goal new reserve 10%", "/goal topup bike 50"} {
+		goals.HandleOne(newTestCommand(1, cmd))
+		if reply := (<-outCh).(tgbotapi.MessageConfig).Text; strings.HasPrefix(reply, "Could not") || strings.HasPrefix(reply, "Usage") {
+			t.Errorf("'%s' has failed: %s", cmd, reply)
+		}
+	}
+	goals.HandleOne(newTestCommand(1, "/goal topup car 50"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; !strings.HasPrefix(reply, "Could not") {
+		t.Errorf("Unknown goal has been topped up: %s", reply)
+	}
+
+	goals.HandleOne(newTestCommand(1, "/goal close reserve"))
+	<-outCh
+	goals.HandleOne(newTestCommand(1, "/goal"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; !strings.HasPrefix(reply, "Your goals:\nbike: 50.") || !strings.HasSuffix(reply, "of 300.00 saved (16%) by 2099-12-31") {
+		t.Errorf("Unexpected list of goals: %s", reply)
+	}
+}
@@ -5,7 +5,6 @@ import "log"
 import "fmt"
 import "time"
 import "strconv"
-import "sort"
 import "gopkg.in/telegram-bot-api.v4"
 
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
@@ -56,27 +55,26 @@ func (h *lastTransactionsListHandler) HandleOne(msg tgbotapi.Message) {
 		return
 	}
 
-	// TODO: retrieve only necessary transactions for arbitrary amount of time
-	now := time.Now()
-	transactions, err := h.storage.GetActualTransactions(wallet.ID, now.Add(-time.Hour*24*30), now)
+	transactions, err := getLastTransactions(h.storage, wallet, numberOfShownTransactions)
 	if err != nil {
 		log.Printf("Could not get transactions for wallet '%s' for %s with error: %s", wallet.ID, dumpMsgUserInfo(msg), err)
 		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Could not obtain transactions in your wallet :( Try to contact bot owner")
 		return
 	}
 
-	sort.Slice(transactions, func(i, j int) bool { return transactions[i].Time.Before(transactions[j].Time) })
-	if numberOfShownTransactions < len(transactions) {
-		transactions = transactions[len(transactions)-numberOfShownTransactions:]
-	}
-
 	result := fmt.Sprintf("List of last %d transactions:\n", len(transactions))
-	for _, t := range transactions {
+	for i, t := range transactions {
 		label := "--None--"
 		if t.Label != "" {
 			label = fmt.Sprintf("#%s", t.Label)
 		}
-		result += fmt.Sprintf("Date: %s; amount: %d; label: %s\n", t.Time.Format(time.RFC3339), t.Value, label)
+		author := ""
+		if t.UserID != 0 {
+			author = fmt.Sprintf("; added by: %s", memberName(h.storage, t.UserID))
+		}
+		// numbers count from the latest transaction, so that they could be passed to /edit and /delete
+		result += fmt.Sprintf("%d. Date: %s; amount: %s; label: %s%s; ID: %s\n", len(transactions)-i, wallet.LocalTime(t.Time).Format(time.RFC3339), formatAmount(t.Value, wallet.Decimals, t.Currency), label, author, t.ID)
 	}
+	result += "Use '/edit N 50 #label' or '/delete N' to fix transaction N"
 	h.OutMsgCh <- tgbotapi.NewMessage(chatId, result)
 }
@@ -0,0 +1,118 @@
+package bot
+
+import "log"
+import "fmt"
+import "regexp"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const limitCmd = "limit"
+
+var limitRe *regexp.Regexp = regexp.MustCompile("^#([\\wА-Яа-я]+) (\\d+(?:[.,]\\d+)?|off)$")
+
+// limitHandler manages spending limits of labels: '/limit #food 15000' caps spending labeled 'food' within a budget period,
+// '/limit #food off' removes the cap and '/limit' lists all limits together with current spending
+type limitHandler struct {
+	baseHandler
+	admins ChatAdminChecker
+}
+
+func NewLimitHandler(storage budget.Storage, admins ChatAdminChecker) tgbotbase.IncomingMessageHandler {
+	h := &limitHandler{admins: admins}
+	h.storage = storage
+	return h
+}
+
+func (h *limitHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{limitCmd})
+}
+
+func (h *limitHandler) Name() string {
+	return "spending limits"
+}
+
+func (h *limitHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Limit request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	args := strings.Join(strings.Fields(msg.CommandArguments()), " ")
+
+	wallet, err := budget.GetWalletForOwner(budget.OwnerId(chatId), true, h.storage)
+	if err != nil {
+		log.Printf("Could not get wallet for %s due to error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not process /%s: %s", limitCmd, err))
+		return
+	}
+
+	var reply string
+	if args == "" {
+		reply, err = h.list(wallet)
+	} else if matches := limitRe.FindStringSubmatch(args); len(matches) > 0 {
+		if err = checkChangeAllowed(h.storage, h.admins, msg); err == nil {
+			reply, err = h.set(wallet, matches[1], matches[2])
+		}
+	} else {
+		reply = fmt.Sprintf("Usage: '/%s #food 15000' sets a limit for a label per month, '/%s #food off' removes it and '/%s' lists all limits", limitCmd, limitCmd, limitCmd)
+	}
+	if err != nil {
+		log.Printf("Could not process limit request of %s due to error: %s", dumpMsgUserInfo(msg), err)
+		reply = fmt.Sprintf("Could not process /%s: %s", limitCmd, err)
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, reply)
+}
+
+func (h *limitHandler) list(wallet *budget.Wallet) (string, error) {
+	usage, err := wallet.GetLimitUsage(wallet.Now())
+	if err != nil {
+		return "", err
+	}
+	if len(usage) == 0 {
+		return fmt.Sprintf("There are no limits yet, set one like '/%s #food 15000'", limitCmd), nil
+	}
+	result := "Limits for current month:"
+	for _, u := range usage {
+		result += fmt.Sprintf("\n#%s: spent %s", u.Label, describeLimitUsage(wallet, u))
+	}
+	return result, nil
+}
+
+func (h *limitHandler) set(wallet *budget.Wallet, label string, value string) (string, error) {
+	limit := 0
+	if value != "off" {
+		var err error
+		if limit, err = budget.ParseAmount(value, wallet.Decimals); err != nil {
+			return "", err
+		}
+	}
+	if err := wallet.SetLimit(label, limit); err != nil {
+		return "", err
+	}
+	if limit == 0 {
+		return fmt.Sprintf("Limit for #%s has been removed", label), nil
+	}
+	return fmt.Sprintf("Spending labeled #%s is limited by %s per month", label, formatAmount(limit, wallet.Decimals, wallet.Currency)), nil
+}
+
+// describeLimitUsage prints spending against its limit, e.g. '12000.00 of 15000.00 (80%)'
+func describeLimitUsage(wallet *budget.Wallet, u budget.LimitUsage) string {
+	return fmt.Sprintf("%s of %s (%d%%)", formatAmount(u.Spent, wallet.Decimals, wallet.Currency), formatAmount(u.Limit, wallet.Decimals, wallet.Currency), u.Percent())
+}
+
+// constructLimitAlert returns a warning if expense t has made spending of its label cross a threshold of its limit; empty otherwise
+func constructLimitAlert(wallet *budget.Wallet, t budget.ActualTransaction) string {
+	usage, threshold, err := wallet.CheckLimit(t)
+	if err != nil {
+		log.Printf("Could not check limit of label '%s' of wallet '%s' due to error: %s", t.Label, wallet.ID, err)
+		return ""
+	}
+	switch {
+	case threshold >= 100:
+		return fmt.Sprintf("Warning: limit for #%s has been exceeded, spent %s", t.Label, describeLimitUsage(wallet, *usage))
+	case threshold > 0:
+		return fmt.Sprintf("Warning: %d%% of limit for #%s has been reached, spent %s", threshold, t.Label, describeLimitUsage(wallet, *usage))
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package bot
+
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestLimitAlerts(t *testing.T) {
+	s := budget.NewRamStorage()
+	outCh := make(chan tgbotapi.Chattable, 10)
+	limits := NewLimitHandler(s, nil)
+	limits.Init(outCh, nil)
+	transactions := NewTransactionHandler(s)
+	transactions.Init(outCh, nil)
+
+	limits.HandleOne(newTestCommand(1, "/limit #food 100"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; reply != "Spending labeled #food is limited by 100.00 per month" {
+		t.Errorf("Unexpected reply to limit: %s", reply)
+	}
+
+	expected := []string{"", "Warning: 80% of limit for #food has been reached, spent 85.00 of 100.00 (85%)", "", "Warning: limit for #food has been exceeded, spent 110.00 of 100.00 (110%)"}
+	for i, text := range []string{"50 #food", "35 #food", "5 #food", "20 #food"} {
+		transactions.HandleOne(newTestMessage(1, i+1, text, false))
+		reply := (<-outCh).(tgbotapi.MessageConfig).Text
+		if expected[i] == "" && strings.Contains(reply, "Warning") || !strings.HasSuffix(reply, expected[i]) {
+			t.Errorf("Unexpected reply to '%s': %s", text, reply)
+		}
+	}
+
+	limits.HandleOne(newTestCommand(1, "/limit"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; reply != "Limits for current month:\n#food: spent 110.00 of 100.00 (110%)" {
+		t.Errorf("Unexpected list of limits: %s", reply)
+	}
+	limits.HandleOne(newTestCommand(1, "/limit #food off"))
+	<-outCh
+	limits.HandleOne(newTestCommand(1, "/limit"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; !strings.HasPrefix(reply, "There are no limits") {
+		t.Errorf("Limit has not been removed: %s", reply)
+	}
+}
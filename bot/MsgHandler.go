@@ -1,5 +1,6 @@
 package bot
 
+import "gopkg.in/telegram-bot-api.v4"
 import "github.com/admirallarimda/tgbotbase"
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 
@@ -7,3 +8,8 @@ type baseHandler struct {
 	tgbotbase.BaseHandler
 	storage budget.Storage
 }
+
+// CallbackQueryHandler is implemented by handlers which attach inline keyboards to their messages and process presses of their buttons
+type CallbackQueryHandler interface {
+	HandleCallback(query tgbotapi.CallbackQuery)
+}
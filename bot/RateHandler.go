@@ -0,0 +1,72 @@
+package bot
+
+import "log"
+import "fmt"
+import "regexp"
+import "strings"
+import "strconv"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+var rateRe *regexp.Regexp = regexp.MustCompile("([a-zA-Z]{3}) +([a-zA-Z]{3})(?: +(\\d+(?:[.,]\\d+)?))?")
+
+const rateExample = "/rate EUR RUB 75.5"
+
+// rateHandler shows and sets exchange rates of a rate table which is shared by all wallets
+type rateHandler struct {
+	tgbotbase.BaseHandler
+	rates *budget.RateTable
+	path  string
+}
+
+// NewRateHandler creates handler modifying rates; if path is not empty, rates are saved there after each change
+func NewRateHandler(rates *budget.RateTable, path string) tgbotbase.IncomingMessageHandler {
+	return &rateHandler{rates: rates, path: path}
+}
+
+func (h *rateHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{"rate"})
+}
+
+func (h *rateHandler) Name() string {
+	return "exchange rate"
+}
+
+func (h *rateHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Exchange rate request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	matches := rateRe.FindStringSubmatch(msg.CommandArguments())
+	if matches == nil {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Please specify currencies and optionally a new rate (example: %s)", rateExample))
+		return
+	}
+	from := budget.Currency(strings.ToUpper(matches[1]))
+	to := budget.Currency(strings.ToUpper(matches[2]))
+
+	if matches[3] != "" {
+		rate, err := strconv.ParseFloat(strings.Replace(matches[3], ",", ".", 1), 64)
+		if err == nil {
+			err = h.rates.SetRate(from, to, rate)
+		}
+		if err != nil {
+			log.Printf("Could not set rate from %s to %s due to error: %s", from, to, err)
+			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not set exchange rate: %s", err))
+			return
+		}
+		if h.path != "" {
+			if err := h.rates.Save(h.path); err != nil {
+				log.Printf("Could not save exchange rates to %s due to error: %s", h.path, err)
+			}
+		}
+	}
+
+	rate, err := h.rates.Rate(from, to)
+	if err != nil {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("%s (example of setting it: %s)", err, rateExample))
+		return
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("1 %s = %s %s", from, strconv.FormatFloat(rate, 'f', -1, 64), to))
+}
@@ -2,19 +2,29 @@ package bot
 
 import "regexp"
 import "log"
+import "time"
+import "errors"
 import "fmt"
-import "strconv"
 import "sort"
+import "strconv"
 import "strings"
 import "gopkg.in/telegram-bot-api.v4"
 
 import "github.com/admirallarimda/tgbotbase"
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 
-var incomeRe *regexp.Regexp = regexp.MustCompile("income (\\d+)")
-var expenseRe *regexp.Regexp = regexp.MustCompile("expense (\\d+)")
-var dateRe *regexp.Regexp = regexp.MustCompile("date (\\d{1,2})")
+var incomeRe *regexp.Regexp = regexp.MustCompile("income (\\d+(?:[.,]\\d+)?)(?: ([a-zA-Z]{3})\\b)?")
+var expenseRe *regexp.Regexp = regexp.MustCompile("expense (\\d+(?:[.,]\\d+)?)(?: ([a-zA-Z]{3})\\b)?")
+var dateRe *regexp.Regexp = regexp.MustCompile("date (\\d{1,2}|last)")
 var labelRe *regexp.Regexp = regexp.MustCompile("#([\\wA-Za-zА-Яа-я]+)")
+var everyWeekdayRe *regexp.Regexp = regexp.MustCompile("every (monday|tuesday|wednesday|thursday|friday|saturday|sunday)")
+var everyIntervalRe *regexp.Regexp = regexp.MustCompile("every (\\d+) (week|month|year)s? from (\\d{4}-\\d{2}-\\d{2})")
+var quarterlyRe *regexp.Regexp = regexp.MustCompile("quarterly from (\\d{4}-\\d{2}-\\d{2})")
+var yearlyRe *regexp.Regexp = regexp.MustCompile("yearly (\\d{1,2})-(\\d{1,2}|last)")
+var fromRe *regexp.Regexp = regexp.MustCompile("from (\\d{4}-\\d{2}-\\d{2})")
+var untilRe *regexp.Regexp = regexp.MustCompile("until (\\d{4}-\\d{2}-\\d{2})")
+var paymentsRe *regexp.Regexp = regexp.MustCompile("payments (\\d+)")
+var shiftRe *regexp.Regexp = regexp.MustCompile("shift (prev|next)")
 var removeRe *regexp.Regexp = regexp.MustCompile("(remove|delete)")
 
 const regularCmd = "regular"
@@ -23,10 +33,11 @@ const example = "/" + regularCmd + " income 2000 date 20 #label"
 
 type regularTransactionHandler struct {
 	baseHandler
+	admins ChatAdminChecker
 }
 
-func NewRegularTransactionHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
-	h := &regularTransactionHandler{}
+func NewRegularTransactionHandler(storage budget.Storage, admins ChatAdminChecker) tgbotbase.IncomingMessageHandler {
+	h := &regularTransactionHandler{admins: admins}
 	h.storage = storage
 	return h
 }
@@ -36,7 +47,7 @@ func (h *regularTransactionHandler) HandleOne(msg tgbotapi.Message) {
 	text := strings.Trim(msg.Text, " /")
 	chatId := msg.Chat.ID
 	ownerId := budget.OwnerId(chatId)
-	w, err := h.storage.GetWalletForOwner(ownerId, true)
+	w, err := budget.GetWalletForOwner(ownerId, true, h.storage)
 	if err != nil {
 		log.Printf("Cannot get wallet for %s, error: %s", dumpMsgUserInfo(msg), err)
 		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Cannot find your wallet. Have you entered /start ?")
@@ -44,9 +55,14 @@ func (h *regularTransactionHandler) HandleOne(msg tgbotapi.Message) {
 	}
 	if text == regularCmd {
 		h.showSummary(w, chatId)
-	} else {
-		h.parseTransaction(w, chatId, text)
+		return
 	}
+	if err := checkChangeAllowed(h.storage, h.admins, msg); err != nil {
+		log.Printf("Regular transactions change is not allowed for %s: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not change regular transactions: %s", err))
+		return
+	}
+	h.parseTransaction(w, chatId, text)
 }
 
 func (h *regularTransactionHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
@@ -78,20 +94,22 @@ func (h *regularTransactionHandler) showSummary(w *budget.Wallet, chatId int64)
 		}
 	}
 	dates = uniqueInts(dates)
-	sort.Ints(dates)
+	sort.Slice(dates, func(i, j int) bool {
+		return monthDayOrder(dates[i]) < monthDayOrder(dates[j])
+	})
 	incomeText := "Incomes:\n"
 	expenseText := "Expenses:\n"
 	for _, d := range dates {
 		incomeList, found := incomes[d]
 		if found {
 			for _, income := range incomeList {
-				incomeText += fmt.Sprintf("Day %d: +%d #%s\n", d, income.Value, income.Label)
+				incomeText += fmt.Sprintf("%s: +%s #%s%s\n", capitalize(income.Schedule()), formatAmount(income.Value, w.Decimals, income.Currency), income.Label, describeShift(income.Shift))
 			}
 		}
 		expenseList, found := expences[d]
 		if found {
 			for _, expense := range expenseList {
-				expenseText += fmt.Sprintf("Day %d: %d #%s\n", d, expense.Value, expense.Label)
+				expenseText += fmt.Sprintf("%s: %s #%s%s\n", capitalize(expense.Schedule()), formatAmount(expense.Value, w.Decimals, expense.Currency), expense.Label, describeShift(expense.Shift))
 			}
 		}
 	}
@@ -100,31 +118,139 @@ func (h *regularTransactionHandler) showSummary(w *budget.Wallet, chatId int64)
 	h.OutMsgCh <- tgbotapi.NewMessage(chatId, result)
 }
 
+func describeShift(shift budget.BusinessDayShift) string {
+	switch shift {
+	case budget.ShiftPrevious:
+		return " (previous business day if it is a day off)"
+	case budget.ShiftNext:
+		return " (next business day if it is a day off)"
+	}
+	return ""
+}
+
+// parseSchedule looks for a schedule (see parseRepetition) limited by 'from 2026-10-02' and either 'until 2027-10-01'
+// or a number of payments like 'payments 12' counted since the start date or since now if it is not set
+func parseSchedule(text string, now time.Time) (int, budget.Recurrence, error) {
+	date, rule, err := parseRepetition(text)
+	if err != nil {
+		return date, rule, err
+	}
+	if matches := fromRe.FindStringSubmatch(text); len(matches) > 0 && rule.From.IsZero() {
+		if rule.From, err = time.Parse("2006-01-02", matches[1]); err != nil {
+			return date, rule, fmt.Errorf("Start date '%s' should look like 2026-10-02", matches[1])
+		}
+	}
+	if matches := untilRe.FindStringSubmatch(text); len(matches) > 0 {
+		if rule.Until, err = time.Parse("2006-01-02", matches[1]); err != nil {
+			return date, rule, fmt.Errorf("End date '%s' should look like 2027-10-01", matches[1])
+		}
+	}
+	if matches := paymentsRe.FindStringSubmatch(text); len(matches) > 0 {
+		if !rule.Until.IsZero() {
+			return date, rule, errors.New("Either an end date or a number of payments could be set, not both")
+		}
+		count, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return date, rule, fmt.Errorf("Number of payments '%s' should be a positive number", matches[1])
+		}
+		tx := budget.RegularTransaction{Date: date, Recurrence: rule}
+		if err := tx.SetPaymentCount(count, now); err != nil {
+			return date, rule, err
+		}
+		rule = tx.Recurrence
+	}
+	return date, rule, nil
+}
+
+// parseRepetition looks for a recurrence rule like 'every friday', 'every 2 weeks from 2026-10-02', 'quarterly from 2026-01-15',
+// 'yearly 03-15' or for a date of a monthly transaction like 'date 5'
+func parseRepetition(text string) (int, budget.Recurrence, error) {
+	rule := budget.Recurrence{}
+	if matches := everyIntervalRe.FindStringSubmatch(text); len(matches) > 0 {
+		interval, err := strconv.Atoi(matches[1])
+		if err != nil || interval < 1 {
+			return 0, rule, fmt.Errorf("Interval '%s' should be a positive number", matches[1])
+		}
+		return scheduleFrom(matches[3], matches[2], interval)
+	}
+	if matches := quarterlyRe.FindStringSubmatch(text); len(matches) > 0 {
+		return scheduleFrom(matches[1], "month", 3)
+	}
+	if matches := everyWeekdayRe.FindStringSubmatch(text); len(matches) > 0 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.ToLower(day.String()) == matches[1] {
+				rule.Freq, rule.Weekday = budget.FreqWeekly, day
+			}
+		}
+		return 0, rule, nil
+	}
+	if matches := yearlyRe.FindStringSubmatch(text); len(matches) > 0 {
+		month, err := strconv.Atoi(matches[1])
+		if err != nil || month < 1 || month > 12 {
+			return 0, rule, fmt.Errorf("Month '%s' should be between 1 and 12", matches[1])
+		}
+		date, err := budget.ParseMonthDay(matches[2])
+		if err != nil {
+			return 0, rule, err
+		}
+		rule.Freq, rule.Month = budget.FreqYearly, time.Month(month)
+		return date, rule, nil
+	}
+	if matches := dateRe.FindStringSubmatch(text); len(matches) > 0 {
+		date, err := budget.ParseMonthDay(matches[1])
+		return date, rule, err
+	}
+	return 0, rule, errors.New("Date (from 1 to 31 or 'last') or a schedule like 'every friday' is mandatory")
+}
+
+// scheduleFrom prepares a rule repeated every interval units starting from date like '2026-10-02'
+func scheduleFrom(dateStr string, unit string, interval int) (int, budget.Recurrence, error) {
+	start, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return 0, budget.Recurrence{}, fmt.Errorf("Start date '%s' should look like 2026-10-02", dateStr)
+	}
+	rule := budget.Recurrence{Interval: interval, From: start}
+	switch unit {
+	case "week":
+		rule.Freq, rule.Weekday = budget.FreqWeekly, start.Weekday()
+		return 0, rule, nil
+	case "year":
+		rule.Freq, rule.Month = budget.FreqYearly, start.Month()
+	default:
+		rule.Freq = budget.FreqMonthly
+	}
+	return start.Day(), rule, nil
+}
+
+func capitalize(text string) string {
+	if text == "" {
+		return text
+	}
+	return strings.ToUpper(text[:1]) + text[1:]
+}
+
+// monthDayOrder puts the last day of month after any other date
+func monthDayOrder(date int) int {
+	if date == budget.LastDayOfMonth {
+		return 32
+	}
+	return date
+}
+
 func (h *regularTransactionHandler) parseTransaction(w *budget.Wallet, chatId int64, text string) {
 	incomeMatches := incomeRe.FindStringSubmatch(text)   // TODO: FindAll?
 	expenseMatches := expenseRe.FindStringSubmatch(text) // TODO: FindAll?
-	dateMatches := dateRe.FindStringSubmatch(text)
 	labelMatches := labelRe.FindStringSubmatch(text)
+	shiftMatches := shiftRe.FindStringSubmatch(text)
 	toBeRemoved := removeRe.MatchString(text)
 
-	if len(dateMatches) == 0 {
-		log.Printf("No date in message, cannot proceed")
-		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Date (from 1 to 28) is mandatory for monthly settings (example: %s)", example))
-		return
-	}
-	dateStr := dateMatches[1]
-	date, err := strconv.Atoi(dateStr)
+	date, rule, err := parseSchedule(text, w.Now())
 	if err != nil {
-		log.Printf("Could not convert date %s to int", dateStr)
-		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your date '%s' is not an integer number (but it should be)", dateStr))
-		return
-	}
-	if date < 1 || date > 28 {
-		log.Printf("Incorrect date %d", date)
-		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your date '%d' should be between 1 and 28. If your planned income/expense is at dates 29, 30 or 31 please use 1 or 28 (which is closer)", date))
+		log.Printf("Could not parse schedule in text '%s': %s", text, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your schedule is incorrect: %s. Dates 29-31 are moved to the last day in shorter months (example: %s)", err, example))
 		return
 	}
-	log.Printf("Parsed date: %d", date)
+	log.Printf("Parsed date %d and rule '%s'", date, rule)
 
 	if len(labelMatches) == 0 {
 		log.Printf("Labels are empty in text '%s'", text)
@@ -134,28 +260,39 @@ func (h *regularTransactionHandler) parseTransaction(w *budget.Wallet, chatId in
 	label := labelMatches[1]
 	log.Printf("Parsed label: %s", label)
 
+	shift := budget.ShiftNone
+	if len(shiftMatches) > 0 {
+		shift = budget.BusinessDayShift(shiftMatches[1])
+	}
+
 	// TODO: think of possibility to add multiple values (/monthly income 500 #salary1 date 5 income 200 #salary2 date 20 expense 10 expense 40 date 3)
 	transactions := make([]*budget.RegularTransaction, 0, len(incomeMatches)+len(expenseMatches))
 	if len(incomeMatches) > 0 {
 		valStr := incomeMatches[1]
-		incomeVal, err := strconv.Atoi(valStr)
+		incomeVal, err := budget.ParseAmount(valStr, w.Decimals)
 		if err != nil {
-			log.Printf("Could not convert income value %s to int", valStr)
-			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your income '%s' is not an integer number (but it should be)", valStr))
+			log.Printf("Could not convert income value %s to amount: %s", valStr, err)
+			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your income is incorrect: %s", err))
 		} else {
-			transactions = append(transactions, budget.NewRegularTransaction(incomeVal, date, label))
+			income := budget.NewRecurringTransaction(incomeVal, date, rule, label)
+			income.Currency = budget.Currency(strings.ToUpper(incomeMatches[2]))
+			income.Shift = shift
+			transactions = append(transactions, income)
 		}
 	}
 
 	// TODO: almost duplicate, can be merged to 1 function
 	if len(expenseMatches) > 0 {
 		valStr := expenseMatches[1]
-		expenseVal, err := strconv.Atoi(valStr)
+		expenseVal, err := budget.ParseAmount(valStr, w.Decimals)
 		if err != nil {
-			log.Printf("Could not convert expense value %s to int", valStr)
-			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your expense '%s' is not an integer number (but it should be)", valStr))
+			log.Printf("Could not convert expense value %s to amount: %s", valStr, err)
+			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Your expense is incorrect: %s", err))
 		} else {
-			transactions = append(transactions, budget.NewRegularTransaction(-expenseVal, date, label))
+			expense := budget.NewRecurringTransaction(-expenseVal, date, rule, label)
+			expense.Currency = budget.Currency(strings.ToUpper(expenseMatches[2]))
+			expense.Shift = shift
+			transactions = append(transactions, expense)
 		}
 	}
 
@@ -0,0 +1,40 @@
+package bot
+
+import "time"
+import "testing"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"income 100 date 5 #salary":                               "day 5",
+		"income 100 date last #salary":                            "day last",
+		"expense 50 every friday #cleaner":                        "every Friday",
+		"income 1000 every 2 weeks from 2026-10-02 #paycheck":     "every 2 weeks on Friday from 2026-10-02",
+		"expense 300 quarterly from 2026-01-15 #taxes":            "day 15 every 3 months from 2026-01-15",
+		"expense 300 every 3 months from 2026-01-15 #taxes":       "day 15 every 3 months from 2026-01-15",
+		"expense 1200 yearly 03-15 #insurance":                    "yearly on 03-15",
+		"expense 1200 every 2 years from 2026-03-15 #visa":        "every 2 years on 03-15 from 2026-03-15",
+		"expense 50 date 5 from 2026-11-01 until 2027-04-30 #gym": "day 5 from 2026-11-01 until 2027-04-30",
+		"expense 50 every friday until 2026-12-31 #cleaner":       "every Friday until 2026-12-31",
+		"expense 200 date 20 payments 3 #loan":                    "day 20 until 2026-12-20",
+		"expense 200 date 10 payments 3 #loan":                    "day 10 until 2027-01-10",
+		"expense 300 quarterly from 2026-01-15 payments 2 #taxes": "day 15 every 3 months from 2026-01-15 until 2026-04-15",
+	}
+	for text, expected := range cases {
+		date, rule, err := parseSchedule(text, now)
+		if err != nil {
+			t.Errorf("'%s' has not been parsed: %s", text, err)
+			continue
+		}
+		if schedule := budget.NewRecurringTransaction(100, date, rule, "test").Schedule(); schedule != expected {
+			t.Errorf("'%s' parsed as '%s' instead of '%s'", text, schedule, expected)
+		}
+	}
+	for _, text := range []string{"income 100 #salary", "income 100 date 32 #salary", "expense 1 yearly 13-01 #x", "expense 1 every 0 weeks from 2026-10-02 #x", "expense 1 every 2 weeks from 2026-13-02 #x", "expense 1 date 5 payments 0 #x", "expense 1 date 5 until 2027-01-01 payments 2 #x"} {
+		if _, _, err := parseSchedule(text, now); err == nil {
+			t.Errorf("'%s' has been accepted", text)
+		}
+	}
+}
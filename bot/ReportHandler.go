@@ -0,0 +1,133 @@
+package bot
+
+import "log"
+import "fmt"
+import "sort"
+import "time"
+import "regexp"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const reportCmd = "report"
+
+var reportRangeRe *regexp.Regexp = regexp.MustCompile("^(\\d{4}-\\d{2}-\\d{2}) ?\\.\\. ?(\\d{4}-\\d{2}-\\d{2})$")
+
+// reportHandler aggregates actual transactions over an arbitrary range of dates: '/report 2026-01-01..2026-06-30'
+type reportHandler struct {
+	baseHandler
+}
+
+func NewReportHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
+	h := &reportHandler{}
+	h.storage = storage
+	return h
+}
+
+func (h *reportHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{reportCmd})
+}
+
+func (h *reportHandler) Name() string {
+	return "range report"
+}
+
+func (h *reportHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Report request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	matches := reportRangeRe.FindStringSubmatch(strings.TrimSpace(msg.CommandArguments()))
+	if len(matches) == 0 {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Usage: '/%s 2026-01-01..2026-06-30' shows income and expenses over the dates", reportCmd))
+		return
+	}
+
+	wallet, err := budget.GetWalletForOwner(budget.OwnerId(chatId), false, h.storage)
+	if err != nil {
+		log.Printf("Wallet is absent during report preparation for %s due to error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "There is no wallet - report cannot be obtained")
+		return
+	}
+	reply, err := prepareReport(wallet, matches[1], matches[2])
+	if err != nil {
+		log.Printf("Could not prepare report for %s due to error: %s", dumpMsgUserInfo(msg), err)
+		reply = fmt.Sprintf("Could not process /%s: %s", reportCmd, err)
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, reply)
+}
+
+// prepareReport describes transactions of the wallet made from the day of start till the day of end inclusive
+func prepareReport(wallet *budget.Wallet, start, end string) (string, error) {
+	loc := wallet.Now().Location()
+	tMin, err := time.ParseInLocation("2006-01-02", start, loc)
+	if err != nil {
+		return "", fmt.Errorf("Incorrect date '%s'", start)
+	}
+	tMax, err := time.ParseInLocation("2006-01-02", end, loc)
+	if err != nil {
+		return "", fmt.Errorf("Incorrect date '%s'", end)
+	}
+	summary, err := wallet.GetSummary(tMin, tMax.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if err != nil {
+		return "", err
+	}
+
+	format := func(value int) string {
+		return formatAmount(value, wallet.Decimals, wallet.Currency)
+	}
+	msg := fmt.Sprintf("Report for dates from %s to %s:", start, end)
+	msg = fmt.Sprintf("%s\nIncome: %s in %d transactions", msg, format(summary.TotalIncome()), summary.IncomeTransactions())
+	msg = fmt.Sprintf("%s\nExpenses: %s in %d transactions", msg, format(-summary.TotalExpense()), summary.ExpenseTransactions())
+	msg = fmt.Sprintf("%s\nDifference: %s", msg, format(summary.TotalIncome()+summary.TotalExpense()))
+	msg = fmt.Sprintf("%s\nAverage daily spend: %s over %d days", msg, format(summary.AverageDailyExpense()), summary.Days())
+
+	if len(summary.ExpenseSummary) > 0 {
+		msg += "\nExpenses by labels:"
+		for _, label := range sortedLabels(summary.ExpenseSummary) {
+			msg = fmt.Sprintf("%s\n%s: %s in %d transactions", msg, describeLabel(label), format(-summary.ExpenseSummary[label]), summary.ExpenseCount[label])
+		}
+	}
+	if len(summary.IncomeSummary) > 0 {
+		msg += "\nIncome by labels:"
+		for _, label := range sortedLabels(summary.IncomeSummary) {
+			msg = fmt.Sprintf("%s\n%s: %s in %d transactions", msg, describeLabel(label), format(summary.IncomeSummary[label]), summary.IncomeCount[label])
+		}
+	}
+	if len(summary.Largest) > 0 {
+		msg += "\nLargest expenses:"
+		for _, tx := range summary.Largest {
+			msg = fmt.Sprintf("%s\n%s %s %s", msg, wallet.LocalTime(tx.Time).Format("2006-01-02"), format(-tx.Value), describeLabel(tx.Label))
+		}
+	}
+	return msg, nil
+}
+
+// sortedLabels returns labels sorted by absolute amounts, the biggest goes first
+func sortedLabels(amounts map[string]int) []string {
+	labels := make([]string, 0, len(amounts))
+	for label := range amounts {
+		labels = append(labels, label)
+	}
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if abs(amounts[labels[i]]) != abs(amounts[labels[j]]) {
+			return abs(amounts[labels[i]]) > abs(amounts[labels[j]])
+		}
+		return labels[i] < labels[j]
+	})
+	return labels
+}
+
+func describeLabel(label string) string {
+	if label == "" {
+		return "unlabeled"
+	}
+	return "#" + label
+}
@@ -0,0 +1,46 @@
+package bot
+
+import "time"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestReport(t *testing.T) {
+	s := budget.NewRamStorage()
+	w, err := s.CreateWalletOwner(budget.OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	for i, tx := range []struct {
+		value int
+		label string
+	}{{300000, "salary"}, {-10000, "food"}, {-70000, "rent"}, {-5000, "food"}, {-3000, ""}} {
+		if _, err := w.AddTransaction(*budget.NewActualTransaction(tx.value, time.Date(2026, 1, 1+i, 12, 0, 0, 0, time.UTC), tx.label, "")); err != nil {
+			t.FailNow()
+		}
+	}
+	outCh := make(chan tgbotapi.Chattable, 1)
+	report := NewReportHandler(s)
+	report.Init(outCh, nil)
+
+	report.HandleOne(newTestCommand(1, "/report 2026-01-01..2026-01-10"))
+	expected := "Report for dates from 2026-01-01 to 2026-01-10:\n" +
+		"Income: 3000.00 in 1 transactions\n" +
+		"Expenses: 880.00 in 4 transactions\n" +
+		"Difference: 2120.00\n" +
+		"Average daily spend: 88.00 over 10 days\n" +
+		"Expenses by labels:\n#rent: 700.00 in 1 transactions\n#food: 150.00 in 2 transactions\nunlabeled: 30.00 in 1 transactions\n" +
+		"Income by labels:\n#salary: 3000.00 in 1 transactions\n" +
+		"Largest expenses:\n2026-01-03 700.00 #rent\n2026-01-02 100.00 #food\n2026-01-04 50.00 #food\n2026-01-05 30.00 unlabeled"
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; reply != expected {
+		t.Errorf("Unexpected report:\n%s", reply)
+	}
+
+	for _, cmd := range []string{"/report", "/report 2026-01-01", "/report 2026-02-01..2026-01-01", "/report 2026-02-30..2026-03-01"} {
+		report.HandleOne(newTestCommand(1, cmd))
+		if reply := (<-outCh).(tgbotapi.MessageConfig).Text; reply == expected || len(reply) == 0 {
+			t.Errorf("'%s' has been replied with: %s", cmd, reply)
+		}
+	}
+}
@@ -12,15 +12,22 @@ import "gopkg.in/telegram-bot-api.v4"
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 import "github.com/admirallarimda/tgbotbase"
 
-var monthStartRe *regexp.Regexp = regexp.MustCompile("monthStart (\\d{1,2})")
+var monthStartRe *regexp.Regexp = regexp.MustCompile("monthStart (\\d{1,2}|last)")
 var notifTimeRe *regexp.Regexp = regexp.MustCompile("notifTime ((\\d{1,2}:\\d{2})|(disable))")
+var currencyRe *regexp.Regexp = regexp.MustCompile("currency ([a-zA-Z]{3})\\b")
+var decimalsRe *regexp.Regexp = regexp.MustCompile("decimals (\\d)")
+var adminsOnlyRe *regexp.Regexp = regexp.MustCompile("adminsOnly (on|off)")
+var timezoneRe *regexp.Regexp = regexp.MustCompile("tz ([\\w/+-]+)")
+var carryOverRe *regexp.Regexp = regexp.MustCompile("carryOver (off|full|cap (\\d+(?:[.,]\\d+)?)|goal ([\\wА-Яа-я]+))")
 
 type settingsHandler struct {
 	baseHandler
+	admins    ChatAdminChecker
+	reminders ReminderScheduler
 }
 
-func NewWalletSettingsHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
-	h := &settingsHandler{}
+func NewWalletSettingsHandler(storage budget.Storage, admins ChatAdminChecker, reminders ReminderScheduler) tgbotbase.IncomingMessageHandler {
+	h := &settingsHandler{admins: admins, reminders: reminders}
 	h.storage = storage
 	return h
 }
@@ -38,14 +45,23 @@ func (h *settingsHandler) HandleOne(msg tgbotapi.Message) {
 	text := msg.Text
 	chatId := msg.Chat.ID
 	ownerId := budget.OwnerId(chatId)
+
+	// admins only mode itself could be switched only by admins, even if it is disabled
+	var err error
+	if adminsOnlyRe.MatchString(text) {
+		err = checkIsAdmin(h.admins, msg)
+	} else {
+		err = checkChangeAllowed(h.storage, h.admins, msg)
+	}
+	if err != nil {
+		log.Printf("Settings change is not allowed for %s: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not change settings: %s", err))
+		return
+	}
 	h.parseCmd(text, chatId, ownerId)
 }
 
 func (h *settingsHandler) setMonthStart(ownerId budget.OwnerId, date int) error {
-	if date < 1 || date > 28 {
-		return errors.New("Date must be between 1 and 28")
-	}
-
 	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
 	if err != nil {
 		return err
@@ -56,28 +72,126 @@ func (h *settingsHandler) setMonthStart(ownerId budget.OwnerId, date int) error
 		return err
 	}
 
-	log.Printf("Month start for wallet '%s' has been successfully modified to %d", wallet.ID, date)
+	log.Printf("Month start for wallet '%s' has been successfully modified to %s", wallet.ID, budget.FormatMonthDay(date))
 	return nil
 }
 
 func (h *settingsHandler) changeMonthStart(text string, chatId int64, ownerId budget.OwnerId) {
 	matches := monthStartRe.FindStringSubmatch(text)
 	dateStr := matches[1]
-	date, err := strconv.Atoi(dateStr)
+	date, err := budget.ParseMonthDay(dateStr)
 	if err != nil {
 		log.Printf("Could not convert date '%s' for month start setting due to error: %s", dateStr, err)
-		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Incorrect format of date for month start modification"))
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Incorrect date for month start modification: %s", err))
 		return
 	}
 	err = h.setMonthStart(ownerId, date)
 	if err != nil {
-		log.Printf("Could not set month start %d for owner %d due to error: %s", date, ownerId, err)
+		log.Printf("Could not set month start %s for owner %d due to error: %s", dateStr, ownerId, err)
 		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not set month start due to the following reason: %s", err))
 		return
 	}
 	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Month start has been successfully modified"))
 }
 
+func (h *settingsHandler) changeCurrency(text string, chatId int64, ownerId budget.OwnerId) {
+	matches := currencyRe.FindStringSubmatch(text)
+	currency := budget.Currency(strings.ToUpper(matches[1]))
+
+	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	if err == nil {
+		err = wallet.SetCurrency(currency)
+	}
+	if err != nil {
+		log.Printf("Could not set currency %s for owner %d due to error: %s", currency, ownerId, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not set currency due to the following reason: %s", err))
+		return
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Balance will be calculated in %s from now on. Transactions without currency are considered to be in %s as well", currency, currency))
+}
+
+func (h *settingsHandler) changeAdminsOnly(text string, chatId int64, ownerId budget.OwnerId) {
+	adminsOnly := adminsOnlyRe.FindStringSubmatch(text)[1] == "on"
+	if err := h.storage.SetOwnerAdminsOnly(ownerId, adminsOnly); err != nil {
+		log.Printf("Could not set admins only mode for owner %d due to error: %s", ownerId, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not change admins only mode due to the following reason: %s", err))
+		return
+	}
+	if adminsOnly {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "From now on only administrators of the chat could change regular transactions and settings")
+	} else {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "From now on everyone in the chat could change regular transactions and settings")
+	}
+}
+
+func (h *settingsHandler) changeTimezone(text string, chatId int64, ownerId budget.OwnerId) {
+	name := timezoneRe.FindStringSubmatch(text)[1]
+	loc, err := budget.SetOwnerTimezone(ownerId, name, h.storage)
+	if err != nil {
+		log.Printf("Could not set time zone '%s' for owner %d due to error: %s", name, ownerId, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not set time zone due to the following reason: %s", err))
+		return
+	}
+	rescheduleReminder(h.reminders, ownerId)
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Time zone has been set to %s, current time there is %s", loc, time.Now().In(loc).Format("2006-01-02 15:04")))
+}
+
+func (h *settingsHandler) changeDecimals(text string, chatId int64, ownerId budget.OwnerId) {
+	matches := decimalsRe.FindStringSubmatch(text)
+	decimals, _ := strconv.Atoi(matches[1]) // a single digit is guaranteed by regexp
+
+	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	if err == nil {
+		err = wallet.SetDecimals(decimals)
+	}
+	if err != nil {
+		log.Printf("Could not set %d decimals for owner %d due to error: %s", decimals, ownerId, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not change number of decimals due to the following reason: %s", err))
+		return
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Amounts will be shown with %d decimals from now on; existing transactions have been rounded if needed and /undo history has been reset", decimals))
+}
+
+func (h *settingsHandler) changeCarryOver(text string, chatId int64, ownerId budget.OwnerId) {
+	matches := carryOverRe.FindStringSubmatch(text)
+	policy := budget.CarryOver{}
+	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	if err == nil {
+		switch {
+		case matches[1] == "full":
+			policy.Mode = budget.CarryOverFull
+		case matches[2] != "":
+			policy.Mode = budget.CarryOverCapped
+			policy.Cap, err = budget.ParseAmount(matches[2], wallet.Decimals)
+		case matches[3] != "":
+			policy.Mode = budget.CarryOverGoal
+			policy.Goal = matches[3]
+		}
+	}
+	if err == nil {
+		err = wallet.SetCarryOver(policy, wallet.Now())
+	}
+	if err != nil {
+		log.Printf("Could not set carry-over '%s' for owner %d due to error: %s", matches[1], ownerId, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not change carry-over due to the following reason: %s", err))
+		return
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, describeCarryOverPolicy(wallet, policy))
+}
+
+// describeCarryOverPolicy explains what happens with leftovers of the wallet under the policy
+func describeCarryOverPolicy(wallet *budget.Wallet, policy budget.CarryOver) string {
+	switch policy.Mode {
+	case budget.CarryOverFull:
+		return "Starting from the current month its surplus or overspend will be carried into the next one"
+	case budget.CarryOverCapped:
+		return fmt.Sprintf("Starting from the current month its surplus up to %s will be carried into the next one; overspend is carried fully", formatAmount(policy.Cap, wallet.Decimals, wallet.Currency))
+	case budget.CarryOverGoal:
+		return fmt.Sprintf("Starting from the current month its surplus will be put aside for goal '%s'; overspend is carried into the next one", policy.Goal)
+	}
+	return "Leftover of a month will not be carried into the next one"
+}
+
 func (h *settingsHandler) setNotificationTime(ownerId budget.OwnerId, enabled bool, hour, minute int) error {
 	if (hour < 0 || hour > 23) || (minute < 0 || minute > 59) {
 		return errors.New(fmt.Sprintf("Incorrect notification hour %d or minute %d", hour, minute))
@@ -120,6 +234,7 @@ func (h *settingsHandler) changeNotificationTime(text string, chatId int64, owne
 		log.Printf("Something went wrong with disabling notification time for owner %d, error: %s", ownerId, err)
 		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Something went wrong - cannot modify notification time :( ")
 	} else {
+		rescheduleReminder(h.reminders, ownerId)
 		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Your daily notification settings have been successfully modified")
 	}
 }
@@ -129,5 +244,15 @@ func (h *settingsHandler) parseCmd(text string, chatId int64, ownerId budget.Own
 		h.changeMonthStart(text, chatId, ownerId)
 	} else if notifTimeRe.MatchString(text) {
 		h.changeNotificationTime(text, chatId, ownerId)
+	} else if currencyRe.MatchString(text) {
+		h.changeCurrency(text, chatId, ownerId)
+	} else if decimalsRe.MatchString(text) {
+		h.changeDecimals(text, chatId, ownerId)
+	} else if adminsOnlyRe.MatchString(text) {
+		h.changeAdminsOnly(text, chatId, ownerId)
+	} else if timezoneRe.MatchString(text) {
+		h.changeTimezone(text, chatId, ownerId)
+	} else if carryOverRe.MatchString(text) {
+		h.changeCarryOver(text, chatId, ownerId)
 	}
 }
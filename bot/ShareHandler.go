@@ -0,0 +1,118 @@
+package bot
+
+import "log"
+import "fmt"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const shareCmd = "share"
+const joinCmd = "join"
+const membersCmd = "members"
+const leaveCmd = "leave"
+
+// shareHandler lets several owners use the same wallet: /share creates an invite code for active wallet, /join uses it,
+// /members lists owners of active wallet and /leave detaches the owner from it if it is shared
+type shareHandler struct {
+	baseHandler
+	reminders ReminderScheduler
+}
+
+func NewShareHandler(storage budget.Storage, reminders ReminderScheduler) tgbotbase.IncomingMessageHandler {
+	h := &shareHandler{reminders: reminders}
+	h.storage = storage
+	return h
+}
+
+func (h *shareHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{shareCmd, joinCmd, membersCmd, leaveCmd})
+}
+
+func (h *shareHandler) Name() string {
+	return "wallet sharing"
+}
+
+func (h *shareHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Wallet sharing request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	ownerId := budget.OwnerId(chatId)
+
+	var reply string
+	var err error
+	switch msg.Command() {
+	case shareCmd:
+		reply, err = h.share(ownerId)
+	case joinCmd:
+		reply, err = h.join(ownerId, strings.Fields(msg.CommandArguments()))
+	case membersCmd:
+		reply, err = h.members(ownerId)
+	case leaveCmd:
+		reply, err = h.leave(ownerId)
+	}
+	if err != nil {
+		log.Printf("Could not process /%s for %s due to error: %s", msg.Command(), dumpMsgUserInfo(msg), err)
+		reply = fmt.Sprintf("Could not process /%s: %s", msg.Command(), err)
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, reply)
+}
+
+func (h *shareHandler) share(ownerId budget.OwnerId) (string, error) {
+	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	if err != nil {
+		return "", err
+	}
+	code, err := wallet.CreateInvite()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Send '/%s %s' to the bot from another chat to use this wallet there. The code could be used once within a day", joinCmd, code), nil
+}
+
+// join expects an invite code optionally followed by a name which the joined wallet gets among owner's wallets
+func (h *shareHandler) join(ownerId budget.OwnerId, args []string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Sprintf("Please specify invite code obtained via /%s and optionally a name for the wallet (example: /%s 1a2b3c4d5e6f7a8b family)", shareCmd, joinCmd), nil
+	}
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+	wallet, err := budget.JoinWallet(ownerId, args[0], name, h.storage)
+	if err != nil {
+		return "", err
+	}
+	rescheduleReminder(h.reminders, ownerId)
+	return fmt.Sprintf("You have joined the shared wallet '%s', it is active now\n%s", wallet.Name, constructBalanceMessage(wallet)), nil
+}
+
+func (h *shareHandler) members(ownerId budget.OwnerId) (string, error) {
+	wallet, err := budget.GetWalletForOwner(ownerId, false, h.storage)
+	if err != nil {
+		return "", err
+	}
+	owners, err := wallet.Owners()
+	if err != nil {
+		return "", err
+	}
+	result := "Members of the wallet:"
+	for _, o := range owners {
+		if o == ownerId {
+			result += fmt.Sprintf("\n%d (you)", o)
+		} else {
+			result += fmt.Sprintf("\n%d", o)
+		}
+	}
+	return result, nil
+}
+
+func (h *shareHandler) leave(ownerId budget.OwnerId) (string, error) {
+	wallet, err := budget.LeaveWallet(ownerId, h.storage)
+	if err != nil {
+		return "", err
+	}
+	rescheduleReminder(h.reminders, ownerId)
+	return fmt.Sprintf("You have left the shared wallet, now wallet '%s' is active", wallet.Name), nil
+}
@@ -3,7 +3,10 @@ package bot
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/admirallarimda/tgbot-daily-budget/budget"
@@ -11,6 +14,16 @@ import (
 	"gopkg.in/telegram-bot-api.v4"
 )
 
+const statsCmd = "stats"
+
+// statsCallbackPrefix starts data of inline keyboard buttons paging between periods, it is followed by start date of a period
+const statsCallbackPrefix = "stats "
+
+var statsMonthRe *regexp.Regexp = regexp.MustCompile("^(\\d{4})-(\\d{2})$")
+var statsBackRe *regexp.Regexp = regexp.MustCompile("^-(\\d{1,3})$")
+
+// statsHandler shows summary of a budget period: '/stats' shows the current one, '/stats 2026-09' the one starting in September,
+// '/stats prev' the previous one and '/stats -3' the one three periods ago; arrows below the summary page between periods
 type statsHandler struct {
 	baseHandler
 }
@@ -23,7 +36,7 @@ func NewStatsHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
 
 func (h *statsHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
 	h.OutMsgCh = outMsgCh
-	return tgbotbase.NewHandlerTrigger(nil, []string{"stats"})
+	return tgbotbase.NewHandlerTrigger(nil, []string{statsCmd})
 }
 
 func (h *statsHandler) Name() string {
@@ -38,16 +51,95 @@ func (h *statsHandler) HandleOne(msg tgbotapi.Message) {
 		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("There is no wallet - stats cannot be obtained"))
 		return
 	}
-	reply, err := prepareMonthlySummary(owner, wallet, time.Now())
+	t, err := parseStatsTime(wallet, strings.TrimSpace(msg.CommandArguments()))
+	if err != nil {
+		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("%s. Usage: '/%s', '/%s 2026-09', '/%s prev' or '/%s -3'", err, statsCmd, statsCmd, statsCmd, statsCmd))
+		return
+	}
+	reply, err := prepareMonthlySummary(h.storage, owner, wallet, t)
 	if err != nil {
 		log.Printf("Could not prepare monthly stats for %s due to error: %s", dumpMsgUserInfo(msg), err)
 		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Thre is a problem with stats preparation"))
 		return
 	}
-	h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, reply)
+	replyMsg := tgbotapi.NewMessage(msg.Chat.ID, reply)
+	replyMsg.ReplyMarkup = statsKeyboard(wallet, t)
+	h.OutMsgCh <- replyMsg
 }
 
-func prepareMonthlySummary(owner budget.OwnerId, wallet *budget.Wallet, t time.Time) (string, error) {
+// HandleCallback pages the summary to the period chosen by an arrow pressed below it, the message is edited in place
+func (h *statsHandler) HandleCallback(query tgbotapi.CallbackQuery) {
+	if query.Message == nil || !strings.HasPrefix(query.Data, statsCallbackPrefix) {
+		return
+	}
+	chatId := query.Message.Chat.ID
+	owner := budget.OwnerId(chatId)
+	wallet, err := budget.GetWalletForOwner(owner, false, h.storage)
+	if err != nil {
+		log.Printf("Wallet is absent during stats paging for chat %d due to error: %s", chatId, err)
+		return
+	}
+	t, err := time.ParseInLocation("2006-01-02", strings.TrimPrefix(query.Data, statsCallbackPrefix), wallet.Now().Location())
+	if err != nil {
+		log.Printf("Incorrect stats paging data '%s' in chat %d: %s", query.Data, chatId, err)
+		return
+	}
+	reply, err := prepareMonthlySummary(h.storage, owner, wallet, t)
+	if err != nil {
+		log.Printf("Could not prepare monthly stats for chat %d due to error: %s", chatId, err)
+		return
+	}
+	edit := tgbotapi.NewEditMessageText(chatId, query.Message.MessageID, reply)
+	keyboard := statsKeyboard(wallet, t)
+	edit.ReplyMarkup = &keyboard
+	h.OutMsgCh <- edit
+}
+
+// parseStatsTime returns a moment within the period requested by /stats arguments; periods follow month start of the wallet
+func parseStatsTime(wallet *budget.Wallet, args string) (time.Time, error) {
+	now := wallet.Now()
+	back := 0
+	switch {
+	case args == "":
+		return now, nil
+	case args == "prev":
+		back = 1
+	case statsBackRe.MatchString(args):
+		back, _ = strconv.Atoi(statsBackRe.FindStringSubmatch(args)[1]) // digits are guaranteed by the expression
+	case statsMonthRe.MatchString(args):
+		matches := statsMonthRe.FindStringSubmatch(args)
+		year, _ := strconv.Atoi(matches[1])
+		month, _ := strconv.Atoi(matches[2])
+		if month < 1 || month > 12 {
+			return now, fmt.Errorf("Incorrect month '%s'", args)
+		}
+		t := time.Date(year, time.Month(month), budget.ActualDay(wallet.MonthStart, year, time.Month(month)), 0, 0, 0, 0, now.Location())
+		if t.After(now) {
+			return now, fmt.Errorf("Month %s has not started yet", args)
+		}
+		return t, nil
+	default:
+		return now, fmt.Errorf("Unknown period '%s'", args)
+	}
+	t := now
+	for i := 0; i < back; i++ {
+		t = wallet.Period(t).Start.AddDate(0, 0, -1)
+	}
+	return t, nil
+}
+
+// statsKeyboard returns arrows to the periods around the period of t; there is no arrow to periods which have not started yet
+func statsKeyboard(wallet *budget.Wallet, t time.Time) tgbotapi.InlineKeyboardMarkup {
+	period := wallet.Period(t)
+	prev := wallet.Period(period.Start.AddDate(0, 0, -1))
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("← "+prev.Start.Format("2006-01"), statsCallbackPrefix+prev.Start.Format("2006-01-02")))
+	if !period.End.After(wallet.Now()) {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(period.End.Format("2006-01")+" →", statsCallbackPrefix+period.End.Format("2006-01-02")))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+func prepareMonthlySummary(storage budget.Storage, owner budget.OwnerId, wallet *budget.Wallet, t time.Time) (string, error) {
 	log.Printf("Preparing monthly stats to owner %d with wallet '%s'", owner, wallet.ID)
 	summary, err := wallet.GetMonthlySummary(t)
 	if err != nil {
@@ -58,6 +150,10 @@ func prepareMonthlySummary(owner budget.OwnerId, wallet *budget.Wallet, t time.T
 		key   string
 		value int
 	}
+	type memberValue struct {
+		userId int
+		value  int
+	}
 	var sortedExpenses []keyValue
 	for k, v := range summary.ExpenseSummary {
 		sortedExpenses = append(sortedExpenses, keyValue{key: k, value: v})
@@ -66,13 +162,41 @@ func prepareMonthlySummary(owner budget.OwnerId, wallet *budget.Wallet, t time.T
 		return sortedExpenses[i].value < sortedExpenses[j].value // lowest value will be the first
 	})
 
-	msg := fmt.Sprintf("Last month summary (for dates from %s to %s):", summary.TimeStart, summary.TimeEnd)
+	limits, err := wallet.GetLimits()
+	if err != nil {
+		return "", err
+	}
+	for label := range limits {
+		if _, found := summary.ExpenseSummary[label]; !found {
+			sortedExpenses = append(sortedExpenses, keyValue{key: label, value: 0}) // limits without spending go last
+		}
+	}
+
+	msg := fmt.Sprintf("Last month summary (for dates from %s to %s):", summary.TimeStart.Format("2006-01-02"), summary.TimeEnd.Format("2006-01-02"))
 	for _, kv := range sortedExpenses {
 		label_txt := "unlabeled category"
 		if kv.key != "" {
 			label_txt = fmt.Sprintf("category labeled '%s'", kv.key)
 		}
-		msg = fmt.Sprintf("%s\nSpent %d for %s", msg, -(kv.value), label_txt)
+		msg = fmt.Sprintf("%s\nSpent %s for %s", msg, formatAmount(-(kv.value), wallet.Decimals, wallet.Currency), label_txt)
+		if limit, found := limits[kv.key]; found {
+			msg = fmt.Sprintf("%s (limit %s, %d%%)", msg, formatAmount(limit, wallet.Decimals, wallet.Currency), budget.LimitUsage{Label: kv.key, Limit: limit, Spent: -kv.value}.Percent())
+		}
+	}
+
+	// breakdown by members makes sense only if several people spend money from the wallet
+	if len(summary.MemberExpenseSummary) > 1 {
+		var sortedMembers []memberValue
+		for userId, v := range summary.MemberExpenseSummary {
+			sortedMembers = append(sortedMembers, memberValue{userId: userId, value: v})
+		}
+		sort.Slice(sortedMembers, func(i, j int) bool {
+			return sortedMembers[i].value < sortedMembers[j].value
+		})
+		msg = fmt.Sprintf("%s\nBy members:", msg)
+		for _, mv := range sortedMembers {
+			msg = fmt.Sprintf("%s\n%s spent %s", msg, memberName(storage, mv.userId), formatAmount(-(mv.value), wallet.Decimals, wallet.Currency))
+		}
 	}
 
 	return msg, nil
@@ -0,0 +1,82 @@
+package bot
+
+import "time"
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestParseStatsTime(t *testing.T) {
+	s := budget.NewRamStorage()
+	w, err := s.CreateWalletOwner(budget.OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.SetMonthStart(budget.LastDayOfMonth); err != nil {
+		t.FailNow()
+	}
+
+	if st, err := parseStatsTime(w, "2020-02"); err != nil || !st.Equal(time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Period starting in February 2020 is at %s (error: %v)", st, err)
+	}
+	current := w.Period(w.Now())
+	if st, err := parseStatsTime(w, "prev"); err != nil || w.Period(st).End != current.Start {
+		t.Errorf("Previous period contains %s (error: %v)", st, err)
+	}
+	back := current
+	for i := 0; i < 3; i++ {
+		back = w.Period(back.Start.AddDate(0, 0, -1))
+	}
+	if st, err := parseStatsTime(w, "-3"); err != nil || w.Period(st) != back {
+		t.Errorf("Period three periods ago contains %s instead of starting at %s (error: %v)", st, back.Start, err)
+	}
+	for _, args := range []string{"2020-13", "2999-01", "next", "+1"} {
+		if _, err := parseStatsTime(w, args); err == nil {
+			t.Errorf("Period '%s' has been accepted", args)
+		}
+	}
+}
+
+func TestStatsPaging(t *testing.T) {
+	s := budget.NewRamStorage()
+	w, err := s.CreateWalletOwner(budget.OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.SetMonthStart(10); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*budget.NewActualTransaction(-1500, time.Date(2020, 3, 15, 12, 0, 0, 0, time.UTC), "food", "")); err != nil {
+		t.FailNow()
+	}
+	outCh := make(chan tgbotapi.Chattable, 1)
+	stats := NewStatsHandler(s)
+	stats.Init(outCh, nil)
+
+	stats.HandleOne(newTestCommand(1, "/stats 2020-02"))
+	reply := (<-outCh).(tgbotapi.MessageConfig)
+	if !strings.HasPrefix(reply.Text, "Last month summary (for dates from 2020-02-10 to 2020-03-09):") {
+		t.Errorf("Unexpected summary: %s", reply.Text)
+	}
+	keyboard := reply.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 2 || *keyboard.InlineKeyboard[0][1].CallbackData != "stats 2020-03-10" {
+		t.Errorf("Unexpected paging arrows: %+v", keyboard.InlineKeyboard)
+	}
+
+	msg := newTestMessage(1, 42, reply.Text, false)
+	stats.(CallbackQueryHandler).HandleCallback(tgbotapi.CallbackQuery{ID: "1", Message: &msg, Data: *keyboard.InlineKeyboard[0][1].CallbackData})
+	edit := (<-outCh).(tgbotapi.EditMessageTextConfig)
+	if edit.MessageID != 42 || !strings.HasPrefix(edit.Text, "Last month summary (for dates from 2020-03-10 to 2020-04-09):\nSpent 15.00 for category labeled 'food'") {
+		t.Errorf("Summary has not been paged: %d %s", edit.MessageID, edit.Text)
+	}
+	if edit.ReplyMarkup == nil || *edit.ReplyMarkup.InlineKeyboard[0][0].CallbackData != "stats 2020-02-10" {
+		t.Errorf("Unexpected paging arrows after paging: %+v", edit.ReplyMarkup)
+	}
+
+	// current period has no arrow to the future
+	stats.HandleOne(newTestCommand(1, "/stats"))
+	if keyboard := (<-outCh).(tgbotapi.MessageConfig).ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); len(keyboard.InlineKeyboard[0]) != 1 {
+		t.Errorf("Current period could be paged forward: %+v", keyboard.InlineKeyboard)
+	}
+}
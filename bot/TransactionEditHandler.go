@@ -0,0 +1,79 @@
+package bot
+
+import "log"
+import "fmt"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const editCmd = "edit"
+const deleteCmd = "delete"
+
+const editExample = "/" + editCmd + " 1 50 #food"
+const deleteExample = "/" + deleteCmd + " 1"
+
+// transactionEditHandler amends or deletes actual transactions referenced by their number in /last output or by ID
+type transactionEditHandler struct {
+	baseHandler
+}
+
+func NewTransactionEditHandler(storage budget.Storage) tgbotbase.IncomingMessageHandler {
+	h := &transactionEditHandler{}
+	h.storage = storage
+	return h
+}
+
+func (h *transactionEditHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{editCmd, deleteCmd})
+}
+
+func (h *transactionEditHandler) Name() string {
+	return "transaction edit"
+}
+
+func (h *transactionEditHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Transaction edit request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	cmd := msg.Command()
+	args := strings.Fields(msg.CommandArguments())
+
+	example := editExample
+	if cmd == deleteCmd {
+		example = deleteExample
+	}
+	if len(args) == 0 || (cmd == editCmd && len(args) < 2) {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Please specify transaction number from /last list or its ID (example: %s)", example))
+		return
+	}
+
+	wallet, err := budget.GetWalletForOwner(budget.OwnerId(chatId), false, h.storage)
+	if err != nil {
+		log.Printf("Could not get wallet for %s with error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Cannot find your wallet. Have you entered /start ?")
+		return
+	}
+
+	transaction, err := findLastTransaction(h.storage, wallet, args[0])
+	if err != nil {
+		log.Printf("Could not find transaction '%s' in wallet '%s' due to error: %s", args[0], wallet.ID, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not find transaction '%s', please check /last list", args[0]))
+		return
+	}
+
+	var replyMsg string
+	if cmd == deleteCmd {
+		replyMsg, err = deleteTransaction(wallet, *transaction)
+	} else {
+		replyMsg, err = amendTransaction(wallet, *transaction, strings.Join(args[1:], " "))
+	}
+	if err != nil {
+		log.Printf("Could not %s transaction '%s' in wallet '%s' due to error: %s", cmd, transaction.ID, wallet.ID, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not %s transaction: %s", cmd, err))
+		return
+	}
+
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("%s\n%s", replyMsg, constructBalanceMessage(wallet)))
+}
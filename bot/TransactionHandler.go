@@ -4,13 +4,12 @@ import "regexp"
 import "log"
 import "fmt"
 import "time"
-import "strconv"
 import "gopkg.in/telegram-bot-api.v4"
 import "github.com/admirallarimda/tgbotbase"
 
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 
-var re *regexp.Regexp = regexp.MustCompile("^([+-]?)(\\d+) *(#([\\wa-zA-ZА-Яа-я]+))?$") // any number + label
+var re *regexp.Regexp = regexp.MustCompile("^([+-]?)([€$£₽]?)(\\d+(?:[.,]\\d+)?) *([a-zA-Z]{3})? *(#([\\wa-zA-ZА-Яа-я]+))?( *@[\\wА-Яа-я-]+)?$") // any number + currency + label + wallet
 
 type transactionHandler struct {
 	baseHandler
@@ -24,56 +23,56 @@ func NewTransactionHandler(storage budget.Storage) tgbotbase.IncomingMessageHand
 
 func (h *transactionHandler) HandleOne(msg tgbotapi.Message) {
 	log.Printf("Transaction: message received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
-
-	matches := re.FindStringSubmatch(msg.Text)
-	if matches == nil {
-		panic("Transaction: no match of regexp, wrong handler!")
+	if msg.EditDate != 0 {
+		log.Printf("Transaction: message %d has been edited, leaving it to edited messages handler", msg.MessageID)
+		return
 	}
-	log.Printf("Transaction: regexp matched the following field: %s", matches)
 
-	signStr := matches[1]
-	sign := -1 // in most cases (no sign or '-' explicitly) we should pass negative number
-	if signStr == "+" {
-		sign = 1
-	}
-	amountStr := matches[2]
-	amount, err := strconv.Atoi(amountStr)
+	ownerId := budget.OwnerId(msg.Chat.ID)
+	wallet, text, err := resolveTransactionWallet(h.storage, ownerId, msg.Text)
 	if err != nil {
-		fmt.Printf("Transaction: amount %s cannot be converted to Int; error: %s", amountStr, err)
+		log.Printf("Could not get wallet for %s with error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Could not add transaction: %s", err))
 		return
 	}
 
-	label := ""
-	if len(matches) > 4 {
-		label = matches[4] // not 3 - using label without #
-	}
-	log.Printf("Message contains label '%s'", label)
-
-	transaction := budget.NewActualTransaction(sign*amount, time.Now(), label, msg.Text)
-	ownerId := budget.OwnerId(msg.Chat.ID)
-	wallet, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	amount, currency, label, err := parseTransactionText(text, wallet.Decimals)
 	if err != nil {
-		fmt.Printf("Could not get wallet for %s with error: %s", dumpMsgUserInfo(msg), err)
+		log.Printf("Transaction: message '%s' cannot be parsed due to error: %s", msg.Text, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Could not add transaction: %s", err))
 		return
 	}
+	log.Printf("Message contains amount %d %s and label '%s'", amount, currency, label)
+
+	transaction := budget.NewActualTransaction(amount, time.Now(), label, msg.Text)
+	transaction.MessageID = msg.MessageID
+	transaction.ChatID = msg.Chat.ID
+	if msg.From != nil {
+		transaction.UserID = msg.From.ID
+		rememberUserName(h.storage, msg)
+	}
+	transaction.Currency = currency
 
 	matchesRegular, err := wallet.AddTransaction(*transaction)
 	if err != nil {
 		log.Printf("Could not add expence for %s with wallet %s due to error: %s", dumpMsgUserInfo(msg), wallet.ID, err)
+		h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Could not add transaction: %s", err))
 		return
 	}
 
 	log.Printf("Expense of %d has been successfully added to wallet %s for %s", transaction.Value, wallet.ID, dumpMsgUserInfo(msg))
 
-	replyMsg := ""
-	availMoney, err := wallet.GetBalance(time.Now())
-	if err == nil {
-		replyMsg = fmt.Sprintf("Currently available money: %d", availMoney)
+	replyMsg := constructBalanceMessage(wallet)
+	if text != msg.Text {
+		replyMsg = fmt.Sprintf("Wallet '%s': %s", wallet.Name, replyMsg)
 	}
 
 	if matchesRegular {
 		replyMsg = fmt.Sprintf("%s\nYour recent transaction matches regular transaction, thus monthly income could be modified. Current values are: %s", replyMsg, constructIncomeMessage(wallet))
 	}
+	if alert := constructLimitAlert(wallet, *transaction); alert != "" {
+		replyMsg = fmt.Sprintf("%s\n%s", replyMsg, alert)
+	}
 
 	h.OutMsgCh <- tgbotapi.NewMessage(msg.Chat.ID, replyMsg)
 }
@@ -0,0 +1,90 @@
+package bot
+
+import "log"
+import "fmt"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+type undoHandler struct {
+	baseHandler
+	admins ChatAdminChecker
+}
+
+func NewUndoHandler(storage budget.Storage, admins ChatAdminChecker) tgbotbase.IncomingMessageHandler {
+	h := &undoHandler{admins: admins}
+	h.storage = storage
+	return h
+}
+
+func (h *undoHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{"undo"})
+}
+
+func (h *undoHandler) Name() string {
+	return "undo"
+}
+
+func (h *undoHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Undo request received from %s", dumpMsgUserInfo(msg))
+	chatId := msg.Chat.ID
+
+	wallet, err := budget.GetWalletForOwner(budget.OwnerId(chatId), false, h.storage)
+	if err != nil {
+		log.Printf("Could not get wallet for %s with error: %s", dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "Cannot find your wallet. Have you entered /start ?")
+		return
+	}
+
+	last, err := wallet.LastOperation()
+	if err != nil {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not undo the last operation: %s", err))
+		return
+	}
+	if last != nil && undoRestricted(*last) {
+		if err := checkChangeAllowed(h.storage, h.admins, msg); err != nil {
+			log.Printf("Undo of operation '%s' is not allowed for %s: %s", last.Type, dumpMsgUserInfo(msg), err)
+			h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not undo %s: %s", describeOperation(*last, wallet), err))
+			return
+		}
+	}
+
+	op, err := wallet.Undo()
+	if err != nil {
+		log.Printf("Could not undo last operation in wallet '%s' for %s with error: %s", wallet.ID, dumpMsgUserInfo(msg), err)
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Could not undo the last operation: %s", err))
+		return
+	}
+	if op == nil {
+		h.OutMsgCh <- tgbotapi.NewMessage(chatId, "There is nothing to undo")
+		return
+	}
+
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, fmt.Sprintf("Undone: %s\n%s", describeOperation(*op, wallet), constructBalanceMessage(wallet)))
+}
+
+// undoRestricted tells whether reverting op changes regular transactions or settings, which could be restricted to administrators
+func undoRestricted(op budget.Operation) bool {
+	switch op.Type {
+	case budget.OperationAddRegular, budget.OperationRemoveRegular, budget.OperationSetMonthStart:
+		return true
+	}
+	return false
+}
+
+func describeOperation(op budget.Operation, w *budget.Wallet) string {
+	decimals := w.Decimals
+	switch op.Type {
+	case budget.OperationAddActual:
+		return fmt.Sprintf("transaction of %s from %s", formatAmount(op.Actual.Value, decimals, op.Actual.Currency), w.LocalTime(op.Actual.Time).Format("2006-01-02 15:04"))
+	case budget.OperationAddRegular:
+		return fmt.Sprintf("added regular transaction %s #%s at %s", formatAmount(op.Regular.Value, decimals, op.Regular.Currency), op.Regular.Label, op.Regular.Schedule())
+	case budget.OperationRemoveRegular:
+		return fmt.Sprintf("removal of regular transaction %s #%s at %s", formatAmount(op.Regular.Value, decimals, op.Regular.Currency), op.Regular.Label, op.Regular.Schedule())
+	case budget.OperationSetMonthStart:
+		return fmt.Sprintf("month start change, it is back to %s", budget.FormatMonthDay(op.MonthStart))
+	}
+	return string(op.Type)
+}
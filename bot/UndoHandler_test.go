@@ -0,0 +1,53 @@
+package bot
+
+import "time"
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestUndoRestrictedToAdmins(t *testing.T) {
+	s := budget.NewRamStorage()
+	group := int64(-100)
+	w, err := s.CreateWalletOwner(budget.OwnerId(group))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := s.SetOwnerAdminsOnly(budget.OwnerId(group), true); err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*budget.NewRegularTransaction(1000, 5, "salary")); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*budget.NewActualTransaction(-500, time.Now(), "food", "")); err != nil {
+		t.FailNow()
+	}
+
+	outCh := make(chan tgbotapi.Chattable, 1)
+	h := NewUndoHandler(s, testAdminChecker{1: true})
+	h.Init(outCh, nil)
+	undo := func(userId int) string {
+		msg := newTestGroupMessage(group, userId, "/undo")
+		msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(msg.Text)}}
+		h.HandleOne(msg)
+		return (<-outCh).(tgbotapi.MessageConfig).Text
+	}
+
+	// transactions could be undone by anyone
+	if reply := undo(2); !strings.HasPrefix(reply, "Undone: transaction of") {
+		t.Errorf("Transaction has not been undone by non-admin: %s", reply)
+	}
+	if reply := undo(2); !strings.HasPrefix(reply, "Could not undo added regular transaction") {
+		t.Errorf("Regular transaction change has been undone by non-admin: %s", reply)
+	}
+	if regular, err := s.GetRegularTransactions(w.ID); err != nil || len(regular) != 1 {
+		t.Errorf("Regular transactions have been changed after rejected undo: %+v", regular)
+	}
+	if reply := undo(1); !strings.HasPrefix(reply, "Undone: added regular transaction") {
+		t.Errorf("Regular transaction change has not been undone by admin: %s", reply)
+	}
+	if regular, err := s.GetRegularTransactions(w.ID); err != nil || len(regular) != 0 {
+		t.Errorf("Regular transaction is still present after undo: %+v", regular)
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import "log"
+import "fmt"
+import "strings"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+import "github.com/admirallarimda/tgbotbase"
+
+const walletCmd = "wallet"
+
+// walletHandler manages named wallets of an owner: '/wallet new NAME' creates a wallet,
+// '/wallet use NAME' makes it active and '/wallet list' (or just '/wallet') lists all of them
+type walletHandler struct {
+	baseHandler
+	admins ChatAdminChecker
+}
+
+func NewWalletHandler(storage budget.Storage, admins ChatAdminChecker) tgbotbase.IncomingMessageHandler {
+	h := &walletHandler{admins: admins}
+	h.storage = storage
+	return h
+}
+
+func (h *walletHandler) Init(outMsgCh chan<- tgbotapi.Chattable, srvCh chan<- tgbotbase.ServiceMsg) tgbotbase.HandlerTrigger {
+	h.OutMsgCh = outMsgCh
+	return tgbotbase.NewHandlerTrigger(nil, []string{walletCmd})
+}
+
+func (h *walletHandler) Name() string {
+	return "named wallets"
+}
+
+func (h *walletHandler) HandleOne(msg tgbotapi.Message) {
+	log.Printf("Wallet request received from %s; text: %s", dumpMsgUserInfo(msg), msg.Text)
+	chatId := msg.Chat.ID
+	ownerId := budget.OwnerId(chatId)
+
+	args := strings.Fields(msg.CommandArguments())
+	var reply string
+	var err error
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "list"):
+		reply, err = h.list(ownerId)
+	case len(args) == 2 && args[0] == "new":
+		if err = checkChangeAllowed(h.storage, h.admins, msg); err == nil {
+			reply, err = h.create(ownerId, args[1])
+		}
+	case len(args) == 2 && args[0] == "use":
+		if err = checkChangeAllowed(h.storage, h.admins, msg); err == nil {
+			reply, err = h.use(ownerId, args[1])
+		}
+	default:
+		reply = fmt.Sprintf("Usage: '/%s new NAME', '/%s use NAME' or '/%s list'", walletCmd, walletCmd, walletCmd)
+	}
+	if err != nil {
+		log.Printf("Could not process wallet request of %s due to error: %s", dumpMsgUserInfo(msg), err)
+		reply = fmt.Sprintf("Could not process /%s: %s", walletCmd, err)
+	}
+	h.OutMsgCh <- tgbotapi.NewMessage(chatId, reply)
+}
+
+func (h *walletHandler) list(ownerId budget.OwnerId) (string, error) {
+	active, err := budget.GetWalletForOwner(ownerId, true, h.storage)
+	if err != nil {
+		return "", err
+	}
+	wallets, err := budget.GetOwnerWallets(ownerId, h.storage)
+	if err != nil {
+		return "", err
+	}
+	result := "Your wallets:"
+	for _, w := range wallets {
+		result += "\n" + w.Name
+		if w.ID == active.ID {
+			result += " (active)"
+		}
+	}
+	return result, nil
+}
+
+func (h *walletHandler) create(ownerId budget.OwnerId, name string) (string, error) {
+	if _, err := budget.CreateNamedWallet(ownerId, name, h.storage); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wallet '%s' has been created. Use '/%s use %s' to make it active or add '@%s' to a transaction to put it there", name, walletCmd, name, name), nil
+}
+
+func (h *walletHandler) use(ownerId budget.OwnerId, name string) (string, error) {
+	wallet, err := budget.UseWallet(ownerId, name, h.storage)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wallet '%s' is active now\n%s", wallet.Name, constructBalanceMessage(wallet)), nil
+}
@@ -0,0 +1,79 @@
+package bot
+
+import "strings"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func newTestCommand(chatId int64, text string) tgbotapi.Message {
+	msg := newTestMessage(chatId, 0, text, false)
+	msg.Chat.Type = "private"
+	length := strings.Index(text, " ")
+	if length < 0 {
+		length = len(text)
+	}
+	msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	return msg
+}
+
+func TestSplitWalletOverride(t *testing.T) {
+	cases := []struct {
+		text, rest, name string
+	}{
+		{"300 #taxi", "300 #taxi", ""},
+		{"300 #taxi @business", "300 #taxi", "business"},
+		{"300@vacation-fund", "300", "vacation-fund"},
+	}
+	for _, c := range cases {
+		if rest, name := splitWalletOverride(c.text); rest != c.rest || name != c.name {
+			t.Errorf("'%s' split into '%s' and '%s'", c.text, rest, name)
+		}
+	}
+}
+
+func TestNamedWallets(t *testing.T) {
+	s := budget.NewRamStorage()
+	outCh := make(chan tgbotapi.Chattable, 10)
+	wallets := NewWalletHandler(s, nil)
+	wallets.Init(outCh, nil)
+	transactions := NewTransactionHandler(s)
+	transactions.Init(outCh, nil)
+
+	wallets.HandleOne(newTestCommand(1, "/wallet new business"))
+	<-outCh
+	transactions.HandleOne(newTestMessage(1, 1, "300 #taxi @business", false))
+	<-outCh
+	transactions.HandleOne(newTestMessage(1, 2, "50", false))
+	<-outCh
+	transactions.HandleOne(newTestMessage(1, 3, "70 @unknown", false))
+	<-outCh
+
+	main, err := s.GetWalletForOwner(budget.OwnerId(1), false)
+	if err != nil {
+		t.FailNow()
+	}
+	business, err := budget.GetOwnerWallet(budget.OwnerId(1), "business", s)
+	if err != nil {
+		t.FailNow()
+	}
+	if tx, err := s.GetActualTransactionByMessage(business.ID, 1, 1); err != nil || tx.Value != -30000 || tx.Label != "taxi" {
+		t.Errorf("Transaction has not been added to named wallet: %+v", tx)
+	}
+	if tx, err := s.GetActualTransactionByMessage(main.ID, 1, 2); err != nil || tx.Value != -5000 {
+		t.Errorf("Transaction has not been added to active wallet: %+v", tx)
+	}
+	if tx, err := s.GetActualTransactionByMessage(main.ID, 1, 3); err == nil {
+		t.Errorf("Transaction for unknown wallet has been added: %+v", tx)
+	}
+
+	wallets.HandleOne(newTestCommand(1, "/wallet use business"))
+	<-outCh
+	if active, err := s.GetWalletForOwner(budget.OwnerId(1), false); err != nil || active.ID != business.ID {
+		t.Errorf("Wallet has not been activated: %+v", active)
+	}
+	wallets.HandleOne(newTestCommand(1, "/wallet"))
+	if reply := (<-outCh).(tgbotapi.MessageConfig).Text; reply != "Your wallets:\nbusiness (active)\nmain" {
+		t.Errorf("Unexpected list of wallets: %s", reply)
+	}
+}
@@ -0,0 +1,61 @@
+package bot
+
+import "log"
+import "errors"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+// ChatAdminChecker tells whether a user is an administrator of a group chat
+type ChatAdminChecker interface {
+	IsChatAdmin(chatId int64, userId int) (bool, error)
+}
+
+type telegramAdminChecker struct {
+	api *tgbotapi.BotAPI
+}
+
+// NewTelegramAdminChecker asks Telegram about chat administrators
+func NewTelegramAdminChecker(api *tgbotapi.BotAPI) ChatAdminChecker {
+	return &telegramAdminChecker{api: api}
+}
+
+func (c *telegramAdminChecker) IsChatAdmin(chatId int64, userId int) (bool, error) {
+	member, err := c.api.GetChatMember(tgbotapi.ChatConfigWithUser{ChatID: chatId, UserID: userId})
+	if err != nil {
+		return false, err
+	}
+	return member.IsCreator() || member.IsAdministrator(), nil
+}
+
+// checkIsAdmin fails if the message comes from a group chat and its author is not an administrator there
+func checkIsAdmin(checker ChatAdminChecker, msg tgbotapi.Message) error {
+	if msg.Chat.IsPrivate() {
+		return nil
+	}
+	if checker == nil || msg.From == nil {
+		return errors.New("Administrators of the chat cannot be verified")
+	}
+	isAdmin, err := checker.IsChatAdmin(msg.Chat.ID, msg.From.ID)
+	if err != nil {
+		log.Printf("Could not check whether %s is an administrator due to error: %s", dumpMsgUserInfo(msg), err)
+		return errors.New("Administrators of the chat cannot be verified")
+	}
+	if !isAdmin {
+		return errors.New("Only administrators of the chat are allowed to do it")
+	}
+	return nil
+}
+
+// checkChangeAllowed fails if changes of regular transactions and settings are restricted to administrators
+// of the chat and the message author is not one of them
+func checkChangeAllowed(storage budget.Storage, checker ChatAdminChecker, msg tgbotapi.Message) error {
+	adminsOnly, err := storage.GetOwnerAdminsOnly(budget.OwnerId(msg.Chat.ID))
+	if err != nil {
+		return err
+	}
+	if !adminsOnly {
+		return nil
+	}
+	return checkIsAdmin(checker, msg)
+}
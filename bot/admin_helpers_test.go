@@ -0,0 +1,56 @@
+package bot
+
+import "errors"
+import "testing"
+import "gopkg.in/telegram-bot-api.v4"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+type testAdminChecker map[int]bool
+
+func (c testAdminChecker) IsChatAdmin(chatId int64, userId int) (bool, error) {
+	if userId < 0 {
+		return false, errors.New("Telegram is unavailable")
+	}
+	return c[userId], nil
+}
+
+func newTestGroupMessage(chatId int64, userId int, text string) tgbotapi.Message {
+	return tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: chatId, Type: "group"},
+		From: &tgbotapi.User{ID: userId},
+		Text: text}
+}
+
+func TestCheckChangeAllowed(t *testing.T) {
+	s := budget.NewRamStorage()
+	admins := testAdminChecker{1: true}
+
+	if err := checkChangeAllowed(s, admins, newTestGroupMessage(-100, 2, "/regular")); err != nil {
+		t.Errorf("Change must be allowed to everyone by default: %s", err)
+	}
+	if err := s.SetOwnerAdminsOnly(budget.OwnerId(-100), true); err != nil {
+		t.FailNow()
+	}
+	if err := checkChangeAllowed(s, admins, newTestGroupMessage(-100, 1, "/regular")); err != nil {
+		t.Errorf("Change must be allowed to admin: %s", err)
+	}
+	if err := checkChangeAllowed(s, admins, newTestGroupMessage(-100, 2, "/regular")); err == nil {
+		t.Errorf("Change has been allowed to non-admin")
+	}
+	if err := checkChangeAllowed(s, admins, newTestGroupMessage(-100, -1, "/regular")); err == nil {
+		t.Errorf("Change has been allowed when admins could not be checked")
+	}
+	if err := checkChangeAllowed(s, nil, newTestGroupMessage(-100, 1, "/regular")); err == nil {
+		t.Errorf("Change has been allowed without admin checker")
+	}
+
+	private := newTestMessage(5, 1, "/regular", false)
+	private.Chat.Type = "private"
+	if err := s.SetOwnerAdminsOnly(budget.OwnerId(5), true); err != nil {
+		t.FailNow()
+	}
+	if err := checkChangeAllowed(s, nil, private); err != nil {
+		t.Errorf("Change must be allowed in private chats: %s", err)
+	}
+}
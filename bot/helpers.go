@@ -1,8 +1,12 @@
 package bot
 
 import "fmt"
+import "log"
+import "strings"
 import "gopkg.in/telegram-bot-api.v4"
 
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
 func dumpMsgUserInfo(msg tgbotapi.Message) string {
 	return fmt.Sprintf("chat ID: %d (type '%s'), message issued by user ID: %d (username: '%s')", msg.Chat.ID,
 		msg.Chat.Type,
@@ -22,3 +26,32 @@ func uniqueInts(list []int) []int {
 	}
 	return result
 }
+
+// userDisplayName returns a name which telegram user is mentioned with in statistics
+func userDisplayName(u *tgbotapi.User) string {
+	if u.UserName != "" {
+		return "@" + u.UserName
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// rememberUserName keeps name of the message author so that transactions could be attributed to it
+func rememberUserName(storage budget.Storage, msg tgbotapi.Message) {
+	if msg.From == nil {
+		return
+	}
+	if err := storage.SetUserName(msg.From.ID, userDisplayName(msg.From)); err != nil {
+		log.Printf("Could not remember name of %s due to error: %s", dumpMsgUserInfo(msg), err)
+	}
+}
+
+// memberName returns name of the user which transactions are attributed to
+func memberName(storage budget.Storage, userId int) string {
+	if userId == 0 {
+		return "unknown member"
+	}
+	if name, err := storage.GetUserName(userId); err == nil && name != "" {
+		return name
+	}
+	return fmt.Sprintf("user %d", userId)
+}
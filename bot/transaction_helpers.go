@@ -2,17 +2,174 @@ package bot
 
 import "time"
 import "fmt"
+import "sort"
+import "errors"
+import "regexp"
+import "strconv"
+import "strings"
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 
+// lastTransactionsPeriod limits how far in the past transactions are looked up for /last and references to its items
+const lastTransactionsPeriod = time.Hour * 24 * 30
+
 func constructIncomeMessage(w *budget.Wallet) string {
 	plannedIncomeMsg := ""
-	if plannedIncome, err := w.GetPlannedMonthlyIncome(); err == nil {
-		if correctedMonthlyIncome, correctedDailyIncome, err := w.GetCorrectedMonthlyIncome(time.Now()); err == nil {
-			plannedIncomeMsg = fmt.Sprintf("Planned monthly income: %d", plannedIncome)
+	if plannedIncome, err := w.GetPlannedMonthlyIncome(w.Now()); err == nil {
+		if correctedMonthlyIncome, correctedDailyIncome, err := w.GetCorrectedMonthlyIncome(w.Now()); err == nil {
+			plannedIncomeMsg = fmt.Sprintf("Planned monthly income: %s", formatAmount(plannedIncome, w.Decimals, w.Currency))
 			if plannedIncome != correctedMonthlyIncome {
-				plannedIncomeMsg = fmt.Sprintf("%s (with corrections for current month: monthly: %d; daily: %d)", plannedIncomeMsg, correctedMonthlyIncome, correctedDailyIncome)
+				plannedIncomeMsg = fmt.Sprintf("%s (with corrections for current month: monthly: %s; daily: %s)", plannedIncomeMsg,
+					formatAmount(correctedMonthlyIncome, w.Decimals, w.Currency), formatAmount(correctedDailyIncome, w.Decimals, w.Currency))
 			}
 		}
 	}
 	return plannedIncomeMsg
 }
+
+// constructBalanceMessage returns a reply with currently available money; empty if it cannot be calculated
+func constructBalanceMessage(w *budget.Wallet) string {
+	availMoney, err := w.GetBalance(w.Now())
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("Currently available money: %s", formatAmount(availMoney, w.Decimals, w.Currency))
+}
+
+// walletOverrideRe matches a trailing '@name' which addresses transaction to a wallet other than the active one
+var walletOverrideRe *regexp.Regexp = regexp.MustCompile(" *@([\\wА-Яа-я-]+)$")
+
+// splitWalletOverride separates transaction text from the name of a wallet it is addressed to; the name is empty if it is absent
+func splitWalletOverride(text string) (string, string) {
+	matches := walletOverrideRe.FindStringSubmatchIndex(text)
+	if matches == nil {
+		return text, ""
+	}
+	return text[:matches[0]], text[matches[2]:matches[3]]
+}
+
+// resolveTransactionWallet returns a wallet which text is addressed to (the active one if there is no '@name')
+// together with the text without wallet name
+func resolveTransactionWallet(storage budget.Storage, owner budget.OwnerId, text string) (*budget.Wallet, string, error) {
+	text, name := splitWalletOverride(text)
+	if name == "" {
+		wallet, err := budget.GetWalletForOwner(owner, true, storage)
+		return wallet, text, err
+	}
+	wallet, err := budget.GetOwnerWallet(owner, name, storage)
+	return wallet, text, err
+}
+
+// currencySymbols maps symbols accepted before amount to currency codes
+var currencySymbols = map[string]budget.Currency{
+	"€": "EUR",
+	"$": "USD",
+	"£": "GBP",
+	"₽": "RUB",
+}
+
+// parseTransactionText converts text like '-500 #food', '20.50 EUR #taxi' or '€20' into transaction value in minor units, currency and label
+func parseTransactionText(text string, decimals int) (value int, currency budget.Currency, label string, err error) {
+	matches := re.FindStringSubmatch(text)
+	if matches == nil {
+		err = errors.New("Transaction should look like '500 #label', '20.50 EUR #label' or '+500'")
+		return
+	}
+
+	sign := -1 // in most cases (no sign or '-' explicitly) we should pass negative number
+	if matches[1] == "+" {
+		sign = 1
+	}
+	amount, err := budget.ParseAmount(matches[3], decimals)
+	if err != nil {
+		return
+	}
+	value = sign * amount
+
+	symbolCurrency := currencySymbols[matches[2]]
+	currency = budget.Currency(strings.ToUpper(matches[4]))
+	if symbolCurrency != "" && currency != "" && symbolCurrency != currency {
+		err = fmt.Errorf("Transaction has two different currencies: %s and %s", symbolCurrency, currency)
+		return
+	}
+	if currency == "" {
+		currency = symbolCurrency
+	}
+
+	label = matches[6] // not 5 - using label without #
+	return
+}
+
+// formatAmount prints value in minor units together with its currency if it is known
+func formatAmount(value int, decimals int, c budget.Currency) string {
+	if c == "" {
+		return budget.FormatAmount(value, decimals)
+	}
+	return fmt.Sprintf("%s %s", budget.FormatAmount(value, decimals), c)
+}
+
+// getLastTransactions returns up to n latest transactions sorted by time, the latest one is the last
+func getLastTransactions(storage budget.Storage, w *budget.Wallet, n int) ([]budget.ActualTransaction, error) {
+	// TODO: retrieve only necessary transactions for arbitrary amount of time
+	now := time.Now()
+	transactions, err := storage.GetActualTransactions(w.ID, now.Add(-lastTransactionsPeriod), now)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].Time.Before(transactions[j].Time) })
+	if n < len(transactions) {
+		transactions = transactions[len(transactions)-n:]
+	}
+	return transactions, nil
+}
+
+// findLastTransaction resolves a reference to transaction which is either its index in /last output (1 is the latest one) or its ID
+func findLastTransaction(storage budget.Storage, w *budget.Wallet, ref string) (*budget.ActualTransaction, error) {
+	index, err := strconv.Atoi(ref)
+	if err != nil {
+		return storage.GetActualTransaction(w.ID, budget.TransactionId(ref))
+	}
+
+	if index < 1 {
+		return nil, fmt.Errorf("There is no transaction number %d in /last list", index)
+	}
+	transactions, err := getLastTransactions(storage, w, index)
+	if err != nil {
+		return nil, err
+	}
+	if index > len(transactions) {
+		return nil, fmt.Errorf("There is no transaction number %d in /last list", index)
+	}
+	return &transactions[0], nil
+}
+
+// deleteTransaction removes t from wallet and returns a reply describing it
+func deleteTransaction(w *budget.Wallet, t budget.ActualTransaction) (string, error) {
+	if err := w.RemoveActualTransaction(t.ID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Transaction of %s from %s has been deleted", formatAmount(t.Value, w.Decimals, t.Currency), w.LocalTime(t.Time).Format("2006-01-02 15:04")), nil
+}
+
+// amendTransaction replaces value and label of t with ones parsed from text and returns a reply describing the change
+func amendTransaction(w *budget.Wallet, t budget.ActualTransaction, text string) (string, error) {
+	amount, currency, label, err := parseTransactionText(text, w.Decimals)
+	if err != nil {
+		return "", err
+	}
+	oldAmount := formatAmount(t.Value, w.Decimals, t.Currency)
+	t.Value = amount
+	t.Currency = currency
+	t.Label = label
+	t.RawText = text
+
+	matchesRegular, err := w.UpdateActualTransaction(t)
+	if err != nil {
+		return "", err
+	}
+	replyMsg := fmt.Sprintf("Transaction from %s has been changed from %s to %s", w.LocalTime(t.Time).Format("2006-01-02 15:04"), oldAmount, formatAmount(t.Value, w.Decimals, t.Currency))
+	if matchesRegular {
+		replyMsg = fmt.Sprintf("%s\nThis transaction matches regular transaction, thus monthly income could be modified. Current values are: %s", replyMsg, constructIncomeMessage(w))
+	}
+	return replyMsg, nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import "time"
+import "testing"
+
+import "github.com/admirallarimda/tgbot-daily-budget/budget"
+
+func TestParseTransactionText(t *testing.T) {
+	cases := []struct {
+		text     string
+		value    int
+		currency budget.Currency
+		label    string
+	}{
+		{"500", -50000, "", ""},
+		{"-500 #food", -50000, "", "food"},
+		{"+50 #refund", 5000, "", "refund"},
+		{"12.50", -1250, "", ""},
+		{"+12,5 #refund", 1250, "", "refund"},
+		{"20 eur #taxi", -2000, "EUR", "taxi"},
+		{"+€20.05", 2005, "EUR", ""},
+	}
+	for _, c := range cases {
+		value, currency, label, err := parseTransactionText(c.text, 2)
+		if err != nil || value != c.value || currency != c.currency || label != c.label {
+			t.Errorf("'%s' parsed as %d %s '%s' (error: %v)", c.text, value, currency, label, err)
+		}
+	}
+	for _, text := range []string{"50 food", "€20 USD", "12.505"} {
+		if _, _, _, err := parseTransactionText(text, 2); err == nil {
+			t.Errorf("Malformed transaction '%s' has been parsed", text)
+		}
+	}
+}
+
+func TestFindLastTransaction(t *testing.T) {
+	s := budget.NewRamStorage()
+	w, err := s.CreateWalletOwner(budget.OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	now := time.Now()
+	older := budget.NewActualTransaction(-10, now.Add(-time.Hour), "", "10")
+	latest := budget.NewActualTransaction(-20, now.Add(-time.Minute), "", "20")
+	if s.AddActualTransaction(w.ID, *latest) != nil || s.AddActualTransaction(w.ID, *older) != nil {
+		t.FailNow()
+	}
+
+	if tx, err := findLastTransaction(s, w, "1"); err != nil || tx.ID != latest.ID {
+		t.Errorf("Number 1 must refer the latest transaction, got %+v", tx)
+	}
+	if tx, err := findLastTransaction(s, w, "2"); err != nil || tx.ID != older.ID {
+		t.Errorf("Number 2 must refer the older transaction, got %+v", tx)
+	}
+	if tx, err := findLastTransaction(s, w, string(older.ID)); err != nil || tx.ID != older.ID {
+		t.Errorf("Transaction must be found by ID, got %+v", tx)
+	}
+	for _, ref := range []string{"0", "-1", "3", "no-such-id"} {
+		if tx, err := findLastTransaction(s, w, ref); err == nil {
+			t.Errorf("Reference '%s' must not be resolved, got %+v", ref, tx)
+		}
+	}
+}
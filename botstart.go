@@ -1,15 +1,36 @@
 package main
 
+import "os"
 import "log"
+import "net/url"
+import "net/http"
 import "gopkg.in/gcfg.v1"
+import "gopkg.in/telegram-bot-api.v4"
 import "github.com/admirallarimda/tgbotbase"
 
 import "github.com/admirallarimda/tgbot-daily-budget/bot"
 import "github.com/admirallarimda/tgbot-daily-budget/budget"
 
+type storageConfig struct {
+	Type string // one of: redis (default), sqlite, postgres, ram
+	Path string // database file for sqlite
+	Dsn  string // connection string for postgres
+}
+
+type currencyConfig struct {
+	Rates string // file with exchange rates, lines like 'EUR RUB 75.5'; rates set via /rate are saved there
+}
+
+type calendarConfig struct {
+	Holidays string // file with holidays, lines like '2018-05-09' or yearly '12-25'; weekends are always days off
+}
+
 type config struct {
 	tgbotbase.Config
-	Redis tgbotbase.RedisConfig
+	Storage  storageConfig
+	Currency currencyConfig
+	Calendar calendarConfig
+	Redis    tgbotbase.RedisConfig
 }
 
 func readGcfg(filename string) config {
@@ -27,25 +48,125 @@ func readGcfg(filename string) config {
 	return cfg
 }
 
+// storageFactory returns a function which provides storage connection for each handler
+func storageFactory(cfg config) func() budget.Storage {
+	switch cfg.Storage.Type {
+	case "", "redis":
+		log.Print("Using Redis storage")
+		pool := tgbotbase.NewRedisPool(cfg.Redis)
+		if err := budget.MigrateStorage(pool); err != nil {
+			log.Panicf("Could not migrate Redis storage due to error: %s", err)
+		}
+		return func() budget.Storage { return budget.CreateStorageConnection(pool) }
+	case "sqlite":
+		log.Printf("Using SQLite storage at %s", cfg.Storage.Path)
+		storage, err := budget.NewSQLiteStorage(cfg.Storage.Path)
+		if err != nil {
+			log.Panicf("Could not open SQLite storage at %s due to error: %s", cfg.Storage.Path, err)
+		}
+		return func() budget.Storage { return storage }
+	case "postgres":
+		log.Print("Using PostgreSQL storage")
+		storage, err := budget.NewPostgresStorage(cfg.Storage.Dsn)
+		if err != nil {
+			log.Panicf("Could not open PostgreSQL storage due to error: %s", err)
+		}
+		return func() budget.Storage { return storage }
+	case "ram":
+		log.Print("Using in-memory storage; all data will be lost on exit")
+		storage := budget.NewRamStorage()
+		return func() budget.Storage { return storage }
+	}
+	log.Panicf("Unknown storage type '%s'", cfg.Storage.Type)
+	return nil
+}
+
+// loadRates prepares exchange rates table used for conversion of transactions in other currencies
+func loadRates(cfg config) *budget.RateTable {
+	path := cfg.Currency.Rates
+	if path == "" {
+		log.Print("No exchange rates file is configured, rates set via /rate will be lost on exit")
+		return budget.NewRateTable()
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		log.Printf("Exchange rates file %s does not exist yet, starting with empty rates", path)
+		return budget.NewRateTable()
+	}
+	rates, err := budget.LoadRateTable(path)
+	if err != nil {
+		log.Panicf("Could not load exchange rates from %s due to error: %s", path, err)
+	}
+	return rates
+}
+
+// loadHolidays prepares calendar used for moving regular transactions from days off
+func loadHolidays(cfg config) *budget.HolidayTable {
+	path := cfg.Calendar.Holidays
+	if path == "" {
+		log.Print("No holidays file is configured, only weekends are considered days off")
+		return budget.NewHolidayTable()
+	}
+	holidays, err := budget.LoadHolidayTable(path)
+	if err != nil {
+		log.Panicf("Could not load holidays from %s due to error: %s", path, err)
+	}
+	return holidays
+}
+
+// newTelegramAPI connects to Telegram API; updates are dispatched by bot.Dispatcher as tgbotbase passes only new messages to handlers
+func newTelegramAPI(cfg config) *tgbotapi.BotAPI {
+	client := &http.Client{}
+	if cfg.Proxy_SOCKS5.Server != "" {
+		proxyURL := &url.URL{Scheme: "socks5", Host: cfg.Proxy_SOCKS5.Server}
+		if cfg.Proxy_SOCKS5.User != "" {
+			proxyURL.User = url.UserPassword(cfg.Proxy_SOCKS5.User, cfg.Proxy_SOCKS5.Pass)
+		}
+		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	}
+	api, err := tgbotapi.NewBotAPIWithClient(cfg.TGBot.Token, client)
+	if err != nil {
+		log.Panicf("Could not connect to Telegram API due to error: %s", err)
+	}
+	return api
+}
+
 func main() {
 	log.Print("Starting daily budget bot")
 
 	cfg := readGcfg("bot.cfg")
-	botCfg := tgbotbase.Config{TGBot: cfg.TGBot, Proxy_SOCKS5: cfg.Proxy_SOCKS5}
-	tgbot := tgbotbase.NewBot(botCfg)
+	api := newTelegramAPI(cfg)
+	tgbot := bot.NewDispatcher(api)
 
-	pool := tgbotbase.NewRedisPool(cfg.Redis)
+	newStorage := storageFactory(cfg)
+	rates := loadRates(cfg)
+	budget.SetRateProvider(rates)
+	budget.SetHolidayCalendar(loadHolidays(cfg))
+	admins := bot.NewTelegramAdminChecker(api)
+	reminder := bot.NewDailyReminder(newStorage())
 
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewTransactionHandler(budget.CreateStorageConnection(pool))))
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewRegularTransactionHandler(budget.CreateStorageConnection(pool))))
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewStartHandler(budget.CreateStorageConnection(pool))))
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewWalletSettingsHandler(budget.CreateStorageConnection(pool))))
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewLastTransactionsHandler(budget.CreateStorageConnection(pool))))
-	tgbot.AddHandler(tgbotbase.NewIncomingMessageDealer(bot.NewStatsHandler(budget.CreateStorageConnection(pool))))
+	tgbot.AddHandler(bot.NewTransactionHandler(newStorage()))
+	tgbot.AddEditedMessageHandler(bot.NewEditedMessageHandler(newStorage()))
+	tgbot.AddHandler(bot.NewRegularTransactionHandler(newStorage(), admins))
+	tgbot.AddHandler(bot.NewStartHandler(newStorage()))
+	tgbot.AddHandler(bot.NewWalletSettingsHandler(newStorage(), admins, reminder))
+	tgbot.AddHandler(bot.NewLastTransactionsHandler(newStorage()))
+	tgbot.AddHandler(bot.NewTransactionEditHandler(newStorage()))
+	tgbot.AddHandler(bot.NewUndoHandler(newStorage(), admins))
+	tgbot.AddHandler(bot.NewShareHandler(newStorage(), reminder))
+	tgbot.AddHandler(bot.NewWalletHandler(newStorage(), admins))
+	tgbot.AddHandler(bot.NewRateHandler(rates, cfg.Currency.Rates))
+	tgbot.AddHandler(bot.NewStatsHandler(newStorage()))
+	tgbot.AddHandler(bot.NewReportHandler(newStorage()))
+	tgbot.AddHandler(bot.NewGoalHandler(newStorage(), admins))
+	tgbot.AddHandler(bot.NewLimitHandler(newStorage(), admins))
 
-	tgbot.AddHandler(tgbotbase.NewBackgroundMessageDealer(bot.NewDailyReminder(budget.CreateStorageConnection(pool))))
+	tgbot.AddBackgroundHandler(reminder)
 
-	tgbot.Start()
+	updates, err := api.GetUpdatesChan(tgbotapi.UpdateConfig{Timeout: 60})
+	if err != nil {
+		log.Panicf("Could not start receiving updates due to error: %s", err)
+	}
+	tgbot.Start(updates)
 
 	log.Print("Daily budget bot has stopped")
 }
@@ -7,6 +7,12 @@ func CreateStorageConnection(pool tgbotbase.RedisPool) Storage {
 	return NewRedisStorage(pool.GetConnByName("budget"))
 }
 
+// MigrateStorage brings data in Redis up to date; it should be called once before any storage connection is used
+func MigrateStorage(pool tgbotbase.RedisPool) error {
+	s := &RedisStorage{client: pool.GetConnByName("budget")}
+	return s.migrate()
+}
+
 func GetWalletForOwner(owner OwnerId, createIfAbsent bool, storageconn Storage) (*Wallet, error) {
 	log.Printf("Acquiring wallet for owner %d", owner)
 	wallet, err := storageconn.GetWalletForOwner(owner, createIfAbsent)
@@ -15,5 +21,6 @@ func GetWalletForOwner(owner OwnerId, createIfAbsent bool, storageconn Storage)
 		return nil, err
 	}
 
+	wallet.Location = OwnerLocation(owner, storageconn)
 	return wallet, nil
 }
@@ -0,0 +1,78 @@
+package budget
+
+import "fmt"
+import "errors"
+import "regexp"
+import "strconv"
+
+// Amounts are stored as integers in minor units of a wallet, e.g. cents if wallet has 2 decimals
+const defaultDecimals = 2
+const maxDecimals = 4
+
+var amountRe *regexp.Regexp = regexp.MustCompile(`^(\d+)(?:[.,](\d+))?$`)
+
+func pow10(n int) int {
+	result := 1
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// ParseAmount converts non-negative text like '12', '12.5' or '12,50' into minor units for given number of decimals
+func ParseAmount(text string, decimals int) (int, error) {
+	matches := amountRe.FindStringSubmatch(text)
+	if matches == nil {
+		return 0, fmt.Errorf("'%s' is not an amount", text)
+	}
+	if len(matches[2]) > decimals {
+		if decimals == 0 {
+			return 0, fmt.Errorf("Amount '%s' should not have a fractional part", text)
+		}
+		return 0, fmt.Errorf("Amount '%s' has more than %d digits after decimal point", text, decimals)
+	}
+	units, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, err
+	}
+	fraction := 0
+	if matches[2] != "" {
+		if fraction, err = strconv.Atoi(matches[2]); err != nil {
+			return 0, err
+		}
+	}
+	return units*pow10(decimals) + fraction*pow10(decimals-len(matches[2])), nil
+}
+
+// FormatAmount prints value in minor units with given number of decimals, e.g. -1250 with 2 decimals as '-12.50'
+func FormatAmount(value int, decimals int) string {
+	if decimals <= 0 {
+		return strconv.Itoa(value)
+	}
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	unit := pow10(decimals)
+	return fmt.Sprintf("%s%d.%0*d", sign, value/unit, decimals, value%unit)
+}
+
+// rescaleAmount converts value in minor units from one number of decimals to another rounding half away from zero
+func rescaleAmount(value int, from, to int) int {
+	if to >= from {
+		return value * pow10(to-from)
+	}
+	divisor := pow10(from - to)
+	if value < 0 {
+		return -((-value + divisor/2) / divisor)
+	}
+	return (value + divisor/2) / divisor
+}
+
+func validateDecimals(decimals int) error {
+	if decimals < 0 || decimals > maxDecimals {
+		return errors.New(fmt.Sprintf("Number of decimals should be between 0 and %d", maxDecimals))
+	}
+	return nil
+}
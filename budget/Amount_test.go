@@ -0,0 +1,51 @@
+package budget
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		text     string
+		decimals int
+		value    int
+	}{
+		{"12", 2, 1200},
+		{"12.5", 2, 1250},
+		{"12,50", 2, 1250},
+		{"0.05", 2, 5},
+		{"12", 0, 12},
+		{"1.5", 3, 1500},
+	}
+	for _, c := range cases {
+		if value, err := ParseAmount(c.text, c.decimals); err != nil || value != c.value {
+			t.Errorf("'%s' with %d decimals parsed as %d instead of %d (error: %v)", c.text, c.decimals, value, c.value, err)
+		}
+	}
+	for _, text := range []string{"12.505", "12.", ".5", "-12", "12 50"} {
+		if _, err := ParseAmount(text, 2); err == nil {
+			t.Errorf("Malformed amount '%s' has been parsed", text)
+		}
+	}
+	if _, err := ParseAmount("12.5", 0); err == nil {
+		t.Errorf("Fractional amount has been parsed for wallet without decimals")
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	cases := []struct {
+		value    int
+		decimals int
+		text     string
+	}{
+		{1250, 2, "12.50"},
+		{-1205, 2, "-12.05"},
+		{-5, 2, "-0.05"},
+		{0, 2, "0.00"},
+		{12, 0, "12"},
+		{-12, 0, "-12"},
+	}
+	for _, c := range cases {
+		if text := FormatAmount(c.value, c.decimals); text != c.text {
+			t.Errorf("%d with %d decimals formatted as '%s' instead of '%s'", c.value, c.decimals, text, c.text)
+		}
+	}
+}
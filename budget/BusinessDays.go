@@ -0,0 +1,144 @@
+package budget
+
+import "os"
+import "fmt"
+import "log"
+import "time"
+import "bufio"
+import "strings"
+
+// BusinessDayShift tells where a regular transaction is moved when its date is not a business day
+type BusinessDayShift string
+
+const (
+	ShiftNone     BusinessDayShift = ""
+	ShiftPrevious BusinessDayShift = "prev"
+	ShiftNext     BusinessDayShift = "next"
+)
+
+// maxShiftDays limits how far a date could be moved, so that a calendar full of holidays does not hang calculations
+const maxShiftDays = 14
+
+// ValidateShift checks that shift is one of known rules
+func ValidateShift(s BusinessDayShift) error {
+	switch s {
+	case ShiftNone, ShiftPrevious, ShiftNext:
+		return nil
+	}
+	return fmt.Errorf("Unknown business day shift '%s'", s)
+}
+
+// HolidayCalendar tells which days are not business days besides weekends
+type HolidayCalendar interface {
+	IsHoliday(day time.Time) bool
+}
+
+var holidayCalendar HolidayCalendar = NewHolidayTable()
+
+// SetHolidayCalendar replaces calendar which is used for moving regular transactions to business days
+func SetHolidayCalendar(c HolidayCalendar) {
+	holidayCalendar = c
+}
+
+// HolidayTable is a HolidayCalendar with listed days: either exact dates or dates repeated every year
+type HolidayTable struct {
+	dates  map[string]bool // '2006-01-02'
+	yearly map[string]bool // '01-02'
+}
+
+func NewHolidayTable() *HolidayTable {
+	return &HolidayTable{dates: make(map[string]bool, 0), yearly: make(map[string]bool, 0)}
+}
+
+// Add marks a date like '2018-05-09' or a yearly date like '12-25' as a holiday
+func (t *HolidayTable) Add(date string) error {
+	if _, err := time.Parse("2006-01-02", date); err == nil {
+		t.dates[date] = true
+		return nil
+	}
+	if _, err := time.Parse("01-02", date); err == nil {
+		t.yearly[date] = true
+		return nil
+	}
+	return fmt.Errorf("Holiday '%s' should look like '2018-05-09' or '12-25'", date)
+}
+
+// LoadHolidayTable reads holidays from file with lines like '2018-05-09 Victory day' or '12-25 Christmas';
+// empty lines and lines starting with '#' are skipped
+func LoadHolidayTable(path string) (*HolidayTable, error) {
+	log.Printf("Loading holidays from %s", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Could not open holidays file %s due to error: %s", path, err)
+		return nil, err
+	}
+	defer f.Close()
+
+	t := NewHolidayTable()
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := t.Add(strings.Fields(line)[0]); err != nil {
+			return nil, fmt.Errorf("Line %d of %s: %s", lineNum, path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *HolidayTable) IsHoliday(day time.Time) bool {
+	return t.dates[day.Format("2006-01-02")] || t.yearly[day.Format("01-02")]
+}
+
+// IsBusinessDay checks that day is neither a weekend nor a holiday
+func IsBusinessDay(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !holidayCalendar.IsHoliday(day)
+}
+
+// shiftToBusinessDay moves day to the closest business day in direction of shift
+func shiftToBusinessDay(day time.Time, shift BusinessDayShift) time.Time {
+	step := 0
+	switch shift {
+	case ShiftPrevious:
+		step = -1
+	case ShiftNext:
+		step = 1
+	default:
+		return day
+	}
+	for i := 0; i < maxShiftDays && !IsBusinessDay(day); i++ {
+		day = day.AddDate(0, 0, step)
+	}
+	return day
+}
+
+// DueOn checks whether the transaction is expected at the day of t considering its move to a business day
+func (t RegularTransaction) DueOn(day time.Time) bool {
+	for _, nominal := range t.occurrences(day.AddDate(0, 0, -maxShiftDays), day.AddDate(0, 0, maxShiftDays+1)) {
+		if daysBetween(shiftToBusinessDay(nominal, t.Shift), day) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// paidInPeriod checks whether actual transaction paying the regular one belongs to period p. A payment made between
+// shifted and nominal dates of the regular transaction pays for the nominal one, otherwise it is attributed by its time
+func (t RegularTransaction) paidInPeriod(tx ActualTransaction, p Period) bool {
+	day := tx.Time.In(p.Start.Location())
+	for _, nominal := range t.occurrences(day.AddDate(0, 0, -maxShiftDays), day.AddDate(0, 0, maxShiftDays+1)) {
+		shifted := shiftToBusinessDay(nominal, t.Shift)
+		if daysBetween(shifted, day) >= 0 && daysBetween(day, nominal) >= 0 || daysBetween(nominal, day) >= 0 && daysBetween(day, shifted) >= 0 {
+			return p.Contains(nominal)
+		}
+	}
+	return p.Contains(day)
+}
@@ -0,0 +1,100 @@
+package budget
+
+import "os"
+import "time"
+import "testing"
+import "io/ioutil"
+
+func TestLoadHolidayTable(t *testing.T) {
+	f, err := ioutil.TempFile("", "holidays")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# holidays\n\n2018-05-09 Victory day\n12-25 Christmas\n")
+	f.Close()
+
+	holidays, err := LoadHolidayTable(f.Name())
+	if err != nil {
+		t.Fatalf("Holidays have not been loaded: %s", err)
+	}
+	cases := map[time.Time]bool{
+		time.Date(2018, 5, 9, 0, 0, 0, 0, time.UTC):   true,
+		time.Date(2019, 5, 9, 0, 0, 0, 0, time.UTC):   false,
+		time.Date(2018, 12, 25, 0, 0, 0, 0, time.UTC): true,
+		time.Date(2030, 12, 25, 0, 0, 0, 0, time.UTC): true,
+		time.Date(2018, 12, 24, 0, 0, 0, 0, time.UTC): false,
+	}
+	for day, expected := range cases {
+		if holidays.IsHoliday(day) != expected {
+			t.Errorf("%s is expected to be a holiday: %t", day, expected)
+		}
+	}
+
+	if err := holidays.Add("2018-02-30"); err == nil {
+		t.Errorf("Incorrect date has been accepted")
+	}
+}
+
+func TestRegularTransactionDueDate(t *testing.T) {
+	holidays := NewHolidayTable()
+	holidays.Add("12-25")
+	holidays.Add("2018-11-30")
+	SetHolidayCalendar(holidays)
+	defer SetHolidayCalendar(NewHolidayTable())
+
+	cases := []struct {
+		date     int
+		shift    BusinessDayShift
+		month    time.Month
+		expected time.Time
+	}{
+		{15, ShiftPrevious, time.June, time.Date(2018, 6, 15, 0, 0, 0, 0, time.UTC)}, // Friday
+		{15, ShiftPrevious, time.September, time.Date(2018, 9, 14, 0, 0, 0, 0, time.UTC)},
+		{15, ShiftNext, time.September, time.Date(2018, 9, 17, 0, 0, 0, 0, time.UTC)},
+		{15, ShiftNone, time.September, time.Date(2018, 9, 15, 0, 0, 0, 0, time.UTC)},
+		{25, ShiftPrevious, time.December, time.Date(2018, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{1, ShiftPrevious, time.December, time.Date(2018, 11, 29, 0, 0, 0, 0, time.UTC)}, // Saturday after a holiday
+		{LastDayOfMonth, ShiftNext, time.March, time.Date(2018, 4, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		tx := NewRegularTransaction(100, c.date, "test")
+		tx.Shift = c.shift
+		for day := c.expected.AddDate(0, 0, -5); day.Before(c.expected.AddDate(0, 0, 5)); day = day.AddDate(0, 0, 1) {
+			if due := tx.DueOn(day.Add(9 * time.Hour)); due != day.Equal(c.expected) {
+				t.Errorf("Date %s with shift '%s' in %s is due on %s: %t", FormatMonthDay(c.date), c.shift, c.month, day, due)
+			}
+		}
+	}
+}
+
+func TestAvailableAmount_ShiftedRegular(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	salary := NewRegularTransaction(3000, 1, "salary")
+	salary.Shift = ShiftPrevious
+	if w.AddRegularTransaction(*salary) != nil {
+		t.FailNow()
+	}
+
+	// December 1st, 2018 is Saturday, so December salary is paid on November 30th
+	november := time.Date(2018, 11, 1, 12, 0, 0, 0, time.UTC)
+	december := time.Date(2018, 11, 30, 12, 0, 0, 0, time.UTC)
+	if _, err := w.AddTransaction(*NewActualTransaction(3000, november, "salary", "")); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*NewActualTransaction(2900, december, "salary", "")); err != nil {
+		t.FailNow()
+	}
+
+	if val, err := w.GetBalance(december); val != 3000 || err != nil {
+		t.Errorf("November: actual=%d; expected=%d", val, 3000)
+	}
+	expected := 2900 * 10 / 31 // 10 days of 31 have passed
+	if val, err := w.GetBalance(time.Date(2018, 12, 10, 12, 0, 0, 0, time.UTC)); val != expected || err != nil {
+		t.Errorf("December: actual=%d; expected=%d", val, expected)
+	}
+}
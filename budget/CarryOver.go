@@ -0,0 +1,198 @@
+package budget
+
+import "log"
+import "fmt"
+import "time"
+import "errors"
+import "strconv"
+import "strings"
+
+// CarryOverMode tells what happens with leftover of a budget period when the next one starts
+type CarryOverMode string
+
+const (
+	CarryOverNone   CarryOverMode = ""     // leftover disappears
+	CarryOverFull   CarryOverMode = "full" // surplus or overspend is added to the next period
+	CarryOverCapped CarryOverMode = "cap"  // surplus is added up to Cap, overspend is added fully
+	CarryOverGoal   CarryOverMode = "goal" // surplus is put aside for Goal, overspend is added fully
+)
+
+// CarryOver is a policy of a wallet for leftovers of its periods
+type CarryOver struct {
+	Mode  CarryOverMode
+	Cap   int       // maximal surplus carried in capped mode
+	Goal  string    // name of the goal which receives surplus in goal mode; surplus is carried fully if there is no such goal
+	Since time.Time // date which the policy has been set at; leftovers of earlier periods are not carried
+}
+
+// Carry tells what has happened with leftover of a period
+type Carry struct {
+	Leftover int // balance at the end of the period including what had been carried into it
+	Carried  int // part of leftover added to balance of the next period
+	ToGoal   int // part of leftover put aside for the goal of the policy
+}
+
+func (c CarryOver) validate() error {
+	switch c.Mode {
+	case CarryOverNone, CarryOverFull:
+	case CarryOverCapped:
+		if c.Cap < 0 {
+			return errors.New("Carry-over cap should not be negative")
+		}
+	case CarryOverGoal:
+		if c.Goal == "" {
+			return errors.New("Carry-over to a goal requires its name")
+		}
+	default:
+		return fmt.Errorf("Unknown carry-over mode '%s'", c.Mode)
+	}
+	return nil
+}
+
+// String serializes the policy like 'MODE=cap;CAP=500000;SINCE=20261016'; policy without carry-over is empty
+func (c CarryOver) String() string {
+	if c.Mode == CarryOverNone {
+		return ""
+	}
+	parts := []string{"MODE=" + string(c.Mode)}
+	switch c.Mode {
+	case CarryOverCapped:
+		parts = append(parts, "CAP="+strconv.Itoa(c.Cap))
+	case CarryOverGoal:
+		parts = append(parts, "GOAL="+c.Goal)
+	}
+	parts = append(parts, "SINCE="+c.Since.Format(recurrenceDateLayout))
+	return strings.Join(parts, ";")
+}
+
+// ParseCarryOver is the opposite of CarryOver.String
+func ParseCarryOver(text string) (CarryOver, error) {
+	c := CarryOver{}
+	if text == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(text, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return c, fmt.Errorf("Incorrect carry-over policy part '%s'", part)
+		}
+		var err error
+		switch kv[0] {
+		case "MODE":
+			c.Mode = CarryOverMode(kv[1])
+		case "CAP":
+			c.Cap, err = strconv.Atoi(kv[1])
+		case "GOAL":
+			c.Goal = kv[1]
+		case "SINCE":
+			c.Since, err = time.Parse(recurrenceDateLayout, kv[1])
+		default:
+			err = fmt.Errorf("Unknown carry-over policy part '%s'", part)
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, c.validate()
+}
+
+// rescaled returns the policy with its cap converted from one number of decimals to another
+func (c CarryOver) rescaled(from, to int) CarryOver {
+	c.Cap = rescaleAmount(c.Cap, from, to)
+	return c
+}
+
+// apply splits leftover of a period according to the policy; goalExists tells whether the goal of the policy could receive surplus
+func (c CarryOver) apply(leftover int, goalExists bool) Carry {
+	result := Carry{Leftover: leftover}
+	if c.Mode == CarryOverNone {
+		return result
+	}
+	result.Carried = leftover
+	if leftover <= 0 {
+		return result
+	}
+	switch c.Mode {
+	case CarryOverCapped:
+		if leftover > c.Cap {
+			result.Carried = c.Cap
+		}
+	case CarryOverGoal:
+		if goalExists {
+			result.Carried = 0
+			result.ToGoal = leftover
+		}
+	}
+	return result
+}
+
+// SetCarryOver changes what happens with leftovers of the wallet; leftover of the period of t is the first one affected
+func (w *Wallet) SetCarryOver(c CarryOver, t time.Time) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if c.Mode == CarryOverGoal {
+		if _, err := w.findGoal(c.Goal); err != nil {
+			return err
+		}
+	}
+	if c.Mode != CarryOverNone {
+		c.Since = goalDate(w.LocalTime(t))
+	}
+	if err := w.storage.SetWalletCarryOver(w.ID, c); err != nil {
+		log.Printf("Could not set carry-over policy of wallet '%s' to '%s' due to error: %s", w.ID, c, err)
+		return err
+	}
+	return nil
+}
+
+// GetCarryOver returns carry-over policy of the wallet
+func (w *Wallet) GetCarryOver() (CarryOver, error) {
+	return w.storage.GetWalletCarryOver(w.ID)
+}
+
+// GetCarry returns what has happened with leftover of the period preceding the period of t
+func (w *Wallet) GetCarry(t time.Time) (Carry, error) {
+	carry, _, err := w.calcCarry(t)
+	return carry, err
+}
+
+// calcCarry goes through all periods since the policy has been set till the period of t; it returns carry of the preceding period
+// together with surplus put aside for goals by names so far
+func (w *Wallet) calcCarry(t time.Time) (Carry, map[string]int, error) {
+	carry := Carry{}
+	policy, err := w.storage.GetWalletCarryOver(w.ID)
+	if err != nil {
+		log.Printf("Could not get carry-over policy of wallet '%s' due to error: %s", w.ID, err)
+		return carry, nil, err
+	}
+	if policy.Mode == CarryOverNone {
+		return carry, nil, nil
+	}
+	var goal *Goal
+	if policy.Mode == CarryOverGoal {
+		goals, err := w.storage.GetGoals(w.ID)
+		if err != nil {
+			return carry, nil, err
+		}
+		for i := range goals {
+			if goals[i].Name == policy.Goal {
+				goal = &goals[i]
+			}
+		}
+	}
+
+	period := w.Period(t)
+	loc := period.Start.Location()
+	toGoals := make(map[string]int, 1)
+	for p := w.Period(time.Date(policy.Since.Year(), policy.Since.Month(), policy.Since.Day(), 0, 0, 0, 0, loc)); p.Start.Before(period.Start); p = w.Period(p.End) {
+		leftover, err := w.calcBalance(p.Last(), carry.Carried, toGoals)
+		if err != nil {
+			return carry, nil, err
+		}
+		carry = policy.apply(leftover, goal != nil && daysBetween(goal.Created, p.Last()) >= 0)
+		toGoals[policy.Goal] += carry.ToGoal
+		log.Printf("Leftover of wallet '%s' for period from %s: %d, carried %d, put aside for goal %d", w.ID, p.Start, carry.Leftover, carry.Carried, carry.ToGoal)
+	}
+	return carry, toGoals, nil
+}
@@ -0,0 +1,132 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestCarryOverString(t *testing.T) {
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	policies := []CarryOver{
+		CarryOver{},
+		CarryOver{Mode: CarryOverFull, Since: since},
+		CarryOver{Mode: CarryOverCapped, Cap: 500000, Since: since},
+		CarryOver{Mode: CarryOverGoal, Goal: "bike", Since: since},
+	}
+	for _, c := range policies {
+		if parsed, err := ParseCarryOver(c.String()); err != nil || parsed != c {
+			t.Errorf("Policy %+v has been parsed from '%s' as %+v (error: %v)", c, c.String(), parsed, err)
+		}
+	}
+	for _, text := range []string{"MODE=all", "MODE=goal;SINCE=20261016", "MODE=full;SINCE=yesterday", "full"} {
+		if _, err := ParseCarryOver(text); err == nil {
+			t.Errorf("Policy '%s' has been accepted", text)
+		}
+	}
+}
+
+func prepareCarryOverWallet(t *testing.T) *Wallet {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*NewRegularTransaction(3100, 1, "salary")); err != nil {
+		t.FailNow()
+	}
+	// leftover of September is not carried as the policy is set in October
+	for _, tx := range []*ActualTransaction{
+		NewActualTransaction(-500, time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC), "", ""),
+		NewActualTransaction(-600, time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC), "", ""),
+	} {
+		if _, err := w.AddTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+	}
+	return w
+}
+
+func TestAvailableAmount_CarryOver(t *testing.T) {
+	set := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	nov1 := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	dec1 := time.Date(2026, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	w := prepareCarryOverWallet(t)
+	if val, err := w.GetBalance(nov1); val != 103 || err != nil {
+		t.Errorf("Balance without carry-over: actual=%d; expected=%d", val, 103)
+	}
+	if err := w.SetCarryOver(CarryOver{Mode: CarryOverFull}, set); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(set); val != 1000 || err != nil {
+		t.Errorf("Balance in period of policy change: actual=%d; expected=%d", val, 1000)
+	}
+	if val, err := w.GetBalance(nov1); val != 103+2500 || err != nil {
+		t.Errorf("Balance with surplus carried: actual=%d; expected=%d", val, 103+2500)
+	}
+	if _, err := w.AddTransaction(*NewActualTransaction(-3000, time.Date(2026, 11, 15, 12, 0, 0, 0, time.UTC), "", "")); err != nil {
+		t.FailNow()
+	}
+	// carried amount is passed further together with leftover of November
+	if carry, err := w.GetCarry(dec1); carry.Leftover != 2600 || carry.Carried != 2600 || err != nil {
+		t.Errorf("Unexpected carry into December: %+v", carry)
+	}
+	if val, err := w.GetBalance(dec1); val != 100+2600 || err != nil {
+		t.Errorf("Balance with surplus carried twice: actual=%d; expected=%d", val, 100+2600)
+	}
+
+	if err := w.SetCarryOver(CarryOver{Mode: CarryOverCapped, Cap: 1000}, set); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(nov1); val != 103+1000 || err != nil {
+		t.Errorf("Balance with capped surplus: actual=%d; expected=%d", val, 103+1000)
+	}
+	// overspend is carried fully regardless of the cap
+	if _, err := w.AddTransaction(*NewActualTransaction(-4400, time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC), "", "")); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(nov1); val != 103-1900 || err != nil {
+		t.Errorf("Balance with overspend carried: actual=%d; expected=%d", val, 103-1900)
+	}
+
+	if err := w.SetCarryOver(CarryOver{}, set); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(nov1); val != 103 || err != nil {
+		t.Errorf("Balance after carry-over is disabled: actual=%d; expected=%d", val, 103)
+	}
+}
+
+func TestAvailableAmount_CarryOverToGoal(t *testing.T) {
+	set := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	nov1 := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	w := prepareCarryOverWallet(t)
+	if err := w.SetCarryOver(CarryOver{Mode: CarryOverGoal, Goal: "reserve"}, set); err == nil {
+		t.Errorf("Surplus has been sent to unknown goal")
+	}
+	if err := w.AddGoal(*NewPercentGoal("reserve", 10, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.FailNow()
+	}
+	if err := w.SetCarryOver(CarryOver{Mode: CarryOverGoal, Goal: "reserve"}, set); err != nil {
+		t.FailNow()
+	}
+
+	// 3100 of income minus 600 spent minus 310 reserved for the goal in October
+	if carry, err := w.GetCarry(nov1); carry.Leftover != 2190 || carry.Carried != 0 || carry.ToGoal != 2190 || err != nil {
+		t.Errorf("Unexpected carry into November: %+v", carry)
+	}
+	if val, err := w.GetBalance(nov1); val != 103-10 || err != nil {
+		t.Errorf("Balance with surplus put aside: actual=%d; expected=%d", val, 103-10)
+	}
+	statuses, err := w.GetGoalStatuses(nov1)
+	if err != nil || len(statuses) != 1 || statuses[0].Saved != 2190+10 {
+		t.Errorf("Surplus has not been counted for the goal: %+v", statuses)
+	}
+
+	// surplus is carried fully once the goal is closed
+	if _, err := w.CloseGoal("reserve"); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(nov1); val != 103+2500 || err != nil {
+		t.Errorf("Balance after goal is closed: actual=%d; expected=%d", val, 103+2500)
+	}
+}
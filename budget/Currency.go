@@ -0,0 +1,151 @@
+package budget
+
+import "os"
+import "log"
+import "fmt"
+import "sync"
+import "bufio"
+import "strings"
+import "strconv"
+
+// Currency is a code like 'EUR'; empty currency of a transaction means base currency of its wallet
+type Currency string
+
+// RateProvider tells exchange rates between currencies
+type RateProvider interface {
+	// Rate returns how much of 'to' currency one unit of 'from' currency costs
+	Rate(from, to Currency) (float64, error)
+}
+
+var rateProvider RateProvider = NewRateTable()
+
+// SetRateProvider replaces provider which is used for conversion into wallets' base currencies
+func SetRateProvider(p RateProvider) {
+	rateProvider = p
+}
+
+// RateTable is a RateProvider with manually set rates. Rates in reverse direction are derived automatically
+type RateTable struct {
+	lock  sync.RWMutex
+	rates map[Currency]map[Currency]float64
+}
+
+func NewRateTable() *RateTable {
+	return &RateTable{rates: make(map[Currency]map[Currency]float64, 0)}
+}
+
+// LoadRateTable reads rates from file with lines like 'EUR RUB 75.5'; empty lines and lines starting with '#' are skipped
+func LoadRateTable(path string) (*RateTable, error) {
+	log.Printf("Loading exchange rates from %s", path)
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Could not open exchange rates file %s due to error: %s", path, err)
+		return nil, err
+	}
+	defer f.Close()
+
+	t := NewRateTable()
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("Line %d of %s should look like 'EUR RUB 75.5'", lineNum, path)
+		}
+		rate, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d of %s contains incorrect rate '%s'", lineNum, path, parts[2])
+		}
+		if err := t.SetRate(Currency(parts[0]), Currency(parts[1]), rate); err != nil {
+			return nil, fmt.Errorf("Line %d of %s: %s", lineNum, path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Save writes all directly set rates in format accepted by LoadRateTable
+func (t *RateTable) Save(path string) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Printf("Could not create exchange rates file %s due to error: %s", path, err)
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for from, rates := range t.rates {
+		for to, rate := range rates {
+			fmt.Fprintf(w, "%s %s %s\n", from, to, strconv.FormatFloat(rate, 'f', -1, 64))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SetRate sets how much of 'to' currency one unit of 'from' currency costs
+func (t *RateTable) SetRate(from, to Currency, rate float64) error {
+	if rate <= 0 {
+		return fmt.Errorf("Rate from %s to %s must be positive", from, to)
+	}
+	if from == to {
+		return fmt.Errorf("Rate could be set only between different currencies")
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// only one direction is kept so that reverse rate doesn't contradict to the new one
+	delete(t.rates[to], from)
+	if _, found := t.rates[from]; !found {
+		t.rates[from] = make(map[Currency]float64, 0)
+	}
+	t.rates[from][to] = rate
+	return nil
+}
+
+func (t *RateTable) Rate(from, to Currency) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if rate, found := t.rates[from][to]; found {
+		return rate, nil
+	}
+	if rate, found := t.rates[to][from]; found {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("No exchange rate from %s to %s", from, to)
+}
+
+// convertCurrency converts value between currencies rounding the result to the nearest integer
+func convertCurrency(value int, from, to Currency) (int, error) {
+	if from == to {
+		return value, nil
+	}
+	if from == "" || to == "" {
+		return 0, fmt.Errorf("Cannot convert %s into %s: base currency of the wallet is not set", from, to)
+	}
+	rate, err := rateProvider.Rate(from, to)
+	if err != nil {
+		log.Printf("Could not get exchange rate from %s to %s due to error: %s", from, to, err)
+		return 0, err
+	}
+	converted := float64(value) * rate
+	if converted < 0 {
+		return int(converted - 0.5), nil
+	}
+	return int(converted + 0.5), nil
+}
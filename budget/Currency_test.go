@@ -0,0 +1,126 @@
+package budget
+
+import "os"
+import "time"
+import "testing"
+import "io/ioutil"
+import "path/filepath"
+
+func TestRateTable(t *testing.T) {
+	table := NewRateTable()
+	if rate, err := table.Rate("EUR", "EUR"); rate != 1 || err != nil {
+		t.Errorf("Rate of a currency to itself must be 1, got %f", rate)
+	}
+	if _, err := table.Rate("EUR", "RUB"); err == nil {
+		t.Errorf("Unknown rate has been returned")
+	}
+
+	if table.SetRate("EUR", "RUB", 80) != nil {
+		t.FailNow()
+	}
+	if rate, err := table.Rate("EUR", "RUB"); rate != 80 || err != nil {
+		t.Errorf("Unexpected direct rate %f", rate)
+	}
+	if rate, err := table.Rate("RUB", "EUR"); rate != 1.0/80 || err != nil {
+		t.Errorf("Unexpected reverse rate %f", rate)
+	}
+
+	// setting the reverse rate replaces the previous one
+	if table.SetRate("RUB", "EUR", 0.0125/2) != nil {
+		t.FailNow()
+	}
+	if rate, err := table.Rate("EUR", "RUB"); rate != 160 || err != nil {
+		t.Errorf("Reverse rate has not been replaced: %f", rate)
+	}
+
+	if table.SetRate("EUR", "RUB", 0) == nil || table.SetRate("EUR", "EUR", 2) == nil {
+		t.Errorf("Incorrect rates have been accepted")
+	}
+}
+
+func TestRateTable_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "budget-rates")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rates")
+
+	content := "# rates for trips\nEUR RUB 80\n\nUSD RUB 65.5\n"
+	if ioutil.WriteFile(path, []byte(content), 0600) != nil {
+		t.FailNow()
+	}
+	table, err := LoadRateTable(path)
+	if err != nil {
+		t.Fatalf("Could not load rates: %s", err)
+	}
+	if rate, err := table.Rate("RUB", "USD"); rate != 1/65.5 || err != nil {
+		t.Errorf("Unexpected rate %f", rate)
+	}
+
+	if err := table.Save(path); err != nil {
+		t.Fatalf("Could not save rates: %s", err)
+	}
+	reloaded, err := LoadRateTable(path)
+	if err != nil {
+		t.Fatalf("Could not load saved rates: %s", err)
+	}
+	if rate, err := reloaded.Rate("EUR", "RUB"); rate != 80 || err != nil {
+		t.Errorf("Unexpected rate after reload %f", rate)
+	}
+
+	if ioutil.WriteFile(path, []byte("EUR RUB\n"), 0600) != nil {
+		t.FailNow()
+	}
+	if _, err := LoadRateTable(path); err == nil {
+		t.Errorf("Malformed file has been loaded")
+	}
+}
+
+func TestAvailableAmount_MultipleCurrencies(t *testing.T) {
+	rates := NewRateTable()
+	rates.SetRate("EUR", "RUB", 80)
+	SetRateProvider(rates)
+	defer SetRateProvider(NewRateTable())
+
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	taxi := NewActualTransaction(-20, t1, "taxi", "20 EUR #taxi")
+	taxi.Currency = "EUR"
+	if _, err := w.AddTransaction(*taxi); err == nil {
+		t.Errorf("Transaction in foreign currency must not be accepted without base currency of the wallet")
+	}
+
+	if err := w.SetCurrency("RUB"); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*taxi); err != nil {
+		t.Fatalf("Could not add transaction in foreign currency: %s", err)
+	}
+	if _, err := w.AddTransaction(*NewActualTransaction(-100, t1, "", "100")); err != nil {
+		t.FailNow()
+	}
+	salary := NewRegularTransaction(150, 1, "salary")
+	salary.Currency = "EUR"
+	if err := w.AddRegularTransaction(*salary); err != nil {
+		t.Fatalf("Could not add regular transaction in foreign currency: %s", err)
+	}
+	unknown := NewActualTransaction(-1, t1, "", "1 USD")
+	unknown.Currency = "USD"
+	if _, err := w.AddTransaction(*unknown); err == nil {
+		t.Errorf("Transaction in currency without exchange rate has been accepted")
+	}
+
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != 12000 || err != nil {
+		t.Errorf("Planned income: actual=%d; expected=%d", val, 12000)
+	}
+	expected := 12000/30*20 - 1600 - 100
+	if val, err := w.GetBalance(t1); val != expected || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, expected)
+	}
+}
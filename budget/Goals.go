@@ -0,0 +1,276 @@
+package budget
+
+import "log"
+import "fmt"
+import "time"
+import "errors"
+import "regexp"
+import "github.com/satori/go.uuid"
+
+// GoalId uniquely identifies a savings goal within storage
+type GoalId string
+
+func newGoalId() GoalId {
+	return GoalId(uuid.Must(uuid.NewV4()).String())
+}
+
+// Goal is money put aside every day: either Target saved evenly till Deadline or Percent of the wallet's income.
+// Top-ups are actual transactions labeled by Label of the goal, so they are spent from the balance as usual expenses
+type Goal struct {
+	ID       GoalId
+	Name     string
+	Target   int       // amount to be saved; 0 for percent goals
+	Percent  int       // share of income kept in reserve; 0 for target goals
+	Created  time.Time // date which saving starts from
+	Deadline time.Time // the last day of saving for Target; zero for percent goals
+}
+
+// GoalStatus tells how much has been put aside for a goal by some moment
+type GoalStatus struct {
+	Goal
+	Saved    int // top-ups together with automatically reserved money; percent goals count only current period
+	Reserved int // money reserved automatically in current period, it is not available for spending
+}
+
+var goalNameRe *regexp.Regexp = regexp.MustCompile("^[\\wА-Яа-я]{1,32}$")
+
+const goalLabelPrefix = "goal_"
+
+// NewTargetGoal creates a goal of saving target by deadline starting from date of created
+func NewTargetGoal(name string, target int, created, deadline time.Time) *Goal {
+	return &Goal{
+		Name:     name,
+		Target:   target,
+		Created:  goalDate(created),
+		Deadline: goalDate(deadline)}
+}
+
+// NewPercentGoal creates a goal of keeping percent of income in reserve starting from date of created
+func NewPercentGoal(name string, percent int, created time.Time) *Goal {
+	return &Goal{
+		Name:    name,
+		Percent: percent,
+		Created: goalDate(created)}
+}
+
+// goalDate drops time and location of t keeping its date only, as storages keep dates of goals without time zones
+func goalDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Label returns a label of actual transactions topping the goal up, e.g. 'goal_bike'
+func (g Goal) Label() string {
+	return goalLabelPrefix + g.Name
+}
+
+func (g Goal) validate() error {
+	if !goalNameRe.MatchString(g.Name) {
+		return fmt.Errorf("Goal name '%s' should consist of up to 32 letters, digits or '_'", g.Name)
+	}
+	if g.Percent != 0 {
+		if g.Target != 0 || g.Percent < 0 || g.Percent > 100 {
+			return errors.New("Percent of income should be between 1 and 100")
+		}
+		return nil
+	}
+	if g.Target <= 0 {
+		return errors.New("Goal should have either a positive target amount or a percent of income")
+	}
+	if daysBetween(g.Created, g.Deadline) < 0 {
+		return errors.New("Deadline should not be in the past")
+	}
+	return nil
+}
+
+// accrued returns how much of Target is reserved automatically till the end of the day of t
+func (g Goal) accrued(t time.Time) int {
+	total := daysBetween(g.Created, g.Deadline) + 1
+	days := daysBetween(g.Created, t) + 1
+	if days <= 0 {
+		return 0
+	}
+	if days > total {
+		days = total
+	}
+	return g.Target * days / total
+}
+
+// AddGoal starts saving for a goal; its name must not be used by another goal of the wallet
+func (w *Wallet) AddGoal(g Goal) error {
+	if err := g.validate(); err != nil {
+		return err
+	}
+	goals, err := w.storage.GetGoals(w.ID)
+	if err != nil {
+		log.Printf("Could not add goal - unable to get a list of current goals for wallet '%s'; error: %s", w.ID, err)
+		return err
+	}
+	for _, other := range goals {
+		if other.Name == g.Name {
+			return fmt.Errorf("Goal '%s' already exists", g.Name)
+		}
+	}
+	if g.ID == "" {
+		g.ID = newGoalId()
+	}
+	if err := w.storage.AddGoal(w.ID, g); err != nil {
+		return err
+	}
+	log.Printf("Goal '%s' has been added to wallet '%s'", g.Name, w.ID)
+	return nil
+}
+
+// GetGoals returns goals of the wallet sorted by name
+func (w *Wallet) GetGoals() ([]Goal, error) {
+	return w.storage.GetGoals(w.ID)
+}
+
+func (w *Wallet) findGoal(name string) (*Goal, error) {
+	goals, err := w.storage.GetGoals(w.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range goals {
+		if g.Name == name {
+			return &g, nil
+		}
+	}
+	return nil, fmt.Errorf("No goal named '%s'", name)
+}
+
+// TopUpGoal puts amount aside for the goal at time t in addition to money reserved automatically
+func (w *Wallet) TopUpGoal(name string, amount int, t time.Time) error {
+	if amount <= 0 {
+		return errors.New("Top-up amount should be positive")
+	}
+	g, err := w.findGoal(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.AddTransaction(*NewActualTransaction(-amount, t, g.Label(), ""))
+	return err
+}
+
+// CloseGoal stops saving for the goal and returns it; money which has been put aside becomes available again
+func (w *Wallet) CloseGoal(name string) (*Goal, error) {
+	g, err := w.findGoal(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.storage.RemoveGoal(w.ID, g.ID); err != nil {
+		log.Printf("Could not remove goal '%s' from wallet '%s' due to error: %s", g.Name, w.ID, err)
+		return nil, err
+	}
+	log.Printf("Goal '%s' of wallet '%s' has been closed", g.Name, w.ID)
+	return g, nil
+}
+
+// GetGoalStatuses returns all goals of the wallet along with money put aside for them till time t
+func (w *Wallet) GetGoalStatuses(t time.Time) ([]GoalStatus, error) {
+	txs := newTransactionCollection()
+	if err := w.loadRegularTransactions(txs); err != nil {
+		return nil, err
+	}
+	if err := w.loadActualTransactionsForCurrentMonthTillDate(t, txs); err != nil {
+		return nil, err
+	}
+	_, toGoals, err := w.calcCarry(t)
+	if err != nil {
+		return nil, err
+	}
+	return w.calcGoalStatuses(*txs, t, toGoals)
+}
+
+// goalTopUps returns how much has been put aside for the goal manually till time t
+func (w *Wallet) goalTopUps(g Goal, t time.Time) (int, error) {
+	transactions, err := w.storage.GetActualTransactions(w.ID, time.Date(g.Created.Year(), g.Created.Month(), g.Created.Day(), 0, 0, 0, 0, w.LocalTime(t).Location()), t)
+	if err != nil {
+		log.Printf("Could not get top-ups of goal '%s' of wallet '%s' due to error: %s", g.Name, w.ID, err)
+		return 0, err
+	}
+	sum := 0
+	for _, tx := range transactions {
+		if tx.Label != g.Label() {
+			continue
+		}
+		if err := w.convertToBaseCurrency(&tx.Value, &tx.Currency); err != nil {
+			return 0, err
+		}
+		sum -= tx.Value
+	}
+	return sum, nil
+}
+
+// calcGoalStatuses counts surplus of previous periods put aside for goals by names as their top-ups
+func (w *Wallet) calcGoalStatuses(txs transactionCollection, t time.Time, toGoals map[string]int) ([]GoalStatus, error) {
+	goals, err := w.storage.GetGoals(w.ID)
+	if err != nil {
+		log.Printf("Could not get goals of wallet '%s' due to error: %s", w.ID, err)
+		return nil, err
+	}
+	period := w.Period(t)
+	result := make([]GoalStatus, 0, len(goals))
+	for _, g := range goals {
+		topUps, err := w.goalTopUps(g, t)
+		if err != nil {
+			return nil, err
+		}
+		topUps += toGoals[g.Name]
+		status := GoalStatus{Goal: g}
+		if g.Percent > 0 {
+			first := period.Start
+			if daysBetween(first, g.Created) > 0 {
+				first = g.Created
+			}
+			if days := daysBetween(first, t) + 1; days > 0 {
+				status.Reserved = w.calcPeriodIncome(txs, period) * g.Percent / 100 * days / period.Days()
+			}
+			status.Saved = topUps + status.Reserved
+		} else {
+			before := g.accrued(period.Start.AddDate(0, 0, -1))
+			status.Reserved = g.accrued(t) - before
+			if need := g.Target - topUps - before; status.Reserved > need {
+				status.Reserved = need
+			}
+			if status.Reserved < 0 {
+				status.Reserved = 0
+			}
+			status.Saved = topUps + g.accrued(t)
+			if status.Saved > g.Target {
+				status.Saved = g.Target
+				if topUps > g.Target {
+					status.Saved = topUps
+				}
+			}
+		}
+		log.Printf("Goal '%s' of wallet '%s': saved %d, reserved in current period %d", g.Name, w.ID, status.Saved, status.Reserved)
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// calcPeriodIncome returns income of the period: planned regular income (or actual one if it is bigger)
+// together with income which has not been planned
+func (w *Wallet) calcPeriodIncome(txs transactionCollection, period Period) int {
+	matched := txs.getMatchedActualTransactions()
+	income := 0
+	for _, tx := range txs.getRegularTransactions() {
+		if tx.Value <= 0 {
+			continue
+		}
+		planned, actual := tx.plannedInPeriod(period), 0
+		for _, matchedTx := range matched[tx.Label] {
+			actual += matchedTx.Value
+		}
+		if actual > planned {
+			planned = actual
+		}
+		income += planned
+	}
+	for _, tx := range txs.getActualIncomeTransactions() {
+		if _, found := matched[tx.Label]; !found {
+			income += tx.Value
+		}
+	}
+	return income
+}
@@ -0,0 +1,104 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestGoalValidation(t *testing.T) {
+	created := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	invalid := []Goal{
+		*NewTargetGoal("bike", 0, created, created.AddDate(0, 5, 0)),
+		*NewTargetGoal("bike", 1000, created, created.AddDate(0, 0, -1)),
+		*NewTargetGoal("new bike", 1000, created, created.AddDate(0, 5, 0)),
+		*NewPercentGoal("reserve", 101, created),
+		Goal{Name: "both", Target: 1000, Percent: 10, Created: created},
+	}
+	for _, g := range invalid {
+		if err := g.validate(); err == nil {
+			t.Errorf("Goal %+v has been accepted", g)
+		}
+	}
+	if err := NewTargetGoal("bike", 1000, created, created).validate(); err != nil {
+		t.Errorf("Goal with deadline today has been rejected: %s", err)
+	}
+}
+
+func TestAvailableAmount_TargetGoal(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*NewRegularTransaction(3100, 1, "salary")); err != nil {
+		t.FailNow()
+	}
+	// 61 days between October 1 and November 30, so 100 is put aside every day
+	bike := NewTargetGoal("bike", 6100, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC))
+	if err := w.AddGoal(*bike); err != nil {
+		t.FailNow()
+	}
+	if err := w.AddGoal(*bike); err == nil {
+		t.Errorf("Goal with the same name has been added twice")
+	}
+
+	t1 := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	if val, err := w.GetBalance(t1); val != 0 || err != nil {
+		t.Errorf("Balance with goal: actual=%d; expected=%d", val, 0)
+	}
+
+	// top-up is spent at once and makes the goal end earlier
+	if err := w.TopUpGoal("bike", 500, t1); err != nil {
+		t.FailNow()
+	}
+	if err := w.TopUpGoal("car", 500, t1); err == nil {
+		t.Errorf("Unknown goal has been topped up")
+	}
+	if val, err := w.GetBalance(t1); val != -500 || err != nil {
+		t.Errorf("Balance after top-up: actual=%d; expected=%d", val, -500)
+	}
+
+	t2 := time.Date(2026, 11, 30, 12, 0, 0, 0, time.UTC)
+	statuses, err := w.GetGoalStatuses(t2)
+	if err != nil || len(statuses) != 1 {
+		t.FailNow()
+	}
+	if statuses[0].Reserved != 2500 || statuses[0].Saved != 6100 {
+		t.Errorf("Unexpected goal status at deadline: %+v", statuses[0])
+	}
+	if val, err := w.GetBalance(t2); val != 3100-2500 || err != nil {
+		t.Errorf("Balance at deadline: actual=%d; expected=%d", val, 3100-2500)
+	}
+
+	if _, err := w.CloseGoal("bike"); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(t2); val != 3100 || err != nil {
+		t.Errorf("Balance after closing goal: actual=%d; expected=%d", val, 3100)
+	}
+}
+
+func TestAvailableAmount_PercentGoal(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*NewRegularTransaction(3100, 1, "salary")); err != nil {
+		t.FailNow()
+	}
+	if err := w.AddGoal(*NewPercentGoal("reserve", 10, time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	if val, err := w.GetBalance(t1); val != 1000-100 || err != nil {
+		t.Errorf("Balance with reserve: actual=%d; expected=%d", val, 1000-100)
+	}
+
+	// unplanned income is reserved as well
+	if _, err := w.AddTransaction(*NewActualTransaction(3100, time.Date(2026, 10, 5, 12, 0, 0, 0, time.UTC), "bonus", "")); err != nil {
+		t.FailNow()
+	}
+	if val, err := w.GetBalance(t1); val != 2000-200 || err != nil {
+		t.Errorf("Balance with reserve and bonus: actual=%d; expected=%d", val, 2000-200)
+	}
+}
@@ -0,0 +1,95 @@
+package budget
+
+import "log"
+import "sort"
+import "time"
+import "errors"
+
+// LimitUsage tells how much has been spent for a label within a budget period compared to its limit
+type LimitUsage struct {
+	Label string
+	Limit int // positive amount which should not be exceeded
+	Spent int // positive amount spent so far
+}
+
+// Percent returns which part of the limit has been spent
+func (u LimitUsage) Percent() int {
+	return u.Spent * 100 / u.Limit
+}
+
+// limitAlertThresholds are percents of a limit which are reported once spending crosses them, the highest goes first
+var limitAlertThresholds = []int{100, 80}
+
+// SetLimit caps spending for label within every budget period; limit of 0 removes the cap
+func (w *Wallet) SetLimit(label string, limit int) error {
+	if label == "" {
+		return errors.New("Limits could be set for labeled transactions only")
+	}
+	if limit < 0 {
+		return errors.New("Limit should be positive")
+	}
+	if err := w.storage.SetWalletLimit(w.ID, label, limit); err != nil {
+		log.Printf("Could not set limit of label '%s' of wallet '%s' to %d due to error: %s", label, w.ID, limit, err)
+		return err
+	}
+	return nil
+}
+
+// GetLimits returns spending limits of the wallet by labels
+func (w *Wallet) GetLimits() (map[string]int, error) {
+	return w.storage.GetWalletLimits(w.ID)
+}
+
+// GetLimitUsage returns spending of every limited label within the period of t sorted by label
+func (w *Wallet) GetLimitUsage(t time.Time) ([]LimitUsage, error) {
+	limits, err := w.storage.GetWalletLimits(w.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LimitUsage, 0, len(limits))
+	if len(limits) == 0 {
+		return result, nil
+	}
+	summary, err := w.GetMonthlySummary(t)
+	if err != nil {
+		return nil, err
+	}
+	for label, limit := range limits {
+		result = append(result, LimitUsage{Label: label, Limit: limit, Spent: -summary.ExpenseSummary[label]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Label < result[j].Label })
+	return result, nil
+}
+
+// CheckLimit should be called after expense t is added; it returns usage of its label's limit along with the highest
+// threshold percent which t has made spending cross. Usage is nil if the label has no limit, threshold is 0 if none is crossed
+func (w *Wallet) CheckLimit(t ActualTransaction) (*LimitUsage, int, error) {
+	if t.Value >= 0 || t.Label == "" {
+		return nil, 0, nil
+	}
+	limits, err := w.storage.GetWalletLimits(w.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	limit, found := limits[t.Label]
+	if !found {
+		return nil, 0, nil
+	}
+	value, err := w.toBaseCurrency(t.Value, t.Currency)
+	if err != nil {
+		return nil, 0, err
+	}
+	summary, err := w.GetMonthlySummary(w.LocalTime(t.Time))
+	if err != nil {
+		return nil, 0, err
+	}
+	usage := &LimitUsage{Label: t.Label, Limit: limit, Spent: -summary.ExpenseSummary[t.Label]}
+	before := usage.Spent + value
+	for _, threshold := range limitAlertThresholds {
+		if before*100 < limit*threshold && usage.Spent*100 >= limit*threshold {
+			log.Printf("Spending labeled '%s' of wallet '%s' has crossed %d%% of its limit", t.Label, w.ID, threshold)
+			return usage, threshold, nil
+		}
+	}
+	return usage, 0, nil
+}
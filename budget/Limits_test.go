@@ -0,0 +1,89 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestCheckLimit(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.SetLimit("food", 1000); err != nil {
+		t.FailNow()
+	}
+	if err := w.SetLimit("", 1000); err == nil {
+		t.Errorf("Limit for unlabeled transactions has been set")
+	}
+
+	cases := []struct {
+		value, spent, threshold int
+	}{
+		{-500, 500, 0},
+		{-300, 800, 80},
+		{-100, 900, 0},
+		{-150, 1050, 100},
+		{-10, 1060, 0},
+	}
+	for i, c := range cases {
+		tx := NewActualTransaction(c.value, time.Date(2026, 10, 10+i, 12, 0, 0, 0, time.UTC), "food", "")
+		if _, err := w.AddTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+		usage, threshold, err := w.CheckLimit(*tx)
+		if err != nil || usage == nil || usage.Spent != c.spent || threshold != c.threshold {
+			t.Errorf("Transaction %d: usage %+v, threshold %d instead of %d spent and threshold %d (error: %v)", i, usage, threshold, c.spent, c.threshold, err)
+		}
+	}
+
+	// a big transaction crossing both thresholds is reported once
+	other := NewActualTransaction(-2000, time.Date(2026, 11, 5, 12, 0, 0, 0, time.UTC), "food", "")
+	if _, err := w.AddTransaction(*other); err != nil {
+		t.FailNow()
+	}
+	if _, threshold, err := w.CheckLimit(*other); threshold != 100 || err != nil {
+		t.Errorf("Limit exceeded in new period is reported as %d%%", threshold)
+	}
+
+	taxi := NewActualTransaction(-2000, time.Date(2026, 11, 5, 12, 0, 0, 0, time.UTC), "taxi", "")
+	if usage, threshold, err := w.CheckLimit(*taxi); usage != nil || threshold != 0 || err != nil {
+		t.Errorf("Label without limit has usage %+v", usage)
+	}
+
+	usage, err := w.GetLimitUsage(time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC))
+	if err != nil || len(usage) != 1 || usage[0].Spent != 1060 || usage[0].Percent() != 106 {
+		t.Errorf("Unexpected limit usage %+v", usage)
+	}
+}
+
+func TestCheckLimit_Timezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.FailNow()
+	}
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := w.SetMonthStart(10); err != nil {
+		t.FailNow()
+	}
+	if err := w.SetLimit("food", 1000); err != nil {
+		t.FailNow()
+	}
+	w.Location = berlin
+
+	if _, err := w.AddTransaction(*NewActualTransaction(-600, time.Date(2026, 10, 9, 12, 0, 0, 0, berlin), "food", "")); err != nil {
+		t.FailNow()
+	}
+	// it is still October 9 in UTC, but the new period has already started for the owner
+	tx := NewActualTransaction(-500, time.Date(2026, 10, 10, 0, 30, 0, 0, berlin).UTC(), "food", "")
+	if _, err := w.AddTransaction(*tx); err != nil {
+		t.FailNow()
+	}
+	usage, threshold, err := w.CheckLimit(*tx)
+	if err != nil || usage == nil || usage.Spent != 500 || threshold != 0 {
+		t.Errorf("Expense after local midnight has usage %+v, threshold %d (error: %v)", usage, threshold, err)
+	}
+}
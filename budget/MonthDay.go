@@ -0,0 +1,57 @@
+package budget
+
+import "fmt"
+import "time"
+import "errors"
+import "strconv"
+
+// LastDayOfMonth is a symbolic date of regular transactions and month start which means the last day of any month.
+// Dates 29-31 are clamped to the month length as well, so 31 and LastDayOfMonth fall on the same day
+const LastDayOfMonth = -1
+
+const lastDayOfMonthText = "last"
+
+// ValidateMonthDay checks that date is either a day of month from 1 to 31 or LastDayOfMonth
+func ValidateMonthDay(date int) error {
+	if date != LastDayOfMonth && (date < 1 || date > 31) {
+		return errors.New("Date must be between 1 and 31 or 'last'")
+	}
+	return nil
+}
+
+// ParseMonthDay converts text like '5' or 'last' into a date of month
+func ParseMonthDay(text string) (int, error) {
+	if text == lastDayOfMonthText {
+		return LastDayOfMonth, nil
+	}
+	date, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("Date '%s' is not a number", text)
+	}
+	if err := ValidateMonthDay(date); err != nil {
+		return 0, err
+	}
+	return date, nil
+}
+
+// FormatMonthDay is the opposite of ParseMonthDay
+func FormatMonthDay(date int) string {
+	if date == LastDayOfMonth {
+		return lastDayOfMonthText
+	}
+	return strconv.Itoa(date)
+}
+
+// DaysInMonth returns real length of the month considering leap years
+func DaysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// ActualDay returns which day of given month date falls on
+func ActualDay(date int, year int, month time.Month) int {
+	days := DaysInMonth(year, month)
+	if date == LastDayOfMonth || date > days {
+		return days
+	}
+	return date
+}
@@ -0,0 +1,49 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestParseMonthDay(t *testing.T) {
+	for text, expected := range map[string]int{"1": 1, "28": 28, "31": 31, "last": LastDayOfMonth} {
+		if date, err := ParseMonthDay(text); err != nil || date != expected {
+			t.Errorf("'%s' parsed as %d instead of %d (error: %v)", text, date, expected, err)
+		}
+		if expected != 31 && FormatMonthDay(expected) != text {
+			t.Errorf("%d formatted as '%s' instead of '%s'", expected, FormatMonthDay(expected), text)
+		}
+	}
+	for _, text := range []string{"0", "32", "first", ""} {
+		if _, err := ParseMonthDay(text); err == nil {
+			t.Errorf("'%s' has been accepted as a date", text)
+		}
+	}
+}
+
+func TestActualDay(t *testing.T) {
+	cases := []struct {
+		date     int
+		year     int
+		month    time.Month
+		expected int
+	}{
+		{5, 2018, time.February, 5},
+		{29, 2018, time.February, 28},
+		{29, 2004, time.February, 29},
+		{31, 2000, time.February, 29},
+		{31, 2018, time.April, 30},
+		{31, 2018, time.May, 31},
+		{LastDayOfMonth, 2018, time.February, 28},
+		{LastDayOfMonth, 2004, time.February, 29},
+		{LastDayOfMonth, 2018, time.December, 31},
+	}
+	for _, c := range cases {
+		if day := ActualDay(c.date, c.year, c.month); day != c.expected {
+			t.Errorf("Date %s of %s %d falls on %d instead of %d", FormatMonthDay(c.date), c.month, c.year, day, c.expected)
+		}
+	}
+
+	salary := NewRegularTransaction(1000, 30, "salary")
+	if !salary.DueOn(time.Date(2018, 2, 28, 9, 0, 0, 0, time.UTC)) || salary.DueOn(time.Date(2018, 3, 28, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Regular transaction at date 30 is due on wrong days")
+	}
+}
@@ -0,0 +1,96 @@
+package budget
+
+import "log"
+import "fmt"
+import "errors"
+import "regexp"
+
+// defaultWalletName is given to the first wallet of an owner
+const defaultWalletName = "main"
+
+// sharedWalletName is given to a joined wallet if the owner hasn't chosen a name
+const sharedWalletName = "shared"
+
+var walletNameRe *regexp.Regexp = regexp.MustCompile("^[\\wА-Яа-я-]{1,32}$")
+
+func validateWalletName(name string) error {
+	if !walletNameRe.MatchString(name) {
+		return fmt.Errorf("Wallet name '%s' should consist of up to 32 letters, digits, '_' or '-'", name)
+	}
+	return nil
+}
+
+// GetOwnerWallets returns all wallets of the owner sorted by name
+func GetOwnerWallets(owner OwnerId, storageconn Storage) ([]*Wallet, error) {
+	if _, err := storageconn.GetWalletForOwner(owner, true); err != nil {
+		return nil, err
+	}
+	wallets, err := storageconn.GetOwnerWallets(owner)
+	if err != nil {
+		return nil, err
+	}
+	loc := OwnerLocation(owner, storageconn)
+	for _, w := range wallets {
+		w.Location = loc
+	}
+	return wallets, nil
+}
+
+// GetOwnerWallet finds a wallet of the owner by its name
+func GetOwnerWallet(owner OwnerId, name string, storageconn Storage) (*Wallet, error) {
+	wallets, err := GetOwnerWallets(owner, storageconn)
+	if err != nil {
+		log.Printf("Could not get wallets of owner %d due to error: %s", owner, err)
+		return nil, err
+	}
+	for _, w := range wallets {
+		if w.Name == name {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("There is no wallet named '%s'", name)
+}
+
+// CreateNamedWallet adds a new wallet to the owner; active wallet is not changed
+func CreateNamedWallet(owner OwnerId, name string, storageconn Storage) (*Wallet, error) {
+	if err := validateWalletName(name); err != nil {
+		return nil, err
+	}
+	wallets, err := GetOwnerWallets(owner, storageconn)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWalletNameFree(name, wallets); err != nil {
+		return nil, err
+	}
+	wallet, err := storageconn.AddOwnerWallet(owner, name)
+	if err != nil {
+		log.Printf("Could not create wallet '%s' for owner %d due to error: %s", name, owner, err)
+		return nil, err
+	}
+	log.Printf("Wallet '%s' (%s) has been created for owner %d", name, wallet.ID, owner)
+	return wallet, nil
+}
+
+// UseWallet makes the wallet named so active for the owner
+func UseWallet(owner OwnerId, name string, storageconn Storage) (*Wallet, error) {
+	wallet, err := GetOwnerWallet(owner, name, storageconn)
+	if err != nil {
+		return nil, err
+	}
+	if err := storageconn.SetActiveWallet(owner, wallet.ID); err != nil {
+		log.Printf("Could not activate wallet '%s' for owner %d due to error: %s", wallet.ID, owner, err)
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// checkWalletNameFree returns an error if the owner already has a wallet with such name
+func checkWalletNameFree(name string, wallets []*Wallet) error {
+	for _, w := range wallets {
+		if w.Name == name {
+			return errors.New(fmt.Sprintf("There is already a wallet named '%s'", name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package budget
+
+import "log"
+import "time"
+
+// Period is a budget month of a wallet: it lasts from month start date till the same date of the next calendar month
+type Period struct {
+	Start time.Time // inclusive, midnight of the month start date
+	End   time.Time // exclusive, midnight of the next month start date
+}
+
+// NewPeriod returns the period which t belongs to; borders are calculated in location of t.
+// Month start dates which do not exist in a month are clamped to its last day
+func NewPeriod(monthStart int, t time.Time) Period {
+	if err := ValidateMonthDay(monthStart); err != nil {
+		panic(err.Error())
+	}
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	if t.Day() < ActualDay(monthStart, t.Year(), t.Month()) {
+		// we've switched the month already, period has started at the previous month
+		month = month.AddDate(0, -1, 0)
+	}
+	p := Period{Start: monthDayStart(monthStart, month), End: monthDayStart(monthStart, month.AddDate(0, 1, 0))}
+	log.Printf("Period borders are from %s to %s", p.Start, p.End)
+	return p
+}
+
+// monthDayStart returns midnight of date in the month which starts at month
+func monthDayStart(date int, month time.Time) time.Time {
+	return time.Date(month.Year(), month.Month(), ActualDay(date, month.Year(), month.Month()), 0, 0, 0, 0, month.Location())
+}
+
+// Period returns the wallet period which t belongs to
+func (w *Wallet) Period(t time.Time) Period {
+	return NewPeriod(w.MonthStart, t)
+}
+
+// Last returns the latest moment of the period, which is handy for inclusive time windows
+func (p Period) Last() time.Time {
+	return p.End.Add(-time.Nanosecond)
+}
+
+// Contains checks whether t is within the period
+func (p Period) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && t.Before(p.End)
+}
+
+// Days returns real length of the period in days considering leap years
+func (p Period) Days() int {
+	return daysBetween(p.Start, p.End)
+}
+
+// DaysSpent returns how many full days of the period have passed before the day of t
+func (p Period) DaysSpent(t time.Time) int {
+	return daysBetween(p.Start, t.In(p.Start.Location()))
+}
+
+// DaysRemaining returns how many days are left in the period including the day of t
+func (p Period) DaysRemaining(t time.Time) int {
+	return p.Days() - p.DaysSpent(t)
+}
+
+// daysBetween counts calendar days between dates of t1 and t2 so that DST switches do not affect the result
+func daysBetween(t1, t2 time.Time) int {
+	d1 := time.Date(t1.Year(), t1.Month(), t1.Day(), 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(t2.Year(), t2.Month(), t2.Day(), 0, 0, 0, 0, time.UTC)
+	return int(d2.Sub(d1).Hours() / 24)
+}
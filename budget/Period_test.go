@@ -0,0 +1,65 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestPeriod(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.FailNow()
+	}
+	cases := []struct {
+		name       string
+		monthStart int
+		t          time.Time
+		start, end time.Time
+		days       int
+		spent      int
+		remaining  int
+	}{
+		{"first day", 1, time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC), 30, 0, 30},
+		{"last moment", 1, time.Date(2018, 6, 30, 23, 59, 59, 0, time.UTC),
+			time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 7, 1, 0, 0, 0, 0, time.UTC), 30, 29, 1},
+		{"february", 1, time.Date(2018, 2, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC), 28, 19, 9},
+		{"leap february", 1, time.Date(2004, 2, 29, 12, 0, 0, 0, time.UTC),
+			time.Date(2004, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2004, 3, 1, 0, 0, 0, 0, time.UTC), 29, 28, 1},
+		{"leap century", 1, time.Date(2000, 2, 10, 0, 0, 0, 0, time.UTC),
+			time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 3, 1, 0, 0, 0, 0, time.UTC), 29, 9, 20},
+		{"not leap century", 1, time.Date(1900, 2, 10, 0, 0, 0, 0, time.UTC),
+			time.Date(1900, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 28, 9, 19},
+		{"started in leap february", 28, time.Date(2004, 3, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2004, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2004, 3, 28, 0, 0, 0, 0, time.UTC), 29, 2, 27},
+		{"started in previous month", 10, time.Date(2018, 7, 5, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2018, 7, 10, 0, 0, 0, 0, time.UTC), 30, 25, 5},
+		{"started in previous year", 10, time.Date(2019, 1, 9, 23, 0, 0, 0, time.UTC),
+			time.Date(2018, 12, 10, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC), 31, 30, 1},
+		{"month start day", 10, time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC),
+			time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2019, 2, 10, 0, 0, 0, 0, time.UTC), 31, 0, 31},
+		{"month start 31 after short month", 31, time.Date(2018, 3, 15, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC), 31, 15, 16},
+		{"month start 31 in previous year", 31, time.Date(2019, 1, 30, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 31, 0, 0, 0, 0, time.UTC), 31, 30, 1},
+		{"month start 30 at leap day", 30, time.Date(2004, 2, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2004, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2004, 3, 30, 0, 0, 0, 0, time.UTC), 30, 0, 30},
+		{"last day start", LastDayOfMonth, time.Date(2018, 2, 28, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC), 31, 0, 31},
+		{"last day start before it", LastDayOfMonth, time.Date(2018, 4, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2018, 4, 30, 0, 0, 0, 0, time.UTC), 30, 29, 1},
+		{"DST switch", 1, time.Date(2018, 3, 31, 12, 0, 0, 0, berlin),
+			time.Date(2018, 3, 1, 0, 0, 0, 0, berlin), time.Date(2018, 4, 1, 0, 0, 0, 0, berlin), 31, 30, 1},
+	}
+	for _, c := range cases {
+		p := NewPeriod(c.monthStart, c.t)
+		if !p.Start.Equal(c.start) || !p.End.Equal(c.end) {
+			t.Errorf("%s: period is from %s to %s instead of %s - %s", c.name, p.Start, p.End, c.start, c.end)
+		}
+		if !p.Last().Before(c.end) || !p.Last().After(c.t) && !p.Last().Equal(c.t) {
+			t.Errorf("%s: last moment %s is out of period", c.name, p.Last())
+		}
+		if days, spent, remaining := p.Days(), p.DaysSpent(c.t), p.DaysRemaining(c.t); days != c.days || spent != c.spent || remaining != c.remaining {
+			t.Errorf("%s: days=%d spent=%d remaining=%d instead of %d, %d, %d", c.name, days, spent, remaining, c.days, c.spent, c.remaining)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package budget
+
+import "fmt"
+import "time"
+import "errors"
+import "strconv"
+import "strings"
+
+// Frequency tells how often a regular transaction repeats
+type Frequency string
+
+const (
+	FreqMonthly Frequency = "MONTHLY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// Recurrence is a subset of iCalendar RRULE: FREQ, INTERVAL, BYDAY for weekly rules, BYMONTH for yearly ones, DTSTART
+// and UNTIL. Zero value means every month on the transaction date forever; day of month of monthly and yearly rules
+// is the transaction date
+type Recurrence struct {
+	Freq     Frequency    // FreqMonthly if empty
+	Interval int          // repeat every Interval weeks, months or years; 0 is the same as 1
+	Weekday  time.Weekday // day of week of weekly rules
+	Month    time.Month   // month of yearly rules
+	From     time.Time    // date which intervals are counted from; there are no occurrences before it. Zero if not set
+	Until    time.Time    // date of the last possible occurrence; zero if the transaction repeats forever
+}
+
+const recurrenceDateLayout = "20060102"
+
+var weekdayCodes = map[time.Weekday]string{
+	time.Monday:    "MO",
+	time.Tuesday:   "TU",
+	time.Wednesday: "WE",
+	time.Thursday:  "TH",
+	time.Friday:    "FR",
+	time.Saturday:  "SA",
+	time.Sunday:    "SU"}
+
+func (r Recurrence) freq() Frequency {
+	if r.Freq == "" {
+		return FreqMonthly
+	}
+	return r.Freq
+}
+
+func (r Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// isDefault checks whether the rule is a plain 'every month' one
+func (r Recurrence) isDefault() bool {
+	return r.freq() == FreqMonthly && r.interval() == 1 && r.From.IsZero() && r.Until.IsZero()
+}
+
+// EndedBefore checks whether the last possible occurrence of the rule has been before the day of t
+func (r Recurrence) EndedBefore(t time.Time) bool {
+	return !r.Until.IsZero() && daysBetween(r.Until, t) > 0
+}
+
+// validate checks the rule along with date of the transaction it is set for
+func (r Recurrence) validate(date int) error {
+	if r.Interval < 0 {
+		return errors.New("Interval must be positive")
+	}
+	if r.interval() > 1 && r.From.IsZero() {
+		return errors.New("Start date is mandatory for rules with an interval")
+	}
+	if !r.From.IsZero() && !r.Until.IsZero() && daysBetween(r.From, r.Until) < 0 {
+		return errors.New("End date must not be before start date")
+	}
+	switch r.freq() {
+	case FreqMonthly:
+		return ValidateMonthDay(date)
+	case FreqYearly:
+		if r.Month < time.January || r.Month > time.December {
+			return errors.New("Month of a yearly rule must be between 1 and 12")
+		}
+		return ValidateMonthDay(date)
+	case FreqWeekly:
+		if _, found := weekdayCodes[r.Weekday]; !found {
+			return errors.New("Unknown day of week")
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown frequency '%s'", r.Freq)
+}
+
+// String returns the rule in RRULE-like format, e.g. 'FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;DTSTART=20261002';
+// the default rule is an empty string
+func (r Recurrence) String() string {
+	if r.isDefault() {
+		return ""
+	}
+	parts := []string{"FREQ=" + string(r.freq())}
+	if r.interval() > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval()))
+	}
+	switch r.freq() {
+	case FreqWeekly:
+		parts = append(parts, "BYDAY="+weekdayCodes[r.Weekday])
+	case FreqYearly:
+		parts = append(parts, "BYMONTH="+strconv.Itoa(int(r.Month)))
+	}
+	if !r.From.IsZero() {
+		parts = append(parts, "DTSTART="+r.From.Format(recurrenceDateLayout))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(recurrenceDateLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParseRecurrence is the opposite of Recurrence.String
+func ParseRecurrence(text string) (Recurrence, error) {
+	r := Recurrence{}
+	if text == "" {
+		return r, nil
+	}
+	for _, part := range strings.Split(text, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("Incorrect recurrence rule part '%s'", part)
+		}
+		var err error
+		switch kv[0] {
+		case "FREQ":
+			r.Freq = Frequency(kv[1])
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(kv[1])
+		case "BYDAY":
+			err = fmt.Errorf("Unknown day of week '%s'", kv[1])
+			for weekday, code := range weekdayCodes {
+				if code == kv[1] {
+					r.Weekday, err = weekday, nil
+				}
+			}
+		case "BYMONTH":
+			var month int
+			month, err = strconv.Atoi(kv[1])
+			r.Month = time.Month(month)
+		case "DTSTART":
+			r.From, err = time.Parse(recurrenceDateLayout, kv[1])
+		case "UNTIL":
+			r.Until, err = time.Parse(recurrenceDateLayout, kv[1])
+		default:
+			err = fmt.Errorf("Unknown recurrence rule part '%s'", part)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// occurrences returns midnights of days in [from, to) when the transaction is expected according to its rule
+// (not moved to business days); days are calculated in location of from
+func (t RegularTransaction) occurrences(from, to time.Time) []time.Time {
+	r := t.Recurrence
+	result := make([]time.Time, 0, 1)
+	if !r.Until.IsZero() {
+		if until := time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day()+1, 0, 0, 0, 0, from.Location()); until.Before(to) {
+			to = until
+		}
+	}
+	if r.freq() == FreqWeekly {
+		for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()); day.Before(to); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() != r.Weekday || day.Before(from) {
+				continue
+			}
+			if !r.From.IsZero() {
+				passed := daysBetween(r.From, day)
+				if passed < 0 || (passed/7)%r.interval() != 0 {
+					continue
+				}
+			}
+			result = append(result, day)
+		}
+		return result
+	}
+
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); month.Before(to); month = month.AddDate(0, 1, 0) {
+		if r.freq() == FreqYearly && month.Month() != r.Month {
+			continue
+		}
+		day := monthDayStart(t.Date, month)
+		if day.Before(from) || !day.Before(to) {
+			continue
+		}
+		if !r.From.IsZero() {
+			months := (month.Year()-r.From.Year())*12 + int(month.Month()-r.From.Month())
+			step := r.interval()
+			if r.freq() == FreqYearly {
+				step *= 12
+			}
+			if daysBetween(r.From, day) < 0 || months%step != 0 {
+				continue
+			}
+		}
+		result = append(result, day)
+	}
+	return result
+}
+
+// plannedInPeriod returns how much of the transaction value falls on period p. Monthly transactions are counted in full
+// if they are expected within the period, others are spread evenly over periods between From and Until,
+// e.g. a half of a bi-monthly value falls on each period
+func (t RegularTransaction) plannedInPeriod(p Period) int {
+	r := t.Recurrence
+	if r.isDefault() {
+		return t.Value
+	}
+	if r.freq() == FreqMonthly && r.interval() == 1 {
+		return t.Value * len(t.occurrences(p.Start, p.End))
+	}
+	if !r.From.IsZero() && daysBetween(r.From, p.Last()) < 0 {
+		return 0
+	}
+	if r.EndedBefore(p.Start) {
+		return 0
+	}
+	switch r.freq() {
+	case FreqWeekly:
+		return t.Value * p.Days() / (7 * r.interval())
+	case FreqYearly:
+		return t.Value / (12 * r.interval())
+	}
+	return t.Value / r.interval()
+}
+
+// SetPaymentCount limits the transaction to count payments starting from its start date or from 'from' if it is not set
+func (t *RegularTransaction) SetPaymentCount(count int, from time.Time) error {
+	if count < 1 {
+		return errors.New("Number of payments must be positive")
+	}
+	if !t.Recurrence.From.IsZero() {
+		from = t.Recurrence.From
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t.Recurrence.Until = time.Time{}
+	// even the rarest rule has a payment within a period of its interval, so count intervals of years are enough
+	found := t.occurrences(from, from.AddDate(count*t.Recurrence.interval()+1, 0, 0))
+	if len(found) < count {
+		return errors.New("Transaction is not expected so many times")
+	}
+	t.Recurrence.Until = found[count-1]
+	return nil
+}
+
+// Schedule describes when the transaction is expected, e.g. 'day 5' or 'every 2 weeks on Friday from 2026-10-02'
+func (t RegularTransaction) Schedule() string {
+	r := t.Recurrence
+	var result string
+	switch r.freq() {
+	case FreqWeekly:
+		result = fmt.Sprintf("every %s", r.Weekday)
+		if r.interval() > 1 {
+			result = fmt.Sprintf("every %d weeks on %s", r.interval(), r.Weekday)
+		}
+	case FreqYearly:
+		result = fmt.Sprintf("yearly on %02d-%s", int(r.Month), FormatMonthDay(t.Date))
+		if r.interval() > 1 {
+			result = fmt.Sprintf("every %d years on %02d-%s", r.interval(), int(r.Month), FormatMonthDay(t.Date))
+		}
+	default:
+		result = fmt.Sprintf("day %s", FormatMonthDay(t.Date))
+		if r.interval() > 1 {
+			result = fmt.Sprintf("day %s every %d months", FormatMonthDay(t.Date), r.interval())
+		}
+	}
+	if !r.From.IsZero() {
+		result = fmt.Sprintf("%s from %s", result, r.From.Format("2006-01-02"))
+	}
+	if !r.Until.IsZero() {
+		result = fmt.Sprintf("%s until %s", result, r.Until.Format("2006-01-02"))
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestRecurrenceString(t *testing.T) {
+	start := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	cases := map[string]Recurrence{
+		"":                     Recurrence{},
+		"FREQ=WEEKLY;BYDAY=FR": Recurrence{Freq: FreqWeekly, Weekday: time.Friday},
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;DTSTART=20261002":     Recurrence{Freq: FreqWeekly, Interval: 2, Weekday: time.Friday, From: start},
+		"FREQ=MONTHLY;INTERVAL=3;DTSTART=20261002":             Recurrence{Freq: FreqMonthly, Interval: 3, From: start},
+		"FREQ=YEARLY;BYMONTH=3":                                Recurrence{Freq: FreqYearly, Month: time.March},
+		"FREQ=MONTHLY;UNTIL=20270305":                          Recurrence{Until: time.Date(2027, 3, 5, 0, 0, 0, 0, time.UTC)},
+		"FREQ=WEEKLY;BYDAY=FR;DTSTART=20261002;UNTIL=20261231": Recurrence{Freq: FreqWeekly, Weekday: time.Friday, From: start, Until: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for text, rule := range cases {
+		if rule.String() != text {
+			t.Errorf("Rule %+v is formatted as '%s' instead of '%s'", rule, rule.String(), text)
+		}
+		if parsed, err := ParseRecurrence(text); err != nil || parsed.String() != text {
+			t.Errorf("Rule '%s' is parsed as %+v (error: %v)", text, parsed, err)
+		}
+	}
+	for _, text := range []string{"FREQ", "FREQ=WEEKLY;BYDAY=XX", "FREQ=WEEKLY;COUNT=2", "FREQ=MONTHLY;DTSTART=2026"} {
+		if _, err := ParseRecurrence(text); err == nil {
+			t.Errorf("Incorrect rule '%s' has been accepted", text)
+		}
+	}
+	if err := (Recurrence{Freq: FreqWeekly, Interval: 2, Weekday: time.Friday}).validate(0); err == nil {
+		t.Errorf("Rule with interval and without start date has been accepted")
+	}
+	if err := (Recurrence{From: start, Until: start.AddDate(0, 0, -1)}).validate(5); err == nil {
+		t.Errorf("Rule ending before its start has been accepted")
+	}
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	start := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		date     int
+		rule     Recurrence
+		from, to time.Time
+		expected []int // days of month of occurrences
+	}{
+		{"monthly", 5, Recurrence{}, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), []int{5}},
+		{"every friday", 0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), []int{2, 9, 16, 23, 30}},
+		{"every 2 weeks", 0, Recurrence{Freq: FreqWeekly, Interval: 2, Weekday: time.Friday, From: start},
+			time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), []int{2, 16, 30}},
+		{"quarterly", 15, Recurrence{Freq: FreqMonthly, Interval: 3, From: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), []int{15, 15, 15, 15}},
+		{"quarterly in other month", 15, Recurrence{Freq: FreqMonthly, Interval: 3, From: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+			time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), []int{}},
+		{"yearly", 15, Recurrence{Freq: FreqYearly, Month: time.March},
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), []int{15}},
+		{"yearly leap day", 29, Recurrence{Freq: FreqYearly, Month: time.February},
+			time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC), []int{28, 29}},
+		{"monthly from", 5, Recurrence{From: time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC)},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), []int{5, 5}},
+		{"monthly until", 5, Recurrence{Until: time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC)},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), []int{5, 5}},
+		{"every friday until", 0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday, Until: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+			time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), []int{2, 9}},
+	}
+	for _, c := range cases {
+		tx := NewRecurringTransaction(100, c.date, c.rule, "test")
+		days := tx.occurrences(c.from, c.to)
+		if len(days) != len(c.expected) {
+			t.Errorf("%s: unexpected occurrences %v", c.name, days)
+			continue
+		}
+		for i, day := range days {
+			if day.Day() != c.expected[i] {
+				t.Errorf("%s: unexpected occurrences %v", c.name, days)
+			}
+		}
+	}
+}
+
+func TestPlannedInPeriod(t *testing.T) {
+	october := NewPeriod(1, time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC))
+	start := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value, date int
+		rule        Recurrence
+		expected    int
+	}{
+		{-100, 5, Recurrence{}, -100},
+		{-70, 0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday}, -70 * 31 / 7},
+		{1400, 0, Recurrence{Freq: FreqWeekly, Interval: 2, Weekday: time.Friday, From: start}, 1400 * 31 / 14},
+		{-300, 15, Recurrence{Freq: FreqMonthly, Interval: 3, From: start}, -100},
+		{-1200, 5, Recurrence{Freq: FreqYearly, Month: time.March}, -100},
+		{-1200, 5, Recurrence{Freq: FreqYearly, Month: time.March, Interval: 2, From: time.Date(2027, 3, 5, 0, 0, 0, 0, time.UTC)}, 0},
+		{-100, 5, Recurrence{Until: time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)}, -100},
+		{-100, 5, Recurrence{Until: time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC)}, 0},
+		{-100, 5, Recurrence{From: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)}, 0},
+		{-70, 0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday, Until: time.Date(2026, 9, 25, 0, 0, 0, 0, time.UTC)}, 0},
+	}
+	for _, c := range cases {
+		tx := NewRecurringTransaction(c.value, c.date, c.rule, "test")
+		if planned := tx.plannedInPeriod(october); planned != c.expected {
+			t.Errorf("%s of %d falls on October as %d instead of %d", tx.Schedule(), c.value, planned, c.expected)
+		}
+	}
+}
+
+func TestSetPaymentCount(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		date     int
+		rule     Recurrence
+		count    int
+		expected time.Time
+	}{
+		{20, Recurrence{}, 1, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{10, Recurrence{}, 12, time.Date(2027, 10, 10, 0, 0, 0, 0, time.UTC)},
+		{LastDayOfMonth, Recurrence{}, 5, time.Date(2027, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday}, 3, time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC)},
+		{15, Recurrence{Interval: 3, From: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)}, 4, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{5, Recurrence{Freq: FreqYearly, Month: time.March, Interval: 2, From: time.Date(2027, 3, 5, 0, 0, 0, 0, time.UTC)}, 3, time.Date(2031, 3, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		tx := NewRecurringTransaction(-100, c.date, c.rule, "loan")
+		if err := tx.SetPaymentCount(c.count, now); err != nil || !tx.Recurrence.Until.Equal(c.expected) {
+			t.Errorf("%d payments of %s end on %s instead of %s (error: %v)", c.count, tx.Schedule(), tx.Recurrence.Until, c.expected, err)
+		}
+	}
+	if err := NewRegularTransaction(-100, 5, "loan").SetPaymentCount(0, now); err == nil {
+		t.Errorf("Zero payments have been accepted")
+	}
+}
+
+func TestRetireFinishedRegularTransactions(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	salary := NewRegularTransaction(3100, 1, "salary")
+	loan := NewRecurringTransaction(-200, 20, Recurrence{Until: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)}, "loan")
+	for _, tx := range []*RegularTransaction{salary, loan} {
+		if err := w.AddRegularTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if retired, err := w.RetireFinishedRegularTransactions(time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC)); len(retired) != 0 || err != nil {
+		t.Errorf("Transactions have been retired before their period is over: %v (error: %v)", retired, err)
+	}
+	retired, err := w.RetireFinishedRegularTransactions(time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil || len(retired) != 1 || retired[0].Label != "loan" {
+		t.Errorf("Loan has not been retired: %v (error: %v)", retired, err)
+	}
+	if regular, _ := s.GetRegularTransactions(w.ID); len(regular) != 1 || regular[0].Label != "salary" {
+		t.Errorf("Unexpected regular transactions after retirement: %v", regular)
+	}
+}
+
+func TestAvailableAmount_RecurringTransactions(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	salary := NewRegularTransaction(3100, 1, "salary")
+	insurance := NewRecurringTransaction(-1200, 5, Recurrence{Freq: FreqYearly, Month: time.October}, "insurance")
+	cleaner := NewRecurringTransaction(-70, 0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday}, "cleaner")
+	for _, tx := range []*RegularTransaction{salary, insurance, cleaner} {
+		if err := w.AddRegularTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+	}
+
+	t1 := time.Date(2026, 10, 30, 23, 0, 0, 0, time.UTC)
+	planned := 3100 - 1200/12 - 70*31/7
+	if val, err := w.GetPlannedMonthlyIncome(t1); val != planned || err != nil {
+		t.Errorf("Planned: actual=%d; expected=%d", val, planned)
+	}
+
+	// insurance has cost more than planned, cleaner has been paid as planned
+	if _, err := w.AddTransaction(*NewActualTransaction(-1300, time.Date(2026, 10, 5, 12, 0, 0, 0, time.UTC), "insurance", "")); err != nil {
+		t.FailNow()
+	}
+	for _, day := range []int{2, 9, 16, 23, 30} {
+		if _, err := w.AddTransaction(*NewActualTransaction(-70, time.Date(2026, 10, day, 12, 0, 0, 0, time.UTC), "cleaner", "")); err != nil {
+			t.FailNow()
+		}
+	}
+	expected := int(float64(planned-100) / 31 * 30)
+	if val, err := w.GetBalance(t1); val != expected || err != nil {
+		t.Errorf("Balance: actual=%d; expected=%d", val, expected)
+	}
+}
@@ -0,0 +1,120 @@
+package budget
+
+import "log"
+import "time"
+import "errors"
+import "strings"
+import "github.com/satori/go.uuid"
+
+// inviteLifetime limits how long an invite code could be used for joining a wallet
+const inviteLifetime = 24 * time.Hour
+
+// inviteCodeLength is a number of hex digits in invite code, so that codes could not be guessed
+const inviteCodeLength = 16
+
+// inviteAttempts limits how many codes are tried if generated ones are already taken
+const inviteAttempts = 3
+
+// CreateInvite generates a one-time code which lets another owner join the wallet
+func (w *Wallet) CreateInvite() (string, error) {
+	var err error
+	for i := 0; i < inviteAttempts; i++ {
+		var id uuid.UUID
+		id, err = uuid.NewV4()
+		if err != nil {
+			log.Printf("Could not generate invite code for wallet '%s' due to error: %s", w.ID, err)
+			return "", err
+		}
+		code := strings.Replace(id.String(), "-", "", -1)[:inviteCodeLength]
+		// storage refuses codes which already exist
+		if err = w.storage.AddWalletInvite(w.ID, code, time.Now().Add(inviteLifetime)); err == nil {
+			log.Printf("Invite code has been created for wallet '%s'", w.ID)
+			return code, nil
+		}
+	}
+	log.Printf("Could not add invite code for wallet '%s' due to error: %s", w.ID, err)
+	return "", err
+}
+
+// Owners returns all owners sharing the wallet
+func (w *Wallet) Owners() ([]OwnerId, error) {
+	return w.storage.GetWalletOwners(w.ID)
+}
+
+// JoinWallet adds a wallet which invite code has been created for to the owner's wallets under given name
+// (or a default one if it is empty) and makes it active
+func JoinWallet(owner OwnerId, code string, name string, storageconn Storage) (*Wallet, error) {
+	if name == "" {
+		name = sharedWalletName
+	}
+	if err := validateWalletName(name); err != nil {
+		return nil, err
+	}
+	// the code is consumed only when the owner could join, so that a mistake does not waste it
+	walletId, expires, err := storageconn.GetWalletInvite(code)
+	if err != nil {
+		log.Printf("Owner %d could not use invite code '%s' due to error: %s", owner, code, err)
+		return nil, errors.New("Invite code is unknown or has expired")
+	}
+	wallets, err := GetOwnerWallets(owner, storageconn)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range wallets {
+		if w.ID == walletId {
+			return nil, errors.New("You are already a member of this wallet")
+		}
+	}
+	if err := checkWalletNameFree(name, wallets); err != nil {
+		return nil, err
+	}
+
+	if used, err := storageconn.UseWalletInvite(code); err != nil || used != walletId {
+		log.Printf("Owner %d could not use invite code '%s' due to error: %v", owner, code, err)
+		return nil, errors.New("Invite code is unknown or has expired")
+	}
+	if err := storageconn.AttachOwnerToWallet(owner, walletId, name); err != nil {
+		log.Printf("Could not attach owner %d to wallet '%s' due to error: %s", owner, walletId, err)
+		if err := storageconn.AddWalletInvite(walletId, code, expires); err != nil {
+			log.Printf("Could not restore invite code '%s' of wallet '%s' due to error: %s", code, walletId, err)
+		}
+		return nil, err
+	}
+	log.Printf("Owner %d has joined wallet '%s' as '%s'", owner, walletId, name)
+	return GetWalletForOwner(owner, false, storageconn)
+}
+
+// LeaveWallet detaches owner from active wallet if it is shared. Another wallet of the owner becomes active;
+// if there are no other wallets, a new empty one is created
+func LeaveWallet(owner OwnerId, storageconn Storage) (*Wallet, error) {
+	wallet, err := storageconn.GetWalletForOwner(owner, false)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := wallet.Owners()
+	if err != nil {
+		return nil, err
+	}
+	if len(owners) < 2 {
+		return nil, errors.New("You are the only member of this wallet")
+	}
+
+	if err := storageconn.DetachOwner(owner, wallet.ID); err != nil {
+		log.Printf("Could not detach owner %d from wallet '%s' due to error: %s", owner, wallet.ID, err)
+		return nil, err
+	}
+	log.Printf("Owner %d has left wallet '%s'", owner, wallet.ID)
+
+	wallets, err := storageconn.GetOwnerWallets(owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		if _, err := storageconn.CreateWalletOwner(owner); err != nil {
+			return nil, err
+		}
+	} else if err := storageconn.SetActiveWallet(owner, wallets[0].ID); err != nil {
+		return nil, err
+	}
+	return GetWalletForOwner(owner, false, storageconn)
+}
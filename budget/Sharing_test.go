@@ -0,0 +1,57 @@
+package budget
+
+import "testing"
+
+func TestJoinAndLeaveWallet(t *testing.T) {
+	s := NewRamStorage()
+	w1, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if _, err := LeaveWallet(OwnerId(1), s); err == nil {
+		t.Errorf("The only member has left the wallet")
+	}
+
+	code, err := w1.CreateInvite()
+	if err != nil || code == "" {
+		t.Fatalf("Invite has not been created: %s", err)
+	}
+	if _, err := JoinWallet(OwnerId(1), code, "", s); err == nil {
+		t.Errorf("Owner has joined own wallet")
+	}
+
+	// failed attempt keeps the code
+	joined, err := JoinWallet(OwnerId(2), code, "", s)
+	if err != nil || joined.ID != w1.ID || joined.Name != sharedWalletName {
+		t.Fatalf("Owner joined wallet %+v instead of '%s' (error: %v)", joined, w1.ID, err)
+	}
+	if _, err := JoinWallet(OwnerId(3), code, "", s); err == nil {
+		t.Errorf("Invite code has been accepted after being used")
+	}
+	if owners, err := w1.Owners(); err != nil || len(owners) != 2 {
+		t.Errorf("Unexpected wallet owners: %v", owners)
+	}
+
+	code, _ = w1.CreateInvite()
+	if _, err := JoinWallet(OwnerId(3), code, "bad name", s); err == nil {
+		t.Errorf("Wallet has been joined under invalid name")
+	}
+	code, _ = w1.CreateInvite()
+	if _, err := JoinWallet(OwnerId(3), code, defaultWalletName, s); err == nil {
+		t.Errorf("Wallet has been joined under a name which is already taken")
+	}
+	if _, err := JoinWallet(OwnerId(3), code, "family", s); err != nil {
+		t.Errorf("Invite code has been wasted by joining under a taken name: %s", err)
+	}
+
+	left, err := LeaveWallet(OwnerId(2), s)
+	if err != nil || left.ID == w1.ID || left.Name != defaultWalletName {
+		t.Fatalf("Owner got wallet %+v after leaving (error: %v)", left, err)
+	}
+	if active, err := s.GetWalletForOwner(OwnerId(2), false); err != nil || active.ID != left.ID {
+		t.Errorf("Owner's own wallet has not become active after leaving: %+v", active)
+	}
+	if owners, err := w1.Owners(); err != nil || len(owners) != 2 || owners[0] != 1 || owners[1] != 3 {
+		t.Errorf("Unexpected wallet owners after leaving: %v", owners)
+	}
+}
@@ -5,19 +5,70 @@ import "time"
 var storage Storage = nil
 
 type Storage interface {
-	GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (*Wallet, error)
-	CreateWalletOwner(ownerId OwnerId) (*Wallet, error)
+	// owner could have several wallets distinguished by names; one of them is active and used by default
+	GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (*Wallet, error) // returns active wallet
+	CreateWalletOwner(ownerId OwnerId) (*Wallet, error)                      // creates active wallet with default name
 	GetAllOwners() (map[OwnerId]OwnerData, error)
+	GetOwnerWallets(ownerId OwnerId) ([]*Wallet, error)           // sorted by name
+	AddOwnerWallet(ownerId OwnerId, name string) (*Wallet, error) // creates a new wallet keeping active one
+	SetActiveWallet(ownerId OwnerId, w WalletId) error            // wallet must be one of the owner's wallets
+
+	// several owners could share a wallet; they are attached to it using one-time invite codes
+	GetWalletOwners(w WalletId) ([]OwnerId, error)                      // sorted by ID
+	AttachOwnerToWallet(ownerId OwnerId, w WalletId, name string) error // adds wallet to the owner's ones and makes it active
+	DetachOwner(ownerId OwnerId, w WalletId) error                      // owner settings are kept; owner has no active wallet if w was active
+	AddWalletInvite(w WalletId, code string, expires time.Time) error
+	GetWalletInvite(code string) (WalletId, time.Time, error) // returns wallet and expiration of the code; fails if it is unknown or expired
+	UseWalletInvite(code string) (WalletId, error)            // removes the code; fails if it is unknown or expired
 
 	GetOwnerDailyNotificationTime(id OwnerId) (*time.Duration, error)
 	SetOwnerDailyNotificationTime(id OwnerId, notifTime *time.Duration) error
+	GetOwnerTimezone(id OwnerId) (string, error) // IANA name of owner's time zone; empty if it is not set
+	SetOwnerTimezone(id OwnerId, tz string) error
+	GetOwnerAdminsOnly(id OwnerId) (bool, error) // whether only chat admins could change regular transactions and settings
+	SetOwnerAdminsOnly(id OwnerId, adminsOnly bool) error
+
+	// names of telegram users which transactions are attributed to
+	SetUserName(userId int, name string) error
+	GetUserName(userId int) (string, error) // returns empty name if it is unknown
 
 	SetWalletInfo(w WalletId, monthStart int) error
+	SetWalletCurrency(w WalletId, c Currency) error
+	RescaleWallet(w WalletId, from, to int) error             // sets number of decimals rescaling all amounts and dropping journal at once
+	SetWalletLimit(w WalletId, label string, limit int) error // monthly spending limit of a label; 0 removes it
+	GetWalletLimits(w WalletId) (map[string]int, error)
+	SetWalletCarryOver(w WalletId, c CarryOver) error
+	GetWalletCarryOver(w WalletId) (CarryOver, error) // policy without carry-over if it has not been set
 
+	// both actual and regular transactions added with empty ID get a new one generated by storage
 	AddActualTransaction(w WalletId, val ActualTransaction) error
 	GetActualTransactions(w WalletId, tMin, tMax time.Time) ([]ActualTransaction, error)
+	GetActualTransaction(w WalletId, id TransactionId) (*ActualTransaction, error)
+	GetActualTransactionByMessage(w WalletId, chatId int64, messageId int) (*ActualTransaction, error)
+	UpdateActualTransaction(w WalletId, t ActualTransaction) error // replaces transaction with t.ID
+	RemoveActualTransaction(w WalletId, id TransactionId) error
 
 	AddRegularTransaction(w WalletId, val RegularTransaction) error
 	GetRegularTransactions(w WalletId) ([]RegularTransaction, error)
-	RemoveRegularTransaction(w WalletId, t RegularTransaction) error
+	RemoveRegularTransaction(w WalletId, t RegularTransaction) error // removes transaction with t.ID
+
+	AddGoal(w WalletId, g Goal) error    // goal added with empty ID gets a new one generated by storage
+	GetGoals(w WalletId) ([]Goal, error) // sorted by name
+	RemoveGoal(w WalletId, id GoalId) error
+
+	// operation journal keeps up to journalLimit latest operations of a wallet
+	PushOperation(w WalletId, op Operation) error
+	PopOperation(w WalletId) (*Operation, error)  // returns nil if journal is empty
+	LastOperation(w WalletId) (*Operation, error) // same as PopOperation, but the operation stays in journal
+	DropOperation(w WalletId, op Operation) error // removes op from journal; fails if it is not the latest one anymore
+}
+
+const journalLimit = 50
+
+// unixCeil converts t into unix seconds rounding up, so that time stored with seconds precision compares with t exactly
+func unixCeil(t time.Time) int64 {
+	if t.Nanosecond() > 0 {
+		return t.Unix() + 1
+	}
+	return t.Unix()
 }
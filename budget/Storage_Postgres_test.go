@@ -0,0 +1,69 @@
+package budget
+
+import "os"
+import "sync"
+import "testing"
+import "database/sql"
+
+// testPostgresEnv names an env variable with DSN of a locally started PostgreSQL.
+// Note that public schema of the database is recreated before each test, so never point it at real data
+const testPostgresEnv = "BUDGET_TEST_POSTGRES"
+
+func newTestPostgresStorage(t *testing.T) (Storage, func()) {
+	dsn := os.Getenv(testPostgresEnv)
+	if dsn == "" {
+		// CI always provides a server, so silently skipped tests there would hide broken PostgreSQL support
+		if os.Getenv("CI") != "" {
+			t.Fatalf("%s must be set when running in CI", testPostgresEnv)
+		}
+		t.Skipf("%s is not set, skipping PostgreSQL tests", testPostgresEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Could not connect to PostgreSQL: %s", err)
+	}
+	_, err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	db.Close()
+	if err != nil {
+		t.Fatalf("Could not clean PostgreSQL database: %s", err)
+	}
+
+	s, err := NewPostgresStorage(dsn)
+	if err != nil {
+		t.Fatalf("Could not open PostgreSQL storage: %s", err)
+	}
+	return s, func() { s.(*PostgresStorage).db.Close() }
+}
+
+func TestPostgresStorage(t *testing.T) {
+	testStorageConformance(t, newTestPostgresStorage)
+}
+
+func TestPostgresStorage_ConcurrentOwnerCreation(t *testing.T) {
+	s, release := newTestPostgresStorage(t)
+	defer release()
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	created := make(chan *Wallet, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w, err := s.CreateWalletOwner(OwnerId(1)); err == nil {
+				created <- w
+			}
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	if len(created) != 1 {
+		t.Errorf("Owner has been created %d times", len(created))
+	}
+	var wallets int
+	if err := s.(*PostgresStorage).db.QueryRow("SELECT count(*) FROM wallets").Scan(&wallets); err != nil || wallets != 1 {
+		t.Errorf("Failed owner creation has left %d wallets", wallets)
+	}
+}
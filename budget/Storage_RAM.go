@@ -1,17 +1,38 @@
 package budget
 
+import "log"
+import "sort"
+import "sync"
 import "time"
 import "errors"
-import "fmt"
+import "github.com/satori/go.uuid"
 
 type walletDetails struct {
 	monthStart int
+	currency   Currency
+	decimals   int
+	limits     map[string]int
+	carryOver  CarryOver
+}
+
+type walletInvite struct {
+	wallet  WalletId
+	expires time.Time
 }
 
 type ramStorage struct {
+	lock sync.Mutex
+
 	walletTransactions        map[WalletId][]ActualTransaction
 	walletRegularTransactions map[WalletId][]RegularTransaction
+	walletGoals               map[WalletId][]Goal
 	walletInfo                map[WalletId]walletDetails
+	walletJournal             map[WalletId][]Operation
+	walletInvites             map[string]walletInvite
+
+	ownerWallets    map[OwnerId]map[string]WalletId // wallets of an owner by their names
+	ownerAdminsOnly map[OwnerId]bool
+	userNames       map[int]string
 
 	ownerDataMap map[OwnerId]OwnerData
 }
@@ -20,85 +41,698 @@ func NewRamStorage() Storage {
 	storage := &ramStorage{
 		walletTransactions:        make(map[WalletId][]ActualTransaction, 0),
 		walletRegularTransactions: make(map[WalletId][]RegularTransaction, 0),
+		walletGoals:               make(map[WalletId][]Goal, 0),
 		walletInfo:                make(map[WalletId]walletDetails, 0),
+		walletJournal:             make(map[WalletId][]Operation, 0),
+		walletInvites:             make(map[string]walletInvite, 0),
+		ownerWallets:              make(map[OwnerId]map[string]WalletId, 0),
+		ownerAdminsOnly:           make(map[OwnerId]bool, 0),
+		userNames:                 make(map[int]string, 0),
 		ownerDataMap:              make(map[OwnerId]OwnerData, 0)}
 	return storage
 }
 
 func (s *ramStorage) AddActualTransaction(w WalletId, val ActualTransaction) error {
-	_, found := s.walletTransactions[w]
-	if !found {
-		s.walletTransactions[w] = make([]ActualTransaction, 0, 1)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if val.ID == "" {
+		val.ID = newTransactionId()
 	}
 	s.walletTransactions[w] = append(s.walletTransactions[w], val)
 	return nil
 }
 
 func (s *ramStorage) AddRegularTransaction(w WalletId, val RegularTransaction) error {
-	_, found := s.walletRegularTransactions[w]
-	if !found {
-		s.walletRegularTransactions[w] = make([]RegularTransaction, 0, 1)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if val.ID == "" {
+		val.ID = newTransactionId()
 	}
 	s.walletRegularTransactions[w] = append(s.walletRegularTransactions[w], val)
 	return nil
 }
 
 func (s *ramStorage) GetRegularTransactions(w WalletId) ([]RegularTransaction, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.getRegularTransactions(w), nil // OK if there are no such transactions
+}
+
+func (s *ramStorage) getRegularTransactions(w WalletId) []RegularTransaction {
+	// copy is returned so callers cannot modify stored records
 	records := s.walletRegularTransactions[w]
-	return records, nil // OK if there are no such transactions
+	result := make([]RegularTransaction, len(records))
+	copy(result, records)
+	return result
+}
+
+func (s *ramStorage) RemoveRegularTransaction(w WalletId, t RegularTransaction) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	records := s.walletRegularTransactions[w]
+	for i, r := range records {
+		if r.ID == t.ID {
+			log.Printf("Removing regular transaction %+v from wallet '%s'", r, w)
+			s.walletRegularTransactions[w] = append(records[:i:i], records[i+1:]...)
+			return nil
+		}
+	}
+
+	log.Printf("No transaction found for wallet '%s' for transaction removal", w)
+	return errors.New("Specified transaction has not been found in DB")
+}
+
+func (s *ramStorage) AddGoal(w WalletId, g Goal) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if g.ID == "" {
+		g.ID = newGoalId()
+	}
+	s.walletGoals[w] = append(s.walletGoals[w], g)
+	return nil
+}
+
+func (s *ramStorage) GetGoals(w WalletId) ([]Goal, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make([]Goal, len(s.walletGoals[w]))
+	copy(result, s.walletGoals[w])
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *ramStorage) RemoveGoal(w WalletId, id GoalId) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	records := s.walletGoals[w]
+	for i, g := range records {
+		if g.ID == id {
+			s.walletGoals[w] = append(records[:i:i], records[i+1:]...)
+			return nil
+		}
+	}
+	log.Printf("No goal '%s' found for wallet '%s' for removal", id, w)
+	return errors.New("Specified goal has not been found in DB")
 }
 
 func (s *ramStorage) GetActualTransactions(w WalletId, tMin, tMax time.Time) ([]ActualTransaction, error) {
-	allRecords := s.walletTransactions[w]
-	if allRecords == nil || len(allRecords) == 0 {
-		return nil, nil
+	if tMax.Before(tMin) {
+		panic("Time borders misaligned")
 	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	allRecords := s.walletTransactions[w]
 	records := make([]ActualTransaction, 0, len(allRecords))
 	for _, r := range allRecords {
-		if r.Time.After(tMin) && (r.Time.Equal(tMax) || r.Time.Before(tMax)) {
+		if !r.Time.Before(tMin) && !r.Time.After(tMax) {
 			records = append(records, r)
 		}
 	}
 	return records, nil // OK if no such transactions
 }
 
+func (s *ramStorage) findActualTransaction(w WalletId, id TransactionId) int {
+	for i, r := range s.walletTransactions[w] {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *ramStorage) GetActualTransaction(w WalletId, id TransactionId) (*ActualTransaction, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.findActualTransaction(w, id)
+	if i < 0 {
+		log.Printf("No actual transaction '%s' found for wallet '%s'", id, w)
+		return nil, errors.New("Specified transaction has not been found in DB")
+	}
+	result := s.walletTransactions[w][i]
+	return &result, nil
+}
+
+func (s *ramStorage) GetActualTransactionByMessage(w WalletId, chatId int64, messageId int) (*ActualTransaction, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, r := range s.walletTransactions[w] {
+		if r.ChatID == chatId && r.MessageID == messageId {
+			result := r
+			return &result, nil
+		}
+	}
+	log.Printf("No actual transaction for message %d of chat %d found for wallet '%s'", messageId, chatId, w)
+	return nil, errors.New("Specified transaction has not been found in DB")
+}
+
+func (s *ramStorage) UpdateActualTransaction(w WalletId, t ActualTransaction) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.findActualTransaction(w, t.ID)
+	if i < 0 {
+		log.Printf("No actual transaction '%s' found for wallet '%s' for update", t.ID, w)
+		return errors.New("Specified transaction has not been found in DB")
+	}
+	s.walletTransactions[w][i] = t
+	return nil
+}
+
+func (s *ramStorage) RemoveActualTransaction(w WalletId, id TransactionId) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	i := s.findActualTransaction(w, id)
+	if i < 0 {
+		log.Printf("No actual transaction '%s' found for wallet '%s' for removal", id, w)
+		return errors.New("Specified transaction has not been found in DB")
+	}
+	records := s.walletTransactions[w]
+	s.walletTransactions[w] = append(records[:i:i], records[i+1:]...)
+	return nil
+}
+
 func (s *ramStorage) GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (*Wallet, error) {
-	return nil, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ownerData, found := s.ownerDataMap[ownerId]
+	if !found || ownerData.WalletId == nil {
+		log.Printf("No wallet found for owner %d", ownerId)
+		if !createIfAbsent {
+			return nil, errors.New("No wallet for owner")
+		}
+		return s.createWalletOwner(ownerId)
+	}
+
+	wId := WalletId(*ownerData.WalletId)
+	return s.loadWallet(wId, s.walletName(ownerId, wId)), nil
+}
+
+func (s *ramStorage) loadWallet(w WalletId, name string) *Wallet {
+	details, found := s.walletInfo[w]
+	if !found {
+		details.monthStart = defaultMonthStart
+		details.decimals = defaultDecimals
+	}
+	wallet := NewWalletFromStorage(string(w), details.monthStart, s)
+	wallet.Name = name
+	wallet.Currency = details.currency
+	wallet.Decimals = details.decimals
+	return wallet
+}
+
+// walletName returns a name which the owner has given to the wallet
+func (s *ramStorage) walletName(ownerId OwnerId, w WalletId) string {
+	for name, id := range s.ownerWallets[ownerId] {
+		if id == w {
+			return name
+		}
+	}
+	return defaultWalletName
+}
+
+func (s *ramStorage) addOwnerWallet(ownerId OwnerId, w WalletId, name string) error {
+	wallets, found := s.ownerWallets[ownerId]
+	if !found {
+		wallets = make(map[string]WalletId, 1)
+		s.ownerWallets[ownerId] = wallets
+	}
+	if _, found := wallets[name]; found {
+		log.Printf("Owner %d already has wallet named '%s'", ownerId, name)
+		return errors.New("Wallet name exists")
+	}
+	for _, id := range wallets {
+		if id == w {
+			log.Printf("Owner %d already has wallet '%s'", ownerId, w)
+			return errors.New("Wallet is already attached to owner")
+		}
+	}
+	wallets[name] = w
+	return nil
 }
 
 func (s *ramStorage) CreateWalletOwner(ownerId OwnerId) (*Wallet, error) {
-	_, found := s.ownerDataMap[ownerId]
-	if found {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.createWalletOwner(ownerId)
+}
+
+func (s *ramStorage) createWalletOwner(ownerId OwnerId) (*Wallet, error) {
+	log.Printf("Starting creation of owner %d", ownerId)
+
+	ownerData, found := s.ownerDataMap[ownerId]
+	if found && ownerData.WalletId != nil {
+		log.Printf("Owner %d has been already created", ownerId)
 		return nil, errors.New("Owner exists")
 	}
-	wId := fmt.Sprintf("%d", ownerId)
-	ownerData := OwnerData{WalletId: &wId}
+
+	if _, found := s.ownerWallets[ownerId][defaultWalletName]; found {
+		log.Printf("Owner %d already has wallet named '%s'", ownerId, defaultWalletName)
+		return nil, errors.New("Wallet name exists")
+	}
+	wallet, err := s.createWallet()
+	if err != nil {
+		log.Printf("Could not create wallet for owner %d with error: %s", ownerId, err)
+		return nil, err
+	}
+	log.Printf("Wallet %s has been created for owner %d", wallet.ID, ownerId)
+	if err := s.addOwnerWallet(ownerId, wallet.ID, defaultWalletName); err != nil {
+		return nil, err
+	}
+
+	wId := string(wallet.ID)
+	ownerData.WalletId = &wId
 	s.ownerDataMap[ownerId] = ownerData
-	wallet := NewWalletFromStorage(wId, 1, s)
 	return wallet, nil
 }
 
+func (s *ramStorage) createWallet() (*Wallet, error) {
+	for {
+		id, err := uuid.NewV4()
+		if err != nil {
+			log.Printf("Could get new wallet UUID due to error: %s", err)
+			return nil, err
+		}
+
+		wId := WalletId(id.String())
+		if _, found := s.walletInfo[wId]; found {
+			log.Printf("Wallet '%s' exists, trying another one", wId)
+			continue
+		}
+
+		s.walletInfo[wId] = walletDetails{monthStart: defaultMonthStart, decimals: defaultDecimals}
+		return NewWalletFromStorage(id.String(), defaultMonthStart, s), nil
+	}
+}
+
+func (s *ramStorage) GetOwnerWallets(ownerId OwnerId) ([]*Wallet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wallets := make([]*Wallet, 0, len(s.ownerWallets[ownerId]))
+	for name, w := range s.ownerWallets[ownerId] {
+		wallets = append(wallets, s.loadWallet(w, name))
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Name < wallets[j].Name })
+	return wallets, nil
+}
+
+func (s *ramStorage) AddOwnerWallet(ownerId OwnerId, name string) (*Wallet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, found := s.ownerWallets[ownerId][name]; found {
+		log.Printf("Owner %d already has wallet named '%s'", ownerId, name)
+		return nil, errors.New("Wallet name exists")
+	}
+	wallet, err := s.createWallet()
+	if err != nil {
+		log.Printf("Could not create wallet '%s' for owner %d with error: %s", name, ownerId, err)
+		return nil, err
+	}
+	if err := s.addOwnerWallet(ownerId, wallet.ID, name); err != nil {
+		return nil, err
+	}
+	wallet.Name = name
+	return wallet, nil
+}
+
+func (s *ramStorage) SetActiveWallet(ownerId OwnerId, w WalletId) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, id := range s.ownerWallets[ownerId] {
+		if id == w {
+			ownerData := s.ownerDataMap[ownerId]
+			wId := string(w)
+			ownerData.WalletId = &wId
+			s.ownerDataMap[ownerId] = ownerData
+			return nil
+		}
+	}
+	log.Printf("Wallet '%s' doesn't belong to owner %d", w, ownerId)
+	return errors.New("No such wallet for owner")
+}
+
 func (s *ramStorage) GetAllOwners() (map[OwnerId]OwnerData, error) {
-	panic("Not implemented")
-	return nil, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	resultMap := make(map[OwnerId]OwnerData, len(s.ownerDataMap))
+	for ownerId, data := range s.ownerDataMap {
+		ownerData := OwnerData{
+			WalletId:          data.WalletId,
+			Timezone:          data.Timezone,
+			DailyReminderTime: data.DailyReminderTime}
+		ownerData.RegularTxs = make(map[int][]RegularTransaction, 0)
+		if ownerData.WalletId != nil {
+			for _, tx := range s.getRegularTransactions(WalletId(*ownerData.WalletId)) {
+				ownerData.RegularTxs[tx.Date] = append(ownerData.RegularTxs[tx.Date], tx)
+			}
+		}
+		resultMap[ownerId] = ownerData
+	}
+	return resultMap, nil
 }
 
 func (s *ramStorage) SetWalletInfo(w WalletId, monthStart int) error {
-	panic("Not implemented")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	details := s.walletInfo[w]
+	details.monthStart = monthStart
+	s.walletInfo[w] = details
 	return nil
 }
 
-func (s *ramStorage) RemoveRegularTransaction(w WalletId, t RegularTransaction) error {
-	panic("Not implemented")
+func (s *ramStorage) SetWalletCurrency(w WalletId, c Currency) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	details, found := s.walletInfo[w]
+	if !found {
+		log.Printf("Wallet '%s' has not been found for setting currency", w)
+		return errors.New("No such wallet")
+	}
+	details.currency = c
+	s.walletInfo[w] = details
+	return nil
+}
+
+func (s *ramStorage) RescaleWallet(w WalletId, from, to int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	details, found := s.walletInfo[w]
+	if !found {
+		log.Printf("Wallet '%s' has not been found for setting decimals", w)
+		return errors.New("No such wallet")
+	}
+	for i := range s.walletTransactions[w] {
+		s.walletTransactions[w][i].Value = rescaleAmount(s.walletTransactions[w][i].Value, from, to)
+	}
+	for i := range s.walletRegularTransactions[w] {
+		s.walletRegularTransactions[w][i].Value = rescaleAmount(s.walletRegularTransactions[w][i].Value, from, to)
+	}
+	for i := range s.walletGoals[w] {
+		s.walletGoals[w][i].Target = rescaleAmount(s.walletGoals[w][i].Target, from, to)
+	}
+	for label, limit := range details.limits {
+		details.limits[label] = rescaleAmount(limit, from, to)
+	}
+	details.carryOver = details.carryOver.rescaled(from, to)
+	details.decimals = to
+	s.walletInfo[w] = details
+	delete(s.walletJournal, w)
+	return nil
+}
+
+func (s *ramStorage) SetWalletLimit(w WalletId, label string, limit int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	details, found := s.walletInfo[w]
+	if !found {
+		log.Printf("Wallet '%s' has not been found for setting limit", w)
+		return errors.New("No such wallet")
+	}
+	limits := make(map[string]int, len(details.limits)+1)
+	for l, v := range details.limits {
+		limits[l] = v
+	}
+	if limit == 0 {
+		delete(limits, label)
+	} else {
+		limits[label] = limit
+	}
+	details.limits = limits
+	s.walletInfo[w] = details
+	return nil
+}
+
+func (s *ramStorage) GetWalletLimits(w WalletId) (map[string]int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := make(map[string]int, len(s.walletInfo[w].limits))
+	for l, v := range s.walletInfo[w].limits {
+		result[l] = v
+	}
+	return result, nil
+}
+
+func (s *ramStorage) SetWalletCarryOver(w WalletId, c CarryOver) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	details, found := s.walletInfo[w]
+	if !found {
+		log.Printf("Wallet '%s' has not been found for setting carry-over policy", w)
+		return errors.New("No such wallet")
+	}
+	details.carryOver = c
+	s.walletInfo[w] = details
 	return nil
 }
 
+func (s *ramStorage) GetWalletCarryOver(w WalletId) (CarryOver, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.walletInfo[w].carryOver, nil
+}
+
 func (s *ramStorage) GetOwnerDailyNotificationTime(id OwnerId) (*time.Duration, error) {
-	panic("Not implemented")
-	return nil, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ownerData, found := s.ownerDataMap[id]
+	if !found || ownerData.DailyReminderTime == nil {
+		log.Printf("Assuming that daily notification is not set (disabled) for owner %d", id)
+		return nil, nil
+	}
+
+	notifTime := *ownerData.DailyReminderTime
+	return &notifTime, nil
 }
 
 func (s *ramStorage) SetOwnerDailyNotificationTime(id OwnerId, notifTime *time.Duration) error {
-	panic("Not implemented")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ownerData := s.ownerDataMap[id]
+	if notifTime == nil {
+		log.Printf("Removing notification time for owner %d", id)
+		ownerData.DailyReminderTime = nil
+	} else {
+		log.Printf("Setting daily notification time for owner %d to '%s'", id, notifTime)
+		t := *notifTime
+		ownerData.DailyReminderTime = &t
+	}
+	s.ownerDataMap[id] = ownerData
 	return nil
 }
+
+func (s *ramStorage) GetOwnerTimezone(id OwnerId) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ownerData := s.ownerDataMap[id]
+	if ownerData.Timezone == nil {
+		return "", nil
+	}
+	return *ownerData.Timezone, nil
+}
+
+func (s *ramStorage) SetOwnerTimezone(id OwnerId, tz string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	log.Printf("Setting time zone for owner %d to '%s'", id, tz)
+	ownerData := s.ownerDataMap[id]
+	ownerData.Timezone = &tz
+	s.ownerDataMap[id] = ownerData
+	return nil
+}
+
+func (s *ramStorage) GetOwnerAdminsOnly(id OwnerId) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.ownerAdminsOnly[id], nil
+}
+
+func (s *ramStorage) SetOwnerAdminsOnly(id OwnerId, adminsOnly bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.ownerAdminsOnly[id] = adminsOnly
+	return nil
+}
+
+func (s *ramStorage) SetUserName(userId int, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.userNames[userId] = name
+	return nil
+}
+
+func (s *ramStorage) GetUserName(userId int) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.userNames[userId], nil
+}
+
+func (s *ramStorage) PushOperation(w WalletId, op Operation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	journal := append(s.walletJournal[w], op)
+	if len(journal) > journalLimit {
+		journal = journal[len(journal)-journalLimit:]
+	}
+	s.walletJournal[w] = journal
+	return nil
+}
+
+func (s *ramStorage) PopOperation(w WalletId) (*Operation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	journal := s.walletJournal[w]
+	if len(journal) == 0 {
+		return nil, nil
+	}
+	op := journal[len(journal)-1]
+	s.walletJournal[w] = journal[:len(journal)-1]
+	return &op, nil
+}
+
+func (s *ramStorage) LastOperation(w WalletId) (*Operation, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	journal := s.walletJournal[w]
+	if len(journal) == 0 {
+		return nil, nil
+	}
+	op := journal[len(journal)-1]
+	return &op, nil
+}
+
+func (s *ramStorage) DropOperation(w WalletId, op Operation) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	journal := s.walletJournal[w]
+	if len(journal) == 0 || !journal[len(journal)-1].sameAs(op) {
+		log.Printf("Operation '%s' made at %s is not the latest one in journal of wallet '%s'", op.Type, op.Time, w)
+		return errors.New("Operation is not the latest one in journal")
+	}
+	s.walletJournal[w] = journal[:len(journal)-1]
+	return nil
+}
+
+func (s *ramStorage) GetWalletOwners(w WalletId) ([]OwnerId, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	owners := make([]OwnerId, 0, 2)
+	for id, wallets := range s.ownerWallets {
+		for _, walletId := range wallets {
+			if walletId == w {
+				owners = append(owners, id)
+			}
+		}
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+	return owners, nil
+}
+
+func (s *ramStorage) AttachOwnerToWallet(ownerId OwnerId, w WalletId, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, found := s.walletInfo[w]; !found {
+		log.Printf("Wallet '%s' has not been found for attaching owner %d", w, ownerId)
+		return errors.New("No such wallet")
+	}
+	if err := s.addOwnerWallet(ownerId, w, name); err != nil {
+		return err
+	}
+	ownerData := s.ownerDataMap[ownerId]
+	wId := string(w)
+	ownerData.WalletId = &wId
+	s.ownerDataMap[ownerId] = ownerData
+	return nil
+}
+
+func (s *ramStorage) DetachOwner(ownerId OwnerId, w WalletId) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for name, id := range s.ownerWallets[ownerId] {
+		if id != w {
+			continue
+		}
+		delete(s.ownerWallets[ownerId], name)
+		if ownerData := s.ownerDataMap[ownerId]; ownerData.WalletId != nil && WalletId(*ownerData.WalletId) == w {
+			ownerData.WalletId = nil
+			s.ownerDataMap[ownerId] = ownerData
+		}
+		return nil
+	}
+	log.Printf("Owner %d has no wallet '%s' to be detached from", ownerId, w)
+	return errors.New("No such wallet for owner")
+}
+
+func (s *ramStorage) AddWalletInvite(w WalletId, code string, expires time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, found := s.walletInvites[code]; found {
+		return errors.New("Invite code exists")
+	}
+	s.walletInvites[code] = walletInvite{wallet: w, expires: expires}
+	return nil
+}
+
+func (s *ramStorage) GetWalletInvite(code string) (WalletId, time.Time, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	invite, found := s.walletInvites[code]
+	if !found || !invite.expires.After(time.Now()) {
+		log.Printf("Invite code '%s' is unknown or expired", code)
+		return "", time.Time{}, errors.New("Unknown invite code")
+	}
+	return invite.wallet, invite.expires, nil
+}
+
+func (s *ramStorage) UseWalletInvite(code string) (WalletId, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	invite, found := s.walletInvites[code]
+	if !found || !invite.expires.After(time.Now()) {
+		log.Printf("Invite code '%s' is unknown or expired", code)
+		return "", errors.New("Unknown invite code")
+	}
+	delete(s.walletInvites, code)
+	return invite.wallet, nil
+}
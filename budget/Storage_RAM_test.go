@@ -0,0 +1,9 @@
+package budget
+
+import "testing"
+
+func TestRamStorage(t *testing.T) {
+	testStorageConformance(t, func(t *testing.T) (Storage, func()) {
+		return NewRamStorage(), func() {}
+	})
+}
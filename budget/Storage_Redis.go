@@ -1,49 +1,87 @@
 package budget
 
 import "log"
-import "fmt"
+import "encoding/json"
 import "strconv"
+import "sort"
 import "strings"
 import "errors"
 import "time"
 import "github.com/go-redis/redis"
 import "github.com/satori/go.uuid"
 
-var daysInMonth = map[time.Month]int{
-	time.January:   31,
-	time.February:  28, // TODO: handle leap year
-	time.March:     31,
-	time.April:     30,
-	time.May:       31,
-	time.June:      30,
-	time.July:      31,
-	time.August:    31,
-	time.September: 30,
-	time.October:   31,
-	time.November:  30,
-	time.December:  31}
-
 const defaultMonthStart = 1
 
 type RedisStorage struct {
 	client *redis.Client
 }
 
-func uniqueStringSlice(s []string) []string {
-	result := make([]string, 0, len(s))
-	seen := make(map[string]bool, len(s))
-	for _, elem := range s {
-		if _, found := seen[elem]; found {
-			continue
+func NewRedisStorage(client *redis.Client) Storage {
+	return &RedisStorage{client: client}
+}
+
+// redisMigrations contains data migrations; each element is applied exactly once, in order.
+// Never modify already released migrations - add new ones instead
+var redisMigrations = []func(s *RedisStorage) error{
+	(*RedisStorage).migrateActualTransactionsIndex,
+	(*RedisStorage).migrateTransactionIds,
+	(*RedisStorage).migrateMinorUnits,
+	(*RedisStorage).migrateWalletOwners,
+	(*RedisStorage).migrateOwnerWallets,
+	(*RedisStorage).migrateMessageChats,
+	(*RedisStorage).migrateActualTransactionIds,
+}
+
+func (s *RedisStorage) migrate() error {
+	version, err := s.client.Get(keySchemaVersion()).Int()
+	if err == redis.Nil {
+		version = 0
+	} else if err != nil {
+		log.Printf("Could not get schema version due to error: %s", err)
+		return err
+	}
+
+	for ; version < len(redisMigrations); version++ {
+		log.Printf("Applying Redis data migration %d", version+1)
+		if err := redisMigrations[version](s); err != nil {
+			log.Printf("Redis data migration %d failed with error: %s", version+1, err)
+			return err
+		}
+		if err := s.set(keySchemaVersion(), strconv.Itoa(version+1)); err != nil {
+			return err
 		}
-		result = append(result, elem)
-		seen[elem] = true
 	}
-	return result
+	log.Printf("Redis data is at version %d", version)
+	return nil
 }
 
-func NewRedisStorage(client *redis.Client) Storage {
-	return &RedisStorage{client: client}
+// migrateActualTransactionsIndex builds per-wallet indexes for transactions added before the indexes have been introduced
+func (s *RedisStorage) migrateActualTransactionsIndex() error {
+	for _, match := range []string{"wallet:*:in:*", "wallet:*:out:*"} {
+		keys, err := s.getAllKeys(match)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			keyParts := strings.Split(k, ":")
+			if len(keyParts) != 4 {
+				// e.g. regular transaction keys match the pattern as well
+				continue
+			}
+			tUnix, err := strconv.ParseInt(keyParts[3], 10, 64)
+			if err != nil {
+				log.Printf("Cannot convert time from key '%s' to integer, skipping it; error: %s", k, err)
+				continue
+			}
+			indexKey := keyActualTransactionsIndex(WalletId(keyParts[1]))
+			if err := s.client.ZAdd(indexKey, redis.Z{Score: float64(tUnix), Member: k}).Err(); err != nil {
+				log.Printf("Could not add key '%s' into index '%s' due to error: %s", k, indexKey, err)
+				return err
+			}
+		}
+		log.Printf("Indexed %d keys matching '%s'", len(keys), match)
+	}
+	return nil
 }
 
 func (s *RedisStorage) set(key, value string) error {
@@ -72,18 +110,297 @@ func (s *RedisStorage) setHash(key string, fields map[string]interface{}) error
 	return nil
 }
 
-func (s *RedisStorage) AddActualTransaction(w WalletId, val ActualTransaction) error {
+// migrateTransactionIds moves transactions to keys with unique IDs; previously keys were unique only up to a second
+func (s *RedisStorage) migrateTransactionIds() error {
+	indexKeys, err := s.getAllKeys(scannerActualTransactionsIndexes())
+	if err != nil {
+		return err
+	}
+	for _, indexKey := range indexKeys {
+		indexed, err := s.client.ZRangeWithScores(indexKey, 0, -1).Result()
+		if err != nil {
+			log.Printf("Could not read index '%s' due to error: %s", indexKey, err)
+			return err
+		}
+		for _, z := range indexed {
+			oldKey := z.Member.(string)
+			keyParts := strings.Split(oldKey, ":")
+			if len(keyParts) != 4 {
+				continue // already has an ID
+			}
+			tUnix, _ := strconv.ParseInt(keyParts[3], 10, 64)
+			id := newTransactionId()
+			newKey := keyActualTransaction(WalletId(keyParts[1]), keyParts[2], tUnix, id)
+			log.Printf("Moving actual transaction '%s' to '%s'", oldKey, newKey)
+			_, err := s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+				pipe.Rename(oldKey, newKey)
+				pipe.HSet(newKey, "id", string(id))
+				pipe.ZRem(indexKey, oldKey)
+				pipe.ZAdd(indexKey, redis.Z{Score: z.Score, Member: newKey})
+				return nil
+			})
+			if err != nil {
+				log.Printf("Could not move actual transaction '%s' due to error: %s", oldKey, err)
+				return err
+			}
+		}
+	}
+
+	regularKeys, err := s.getAllKeys(scannerRegularTransactions("*"))
+	if err != nil {
+		return err
+	}
+	for _, oldKey := range regularKeys {
+		if exists, err := s.client.HExists(oldKey, "id").Result(); err != nil || exists {
+			continue // either already has an ID or will be retried during next migration attempt
+		}
+		keyParts := strings.Split(oldKey, ":")
+		date, err := ParseMonthDay(keyParts[4])
+		if err != nil {
+			log.Printf("Could not convert date from key '%s', skipping it; error: %s", oldKey, err)
+			continue
+		}
+		id := newTransactionId()
+		newKey := keyRegularTransaction(WalletId(keyParts[1]), keyParts[3], date, id)
+		log.Printf("Moving regular transaction '%s' to '%s'", oldKey, newKey)
+		_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+			pipe.Rename(oldKey, newKey)
+			pipe.HSet(newKey, "id", string(id))
+			return nil
+		})
+		if err != nil {
+			log.Printf("Could not move regular transaction '%s' due to error: %s", oldKey, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateMinorUnits converts amounts stored in whole units into minor units with default number of decimals.
+// Converted hashes get field 'minor' together with the new value, so that an interrupted migration could be rerun.
+// Journals are dropped as they keep amounts in whole units
+func (s *RedisStorage) migrateMinorUnits() error {
+	factor := int64(pow10(defaultDecimals))
+	keys, err := s.getAllKeys(scannerRegularTransactions("*"))
+	if err != nil {
+		return err
+	}
+	indexKeys, err := s.getAllKeys(scannerActualTransactionsIndexes())
+	if err != nil {
+		return err
+	}
+	for _, indexKey := range indexKeys {
+		indexed, err := s.client.ZRange(indexKey, 0, -1).Result()
+		if err != nil {
+			log.Printf("Could not read index '%s' due to error: %s", indexKey, err)
+			return err
+		}
+		keys = append(keys, indexed...)
+	}
+
+	converted := 0
+	for _, k := range keys {
+		fields, err := s.client.HMGet(k, "value", "minor").Result()
+		if err != nil {
+			log.Printf("Could not get value of '%s' due to error: %s", k, err)
+			return err
+		}
+		if fields[0] == nil {
+			continue // key has been removed while index has not been updated
+		}
+		if fields[1] != nil {
+			continue // converted by previous run
+		}
+		value, err := strconv.ParseInt(fields[0].(string), 10, 64)
+		if err != nil {
+			log.Printf("Could not convert value '%s' of '%s' to integer due to error: %s", fields[0], k, err)
+			return err
+		}
+		if err := s.client.HMSet(k, map[string]interface{}{"value": value * factor, "minor": 1}).Err(); err != nil {
+			log.Printf("Could not update value of '%s' due to error: %s", k, err)
+			return err
+		}
+		converted++
+	}
+	log.Printf("Converted %d transactions into minor units", converted)
+
+	journalKeys, err := s.getAllKeys(keyJournal("*"))
+	if err != nil {
+		return err
+	}
+	for _, k := range journalKeys {
+		if err := s.client.Del(k).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateWalletOwners builds sets of wallet owners which have been introduced together with wallet sharing
+func (s *RedisStorage) migrateWalletOwners() error {
+	keys, err := s.getAllKeys(scannerOwners())
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		walletId, err := s.client.HGet(k, "wallet").Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Could not get wallet of '%s' due to error: %s", k, err)
+			return err
+		}
+		ownerId, err := strconv.ParseInt(strings.Split(k, ":")[1], 10, 64)
+		if err != nil {
+			log.Printf("Could not get owner ID from key '%s', skipping it; error: %s", k, err)
+			continue
+		}
+		if err := s.client.SAdd(keyWalletOwners(WalletId(walletId)), ownerId).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateOwnerWallets gives the default name to the only wallet each owner has had before wallets have got names
+func (s *RedisStorage) migrateOwnerWallets() error {
+	keys, err := s.getAllKeys(scannerOwners())
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		walletId, err := s.client.HGet(k, "wallet").Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("Could not get wallet of '%s' due to error: %s", k, err)
+			return err
+		}
+		ownerId, err := strconv.ParseInt(strings.Split(k, ":")[1], 10, 64)
+		if err != nil {
+			log.Printf("Could not get owner ID from key '%s', skipping it; error: %s", k, err)
+			continue
+		}
+		if err := s.client.HSetNX(keyOwnerWallets(OwnerId(ownerId)), defaultWalletName, walletId).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateMessageChats moves lookups of transactions by messages under chats of the messages. Chat is known only for
+// wallets with a single owner, lookups of shared wallets are dropped as their messages could come from any chat
+func (s *RedisStorage) migrateMessageChats() error {
+	keys, err := s.getAllKeys(scannerActualTransactionMessages())
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		keyParts := strings.Split(k, ":")
+		if len(keyParts) != 4 {
+			continue // already contains chat
+		}
+		messageId, err := strconv.Atoi(keyParts[3])
+		if err != nil {
+			log.Printf("Could not get message ID from key '%s', skipping it; error: %s", k, err)
+			continue
+		}
+		w := WalletId(keyParts[1])
+		key, err := s.client.Get(k).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		owners, err := s.GetWalletOwners(w)
+		if err != nil {
+			return err
+		}
+		_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+			if key != "" && len(owners) == 1 {
+				chatId := int64(owners[0])
+				pipe.HSet(key, "chat", chatId)
+				pipe.Set(keyActualTransactionMessage(w, chatId, messageId), key, 0)
+			}
+			pipe.Del(k)
+			return nil
+		})
+		if err != nil {
+			log.Printf("Could not move message lookup '%s' due to error: %s", k, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateActualTransactionIds fills hashes used for lookup of actual transactions by IDs
+func (s *RedisStorage) migrateActualTransactionIds() error {
+	indexKeys, err := s.getAllKeys(scannerActualTransactionsIndexes())
+	if err != nil {
+		return err
+	}
+	for _, indexKey := range indexKeys {
+		keys, err := s.client.ZRange(indexKey, 0, -1).Result()
+		if err != nil {
+			log.Printf("Could not read index '%s' due to error: %s", indexKey, err)
+			return err
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		ids := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			keyParts := strings.Split(k, ":")
+			ids[keyParts[len(keyParts)-1]] = k
+		}
+		idsKey := keyActualTransactionIds(WalletId(strings.Split(indexKey, ":")[1]))
+		if err := s.client.HMSet(idsKey, ids).Err(); err != nil {
+			log.Printf("Could not fill '%s' due to error: %s", idsKey, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// actualTransactionKeyAndFields returns a key and hash fields which transaction t is stored with
+func actualTransactionKeyAndFields(w WalletId, t ActualTransaction) (string, map[string]interface{}) {
 	operation := "out"
-	if val.Value >= 0 {
+	if t.Value >= 0 {
 		operation = "in"
 	}
-	key := keyActualTransaction(w, operation, val.Time.Unix())
-	fields := make(map[string]interface{}, 3)
-	fields["value"] = val.Value
-	fields["label"] = val.Label
-	fields["raw"] = val.RawText
+	key := keyActualTransaction(w, operation, t.Time.Unix(), t.ID)
+	fields := make(map[string]interface{}, 6)
+	fields["id"] = string(t.ID)
+	fields["value"] = t.Value
+	fields["currency"] = string(t.Currency)
+	fields["label"] = t.Label
+	fields["raw"] = t.RawText
+	fields["msgId"] = t.MessageID
+	fields["chat"] = t.ChatID
+	fields["user"] = t.UserID
+	return key, fields
+}
 
-	return s.setHash(key, fields)
+func (s *RedisStorage) AddActualTransaction(w WalletId, val ActualTransaction) error {
+	if val.ID == "" {
+		val.ID = newTransactionId()
+	}
+	key, fields := actualTransactionKeyAndFields(w, val)
+
+	log.Printf("Setting actual transaction at key %s", key)
+	_, err := s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.HMSet(key, fields)
+		pipe.ZAdd(keyActualTransactionsIndex(w), redis.Z{Score: float64(val.Time.Unix()), Member: key})
+		pipe.HSet(keyActualTransactionIds(w), string(val.ID), key)
+		if val.MessageID != 0 {
+			pipe.Set(keyActualTransactionMessage(w, val.ChatID, val.MessageID), key, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Unable to set actual transaction with key %s; error: %s", key, err)
+	}
+	return err
 }
 
 func (s *RedisStorage) AddRegularTransaction(w WalletId, t RegularTransaction) error {
@@ -91,46 +408,29 @@ func (s *RedisStorage) AddRegularTransaction(w WalletId, t RegularTransaction) e
 	if t.Value < 0 {
 		operation = "out"
 	}
-	now := time.Now().Unix() // necessary for distinguishing 2 records
-	key := keyRegularTransaction(w, operation, t.Date, now)
+	if t.ID == "" {
+		t.ID = newTransactionId()
+	}
+	key := keyRegularTransaction(w, operation, t.Date, t.ID)
 
 	log.Printf("Setting regular monthly income/outcome with value '%d' to key '%s'", t.Value, key)
 
-	fields := make(map[string]interface{}, 3)
+	fields := make(map[string]interface{}, 6)
+	fields["id"] = string(t.ID)
 	fields["value"] = t.Value
+	fields["currency"] = string(t.Currency)
+	fields["shift"] = string(t.Shift)
+	fields["recurrence"] = t.Recurrence.String()
 	fields["label"] = t.Label
 	return s.setHash(key, fields)
 }
 
 func (s *RedisStorage) RemoveRegularTransaction(w WalletId, t RegularTransaction) error {
-	scanner := scannerRegularTransactions(w)
-	keys, err := s.getAllKeys(scanner)
-	if err != nil {
-		log.Printf("Could not scan for scanner '%s' due to error: %s", scanner, err)
-	}
-	targetKey := ""
-	for _, key := range keys {
-		keyParts := strings.Split(key, ":")
-		dateStr := keyParts[4]
-		if dateStr != strconv.Itoa(t.Date) {
-			// it's not the key we're looking for
-			continue
-		}
-		fields, err := s.client.HGetAll(key).Result()
-		if err != nil {
-			log.Printf("Could not get fields for key '%s' during regular transaction removal due to error: %s", w, err)
-			return err
-		}
-		if t.Label == fields["label"] && strconv.Itoa(t.Value) == fields["value"] {
-			targetKey = key
-			break
-		}
-	}
-
-	if targetKey == "" {
-		log.Printf("No transaction in Redis found for wallet '%s' for transaction removal", w)
-		return errors.New("Specified transaction has not been found in DB")
+	operation := "in"
+	if t.Value < 0 {
+		operation = "out"
 	}
+	targetKey := keyRegularTransaction(w, operation, t.Date, t.ID)
 
 	log.Printf("Removing transaction with key '%s'", targetKey)
 	count, err := s.client.Del(targetKey).Result()
@@ -139,8 +439,8 @@ func (s *RedisStorage) RemoveRegularTransaction(w WalletId, t RegularTransaction
 		return err
 	}
 	if count != 1 {
-		log.Printf("Incorrectly removed transaction with key '%s' - removed %d records instead of 1", targetKey, count)
-		return errors.New("Incorrect number of transactions have been removed")
+		log.Printf("No transaction in Redis found with key '%s' for transaction removal (removed %d records instead of 1)", targetKey, count)
+		return errors.New("Specified transaction has not been found in DB")
 	}
 	return nil
 }
@@ -183,15 +483,35 @@ func (s *RedisStorage) GetRegularTransactions(w WalletId) ([]RegularTransaction,
 				return nil, err
 			}
 
-			keyParts := strings.Split(k, ":")
-			dateStr := keyParts[4]
-			date, err := strconv.Atoi(dateStr)
+			rule, err := ParseRecurrence(fields["recurrence"])
 			if err != nil {
-				log.Printf("Could not convert time %s to integer, error: %s", dateStr, err)
+				log.Printf("Could not parse recurrence rule '%s', error: %s", fields["recurrence"], err)
 				return nil, err
 			}
+			date := 0 // weekly transactions have no date
+			if rule.Freq != FreqWeekly {
+				keyParts := strings.Split(k, ":")
+				dateStr := keyParts[4]
+				date, err = ParseMonthDay(dateStr)
+				if err != nil {
+					log.Printf("Could not convert date %s, error: %s", dateStr, err)
+					return nil, err
+				}
+			}
 
-			result = append(result, *NewRegularTransaction(value, date, fields["label"]))
+			tx := RegularTransaction{
+				ID:         TransactionId(fields["id"]),
+				Value:      value,
+				Date:       date,
+				Currency:   Currency(fields["currency"]),
+				Shift:      BusinessDayShift(fields["shift"]),
+				Recurrence: rule,
+				Label:      fields["label"]}
+			if err := tx.validate(); err != nil {
+				log.Printf("Invalid regular transaction at key '%s': %s", k, err)
+				return nil, err
+			}
+			result = append(result, tx)
 
 			repeatedKeysGuard[k] = true
 		}
@@ -233,49 +553,223 @@ func (s *RedisStorage) GetActualTransactions(w WalletId, t1, t2 time.Time) ([]Ac
 		panic("Time borders misaligned")
 	}
 
-	matchIn := fmt.Sprintf("wallet:%s:in:*", w)
-	matchOut := fmt.Sprintf("wallet:%s:out:*", w)
+	indexKey := keyActualTransactionsIndex(w)
+	window := redis.ZRangeBy{
+		Min: strconv.FormatInt(unixCeil(t1), 10),
+		Max: strconv.FormatInt(t2.Unix(), 10)}
+	indexed, err := s.client.ZRangeByScoreWithScores(indexKey, window).Result()
+	if err != nil {
+		log.Printf("Could not get transactions from index '%s' for window %+v, error: %s", indexKey, window, err)
+		return nil, err
+	}
+	log.Printf("Found %d keys in index '%s' for window %+v", len(indexed), indexKey, window)
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, 0, len(indexed))
+	for _, z := range indexed {
+		cmds = append(cmds, pipe.HGetAll(z.Member.(string)))
+	}
+	if len(cmds) > 0 {
+		if _, err := pipe.Exec(); err != nil {
+			log.Printf("Cannot get transactions listed in index '%s', error: %s", indexKey, err)
+			return nil, err
+		}
+	}
+
+	result := make([]ActualTransaction, 0, len(indexed))
+	for i, z := range indexed {
+		fields := cmds[i].Val()
+		if len(fields) == 0 {
+			log.Printf("Key '%s' is listed in index '%s' but is absent, skipping it", z.Member, indexKey)
+			continue
+		}
+
+		tx, err := actualTransactionFromFields(fields, z.Score)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *tx)
+	}
+	return result, nil
+}
+
+func actualTransactionFromFields(fields map[string]string, score float64) (*ActualTransaction, error) {
+	valueStr := fields["value"]
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		log.Printf("Could not convert value %s to integer, error: %s", valueStr, err)
+		return nil, err
+	}
+	tx := NewActualTransaction(value, time.Unix(int64(score), 0), fields["label"], fields["raw"])
+	tx.ID = TransactionId(fields["id"])
+	tx.Currency = Currency(fields["currency"])
+	if msgIdStr, found := fields["msgId"]; found {
+		tx.MessageID, err = strconv.Atoi(msgIdStr)
+		if err != nil {
+			log.Printf("Could not convert message ID %s to integer, error: %s", msgIdStr, err)
+			return nil, err
+		}
+	}
+	if chatIdStr, found := fields["chat"]; found {
+		tx.ChatID, err = strconv.ParseInt(chatIdStr, 10, 64)
+		if err != nil {
+			log.Printf("Could not convert chat ID %s to integer, error: %s", chatIdStr, err)
+			return nil, err
+		}
+	}
+	if userIdStr, found := fields["user"]; found {
+		tx.UserID, err = strconv.Atoi(userIdStr)
+		if err != nil {
+			log.Printf("Could not convert user ID %s to integer, error: %s", userIdStr, err)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
 
-	keysIn, err := s.getAllKeys(matchIn)
+// findActualTransactionKey returns a key of wallet's transaction with given ID
+func (s *RedisStorage) findActualTransactionKey(w WalletId, id TransactionId) (string, error) {
+	if id == "" {
+		return "", errors.New("Empty transaction ID")
+	}
+	idsKey := keyActualTransactionIds(w)
+	key, err := s.client.HGet(idsKey, string(id)).Result()
+	if err == redis.Nil {
+		log.Printf("No actual transaction '%s' found in '%s'", id, idsKey)
+		return "", errors.New("Specified transaction has not been found in DB")
+	}
+	if err != nil {
+		log.Printf("Could not get key of actual transaction '%s' from '%s' due to error: %s", id, idsKey, err)
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *RedisStorage) GetActualTransaction(w WalletId, id TransactionId) (*ActualTransaction, error) {
+	key, err := s.findActualTransactionKey(w, id)
 	if err != nil {
-		log.Printf("Error happened during getting all IN transactions via match '%s', error: %s", matchIn, err)
 		return nil, err
 	}
-	log.Printf("Found %d keys matching scanner '%s'", len(keysIn), matchIn)
-	keysOut, err := s.getAllKeys(matchOut)
+	return s.getActualTransactionByKey(w, key)
+}
+
+func (s *RedisStorage) GetActualTransactionByMessage(w WalletId, chatId int64, messageId int) (*ActualTransaction, error) {
+	lookupKey := keyActualTransactionMessage(w, chatId, messageId)
+	key, err := s.client.Get(lookupKey).Result()
+	if err == redis.Nil {
+		log.Printf("No actual transaction for message %d of chat %d found for wallet '%s'", messageId, chatId, w)
+		return nil, errors.New("Specified transaction has not been found in DB")
+	}
 	if err != nil {
-		log.Printf("Error happened during getting all OUT transactions via match '%s', error: %s", matchOut, err)
+		log.Printf("Could not get transaction key via '%s' due to error: %s", lookupKey, err)
 		return nil, err
 	}
-	log.Printf("Found %d keys matching scanner '%s'", len(keysOut), matchOut)
-	allKeys := append(keysIn, keysOut...)
-	allKeys = uniqueStringSlice(allKeys)
+	return s.getActualTransactionByKey(w, key)
+}
 
-	result := make([]ActualTransaction, 0, len(allKeys))
-	for _, k := range allKeys {
-		tUnix, err := strconv.ParseInt(strings.Split(k, ":")[3], 10, 64)
-		if err != nil {
-			log.Printf("Cannot convert time from key '%s' to integer, error: %s", k, err)
+func (s *RedisStorage) getActualTransactionByKey(w WalletId, key string) (*ActualTransaction, error) {
+	score, err := s.client.ZScore(keyActualTransactionsIndex(w), key).Result()
+	if err == redis.Nil {
+		log.Printf("Key '%s' is not listed in index", key)
+		return nil, errors.New("Specified transaction has not been found in DB")
+	}
+	if err != nil {
+		log.Printf("Could not get time of transaction '%s' due to error: %s", key, err)
+		return nil, err
+	}
+	fields, err := s.client.HGetAll(key).Result()
+	if err != nil {
+		log.Printf("Could not get transaction '%s' due to error: %s", key, err)
+		return nil, err
+	}
+	if len(fields) == 0 {
+		log.Printf("Key '%s' is listed in index but is absent", key)
+		return nil, errors.New("Specified transaction has not been found in DB")
+	}
+	return actualTransactionFromFields(fields, score)
+}
+
+// getActualTransactionMessageKey returns a key of lookup by message of transaction stored at key; empty if it has no message
+func (s *RedisStorage) getActualTransactionMessageKey(w WalletId, key string) (string, error) {
+	fields, err := s.client.HMGet(key, "msgId", "chat").Result()
+	if err != nil {
+		log.Printf("Could not get message ID of transaction '%s' due to error: %s", key, err)
+		return "", err
+	}
+	if fields[0] == nil {
+		return "", nil
+	}
+	messageId, err := strconv.Atoi(fields[0].(string))
+	if err != nil || messageId == 0 {
+		return "", err
+	}
+	var chatId int64
+	if fields[1] != nil {
+		if chatId, err = strconv.ParseInt(fields[1].(string), 10, 64); err != nil {
+			return "", err
 		}
-		t := time.Unix(tUnix, 0)
-		if t.After(t1) && (t.Equal(t2) || t.Before(t2)) {
-			log.Printf("Key '%s' corresponding to date %s is in our time window, getting data from it", k, t)
-			fields, err := s.client.HGetAll(k).Result()
-			if err != nil {
-				log.Printf("Cannot get transaction for key '%s', error: %s", k, err)
-				continue // let's just skip it
-			}
+	}
+	return keyActualTransactionMessage(w, chatId, messageId), nil
+}
 
-			valueStr := fields["value"]
-			value, err := strconv.Atoi(valueStr)
-			if err != nil {
-				log.Printf("Could not convert value %s to integer, error: %s", valueStr, err)
-				return nil, err
-			}
-			result = append(result, *NewActualTransaction(value, t, fields["label"], ""))
+func (s *RedisStorage) UpdateActualTransaction(w WalletId, t ActualTransaction) error {
+	oldKey, err := s.findActualTransactionKey(w, t.ID)
+	if err != nil {
+		return err
+	}
+	oldMessageKey, err := s.getActualTransactionMessageKey(w, oldKey)
+	if err != nil {
+		return err
+	}
+	// time and sign are parts of the key, so the transaction is moved to a new key
+	key, fields := actualTransactionKeyAndFields(w, t)
+
+	log.Printf("Updating actual transaction at key %s (previously %s)", key, oldKey)
+	indexKey := keyActualTransactionsIndex(w)
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(oldKey)
+		pipe.ZRem(indexKey, oldKey)
+		if oldMessageKey != "" {
+			pipe.Del(oldMessageKey)
+		}
+		pipe.HMSet(key, fields)
+		pipe.ZAdd(indexKey, redis.Z{Score: float64(t.Time.Unix()), Member: key})
+		pipe.HSet(keyActualTransactionIds(w), string(t.ID), key)
+		if t.MessageID != 0 {
+			pipe.Set(keyActualTransactionMessage(w, t.ChatID, t.MessageID), key, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Unable to update actual transaction with key %s; error: %s", key, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) RemoveActualTransaction(w WalletId, id TransactionId) error {
+	key, err := s.findActualTransactionKey(w, id)
+	if err != nil {
+		return err
+	}
+	messageKey, err := s.getActualTransactionMessageKey(w, key)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Removing actual transaction with key '%s'", key)
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(key)
+		pipe.ZRem(keyActualTransactionsIndex(w), key)
+		pipe.HDel(keyActualTransactionIds(w), string(id))
+		if messageKey != "" {
+			pipe.Del(messageKey)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not remove actual transaction with key '%s' due to error: %s", key, err)
 	}
-	return result, nil
+	return err
 }
 
 func (s *RedisStorage) GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (*Wallet, error) {
@@ -300,7 +794,31 @@ func (s *RedisStorage) GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (
 		return s.CreateWalletOwner(ownerId)
 	}
 
-	walletKey := keyWallet(WalletId(walletId))
+	name, err := s.walletName(ownerId, WalletId(walletId))
+	if err != nil {
+		return nil, err
+	}
+	return s.loadWallet(WalletId(walletId), name)
+}
+
+// walletName returns a name which the owner has given to the wallet
+func (s *RedisStorage) walletName(ownerId OwnerId, w WalletId) (string, error) {
+	wallets, err := s.client.HGetAll(keyOwnerWallets(ownerId)).Result()
+	if err != nil {
+		log.Printf("Could not get wallets of owner %d due to error: %s", ownerId, err)
+		return "", err
+	}
+	for name, id := range wallets {
+		if WalletId(id) == w {
+			return name, nil
+		}
+	}
+	return defaultWalletName, nil
+}
+
+func (s *RedisStorage) loadWallet(w WalletId, name string) (*Wallet, error) {
+	walletId := string(w)
+	walletKey := keyWallet(w)
 	fields, err := s.client.HGetAll(walletKey).Result()
 	if err != nil {
 		log.Printf("Could not get wallet fields via key '%s'", walletKey)
@@ -316,18 +834,50 @@ func (s *RedisStorage) GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (
 		}
 	}
 
-	return NewWalletFromStorage(walletId, monthStart, s), nil
+	wallet := NewWalletFromStorage(walletId, monthStart, s)
+	wallet.Name = name
+	wallet.Currency = Currency(fields["currency"])
+	if decimalsStr, found := fields["decimals"]; found {
+		wallet.Decimals, err = strconv.Atoi(decimalsStr)
+		if err != nil {
+			log.Printf("Could not convert decimals %s for wallet '%s' due to error: %s", decimalsStr, walletKey, err)
+			return nil, err
+		}
+	}
+	return wallet, nil
 }
 
-func (s *RedisStorage) attachWalletToUser(ownerKey string, walletId string) error {
-	res := s.client.HSet(ownerKey, "wallet", walletId)
-
-	if res != nil && res.Val() == false {
-		log.Printf("Could not attach owner '%s' and wallet '%s'", ownerKey, walletId)
-		return errors.New("Could not attach wallet to owner")
+// addOwnerWallet adds wallet w to the owner's wallets under the name keeping sets of wallet owners consistent;
+// the wallet becomes active if activate is set
+func (s *RedisStorage) addOwnerWallet(ownerId OwnerId, w WalletId, name string, activate bool) error {
+	ownerKey := keyOwner(ownerId)
+	walletsKey := keyOwnerWallets(ownerId)
+	if member, err := s.client.SIsMember(keyWalletOwners(w), int64(ownerId)).Result(); err != nil || member {
+		log.Printf("Owner %d already has wallet '%s' (error: %v)", ownerId, w, err)
+		return errors.New("Wallet is already attached to owner")
+	}
+	added, err := s.client.HSetNX(walletsKey, name, string(w)).Result()
+	if err != nil {
+		return err
+	}
+	if !added {
+		log.Printf("Owner %d already has wallet named '%s'", ownerId, name)
+		return errors.New("Wallet name exists")
+	}
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		if activate {
+			pipe.HSet(ownerKey, "wallet", string(w))
+		}
+		pipe.SAdd(keyWalletOwners(w), int64(ownerId))
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not attach owner '%s' and wallet '%s' due to error: %s", ownerKey, w, err)
+		s.client.HDel(walletsKey, name)
+		return err
 	}
 
-	log.Printf("Attached owner with key '%s' and wallet '%s'", ownerKey, walletId)
+	log.Printf("Attached owner with key '%s' and wallet '%s' named '%s'", ownerKey, w, name)
 	return nil
 }
 
@@ -335,8 +885,12 @@ func (s *RedisStorage) CreateWalletOwner(ownerId OwnerId) (*Wallet, error) {
 	log.Printf("Starting creation of owner %d", ownerId)
 
 	key := keyOwner(ownerId)
-	owner := s.client.HGetAll(key)
-	if owner != nil && len(owner.Val()) > 0 {
+	exists, err := s.client.HExists(key, "wallet").Result()
+	if err != nil {
+		log.Printf("Could not check wallet existence for owner %d due to error: %s", ownerId, err)
+		return nil, err
+	}
+	if exists {
 		log.Printf("Owner %d has been already created", ownerId)
 		return nil, errors.New("Owner exists")
 	}
@@ -348,11 +902,55 @@ func (s *RedisStorage) CreateWalletOwner(ownerId OwnerId) (*Wallet, error) {
 	}
 	log.Printf("Wallet %s has been created for owner %d", wallet.ID, ownerId)
 
-	s.attachWalletToUser(key, string(wallet.ID))
+	if err := s.addOwnerWallet(ownerId, wallet.ID, defaultWalletName, true); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (s *RedisStorage) GetOwnerWallets(ownerId OwnerId) ([]*Wallet, error) {
+	ids, err := s.client.HGetAll(keyOwnerWallets(ownerId)).Result()
+	if err != nil {
+		log.Printf("Could not get wallets of owner %d due to error: %s", ownerId, err)
+		return nil, err
+	}
+	wallets := make([]*Wallet, 0, len(ids))
+	for name, id := range ids {
+		wallet, err := s.loadWallet(WalletId(id), name)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Name < wallets[j].Name })
+	return wallets, nil
+}
 
+func (s *RedisStorage) AddOwnerWallet(ownerId OwnerId, name string) (*Wallet, error) {
+	if exists, err := s.client.HExists(keyOwnerWallets(ownerId), name).Result(); err != nil || exists {
+		log.Printf("Owner %d already has wallet named '%s' (error: %v)", ownerId, name, err)
+		return nil, errors.New("Wallet name exists")
+	}
+	wallet, err := s.createWallet()
+	if err != nil {
+		log.Printf("Could not create wallet '%s' for owner %d with error: %s", name, ownerId, err)
+		return nil, err
+	}
+	if err := s.addOwnerWallet(ownerId, wallet.ID, name, false); err != nil {
+		return nil, err
+	}
+	wallet.Name = name
 	return wallet, nil
 }
 
+func (s *RedisStorage) SetActiveWallet(ownerId OwnerId, w WalletId) error {
+	if member, err := s.client.SIsMember(keyWalletOwners(w), int64(ownerId)).Result(); err != nil || !member {
+		log.Printf("Wallet '%s' doesn't belong to owner %d (error: %v)", w, ownerId, err)
+		return errors.New("No such wallet for owner")
+	}
+	return s.client.HSet(keyOwner(ownerId), "wallet", string(w)).Err()
+}
+
 func (s *RedisStorage) createWallet() (*Wallet, error) {
 	var wallet *Wallet = nil
 	for wallet == nil {
@@ -385,6 +983,9 @@ func parseOwnerData(data map[string]string) OwnerData {
 	if walletId, found := data["wallet"]; found {
 		ownerData.WalletId = &walletId
 	}
+	if tz, found := data["tz"]; found {
+		ownerData.Timezone = &tz
+	}
 	if reminderTime, found := data["dailyNotifTime"]; found {
 		dur, err := time.ParseDuration(reminderTime)
 		if err == nil {
@@ -421,12 +1022,17 @@ func (s *RedisStorage) GetAllOwners() (map[OwnerId]OwnerData, error) {
 				log.Printf("Could not get owner ID from key %s; error: %s", k, err)
 				continue
 			}
+			ownerData.RegularTxs = make(map[int][]RegularTransaction, 0)
+			if ownerData.WalletId == nil {
+				log.Printf("Owner %d has no wallet yet", ownerId)
+				resultMap[OwnerId(ownerId)] = ownerData
+				continue
+			}
 			regularTxs, err := s.GetRegularTransactions(WalletId(*ownerData.WalletId))
 			if err != nil {
 				log.Printf("Could not get regular transactions for owner %d wallet '%s' due to error: %s", ownerId, *ownerData.WalletId, err)
 				// let's move forward to complete at least what we have
 			}
-			ownerData.RegularTxs = make(map[int][]RegularTransaction, len(regularTxs))
 			for _, tx := range regularTxs {
 				if sameDateTxs, found := ownerData.RegularTxs[tx.Date]; found {
 					ownerData.RegularTxs[tx.Date] = append(sameDateTxs, tx)
@@ -453,6 +1059,144 @@ func (s *RedisStorage) SetWalletInfo(w WalletId, monthStart int) error {
 	return s.setHash(key, fields)
 }
 
+func (s *RedisStorage) SetWalletCurrency(w WalletId, c Currency) error {
+	key := keyWallet(w)
+	fields := make(map[string]interface{}, 1)
+	fields["currency"] = string(c)
+	return s.setHash(key, fields)
+}
+
+// RescaleWallet reads all amounts of the wallet first and then writes them back rescaled within a single MULTI
+func (s *RedisStorage) RescaleWallet(w WalletId, from, to int) error {
+	if exists, err := s.client.Exists(keyWallet(w)).Result(); err != nil || exists == 0 {
+		log.Printf("Wallet '%s' has not been found for setting decimals (error: %v)", w, err)
+		return errors.New("No such wallet")
+	}
+	keys, err := s.client.ZRange(keyActualTransactionsIndex(w), 0, -1).Result()
+	if err != nil {
+		log.Printf("Could not read transactions index of wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	regularKeys, err := s.getAllKeys(scannerRegularTransactions(w))
+	if err != nil {
+		return err
+	}
+	values := make(map[string]int, len(keys)+len(regularKeys))
+	for _, k := range append(keys, regularKeys...) {
+		value, err := s.client.HGet(k, "value").Int()
+		if err == redis.Nil {
+			continue // key has been removed while index has not been updated
+		}
+		if err != nil {
+			log.Printf("Could not get value of '%s' due to error: %s", k, err)
+			return err
+		}
+		values[k] = rescaleAmount(value, from, to)
+	}
+
+	goals, err := s.GetGoals(w)
+	if err != nil {
+		return err
+	}
+	goalFields := make(map[string]interface{}, len(goals))
+	for _, g := range goals {
+		g.Target = rescaleAmount(g.Target, from, to)
+		data, err := json.Marshal(g)
+		if err != nil {
+			log.Printf("Could not serialize goal %+v due to error: %s", g, err)
+			return err
+		}
+		goalFields[string(g.ID)] = data
+	}
+	limits, err := s.GetWalletLimits(w)
+	if err != nil {
+		return err
+	}
+	limitFields := make(map[string]interface{}, len(limits))
+	for label, limit := range limits {
+		limitFields[label] = rescaleAmount(limit, from, to)
+	}
+	carryOver, err := s.GetWalletCarryOver(w)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for k, value := range values {
+			pipe.HSet(k, "value", value)
+		}
+		if len(goalFields) > 0 {
+			pipe.HMSet(keyGoals(w), goalFields)
+		}
+		if len(limitFields) > 0 {
+			pipe.HMSet(keyWalletLimits(w), limitFields)
+		}
+		pipe.HMSet(keyWallet(w), map[string]interface{}{"decimals": to, "carryOver": carryOver.rescaled(from, to).String()})
+		pipe.Del(keyJournal(w))
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not rescale wallet '%s' from %d to %d decimals due to error: %s", w, from, to, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) SetWalletCarryOver(w WalletId, c CarryOver) error {
+	key := keyWallet(w)
+	fields := make(map[string]interface{}, 1)
+	fields["carryOver"] = c.String()
+	return s.setHash(key, fields)
+}
+
+func (s *RedisStorage) GetWalletCarryOver(w WalletId) (CarryOver, error) {
+	text, err := s.client.HGet(keyWallet(w), "carryOver").Result()
+	if err == redis.Nil {
+		return CarryOver{}, nil
+	}
+	if err != nil {
+		log.Printf("Could not get carry-over policy of wallet '%s' due to error: %s", w, err)
+		return CarryOver{}, err
+	}
+	c, err := ParseCarryOver(text)
+	if err != nil {
+		log.Printf("Could not parse carry-over policy '%s' of wallet '%s' due to error: %s", text, w, err)
+		return CarryOver{}, err
+	}
+	return c, nil
+}
+
+func (s *RedisStorage) SetWalletLimit(w WalletId, label string, limit int) error {
+	key := keyWalletLimits(w)
+	var err error
+	if limit == 0 {
+		err = s.client.HDel(key, label).Err()
+	} else {
+		err = s.client.HSet(key, label, limit).Err()
+	}
+	if err != nil {
+		log.Printf("Could not set limit of label '%s' of wallet '%s' due to error: %s", label, w, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) GetWalletLimits(w WalletId) (map[string]int, error) {
+	fields, err := s.client.HGetAll(keyWalletLimits(w)).Result()
+	if err != nil {
+		log.Printf("Could not get limits of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	result := make(map[string]int, len(fields))
+	for label, valueStr := range fields {
+		value, err := strconv.Atoi(valueStr)
+		if err != nil {
+			log.Printf("Could not convert limit %s of label '%s' to integer, error: %s", valueStr, label, err)
+			return nil, err
+		}
+		result[label] = value
+	}
+	return result, nil
+}
+
 func (s *RedisStorage) GetOwnerDailyNotificationTime(id OwnerId) (*time.Duration, error) {
 	k := keyOwner(id)
 
@@ -487,3 +1231,304 @@ func (s *RedisStorage) SetOwnerDailyNotificationTime(id OwnerId, notifTime *time
 	log.Printf("Setting daily notification time for key '%s' to '%s'", k, notifTime)
 	return s.client.HSet(k, "dailyNotifTime", notifTime.String()).Err()
 }
+
+func (s *RedisStorage) GetOwnerTimezone(id OwnerId) (string, error) {
+	tz, err := s.client.HGet(keyOwner(id), "tz").Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Could not get time zone for owner %d due to error: %s", id, err)
+		return "", err
+	}
+	return tz, nil
+}
+
+func (s *RedisStorage) SetOwnerTimezone(id OwnerId, tz string) error {
+	log.Printf("Setting time zone for owner %d to '%s'", id, tz)
+	return s.client.HSet(keyOwner(id), "tz", tz).Err()
+}
+
+func (s *RedisStorage) GetOwnerAdminsOnly(id OwnerId) (bool, error) {
+	value, err := s.client.HGet(keyOwner(id), "adminsOnly").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Could not get admins only setting for owner %d due to error: %s", id, err)
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+func (s *RedisStorage) SetOwnerAdminsOnly(id OwnerId, adminsOnly bool) error {
+	log.Printf("Setting admins only mode for owner %d to %t", id, adminsOnly)
+	return s.client.HSet(keyOwner(id), "adminsOnly", strconv.FormatBool(adminsOnly)).Err()
+}
+
+func (s *RedisStorage) SetUserName(userId int, name string) error {
+	if err := s.client.HSet(keyUserNames(), strconv.Itoa(userId), name).Err(); err != nil {
+		log.Printf("Could not set name of user %d due to error: %s", userId, err)
+		return err
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetUserName(userId int) (string, error) {
+	name, err := s.client.HGet(keyUserNames(), strconv.Itoa(userId)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Could not get name of user %d due to error: %s", userId, err)
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *RedisStorage) AddGoal(w WalletId, g Goal) error {
+	if g.ID == "" {
+		g.ID = newGoalId()
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		log.Printf("Could not serialize goal %+v due to error: %s", g, err)
+		return err
+	}
+	if err := s.client.HSet(keyGoals(w), string(g.ID), data).Err(); err != nil {
+		log.Printf("Could not add goal '%s' to wallet '%s' due to error: %s", g.Name, w, err)
+		return err
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetGoals(w WalletId) ([]Goal, error) {
+	fields, err := s.client.HGetAll(keyGoals(w)).Result()
+	if err != nil {
+		log.Printf("Could not get goals of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	result := make([]Goal, 0, len(fields))
+	for id, data := range fields {
+		g := Goal{}
+		if err := json.Unmarshal([]byte(data), &g); err != nil {
+			log.Printf("Could not parse goal '%s' of wallet '%s' due to error: %s", id, w, err)
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *RedisStorage) RemoveGoal(w WalletId, id GoalId) error {
+	count, err := s.client.HDel(keyGoals(w), string(id)).Result()
+	if err != nil {
+		log.Printf("Could not remove goal '%s' of wallet '%s' due to error: %s", id, w, err)
+		return err
+	}
+	if count != 1 {
+		log.Printf("No goal '%s' found for wallet '%s' for removal", id, w)
+		return errors.New("Specified goal has not been found in DB")
+	}
+	return nil
+}
+
+func (s *RedisStorage) PushOperation(w WalletId, op Operation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		log.Printf("Could not serialize operation %+v due to error: %s", op, err)
+		return err
+	}
+	key := keyJournal(w)
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.LPush(key, data)
+		pipe.LTrim(key, 0, journalLimit-1)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not add operation to journal '%s' due to error: %s", key, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) PopOperation(w WalletId) (*Operation, error) {
+	key := keyJournal(w)
+	data, err := s.client.LPop(key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Could not get operation from journal '%s' due to error: %s", key, err)
+		return nil, err
+	}
+	op := &Operation{}
+	if err := json.Unmarshal(data, op); err != nil {
+		log.Printf("Could not parse operation '%s' from journal '%s' due to error: %s", data, key, err)
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *RedisStorage) LastOperation(w WalletId) (*Operation, error) {
+	key := keyJournal(w)
+	data, err := s.client.LIndex(key, 0).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Could not get latest operation from journal '%s' due to error: %s", key, err)
+		return nil, err
+	}
+	op := &Operation{}
+	if err := json.Unmarshal(data, op); err != nil {
+		log.Printf("Could not parse operation '%s' from journal '%s' due to error: %s", data, key, err)
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *RedisStorage) DropOperation(w WalletId, op Operation) error {
+	key := keyJournal(w)
+	err := s.client.Watch(func(tx *redis.Tx) error {
+		data, err := tx.LIndex(key, 0).Bytes()
+		if err == redis.Nil {
+			return errors.New("Operation is not the latest one in journal")
+		}
+		if err != nil {
+			return err
+		}
+		latest := Operation{}
+		if err := json.Unmarshal(data, &latest); err != nil {
+			return err
+		}
+		if !latest.sameAs(op) {
+			return errors.New("Operation is not the latest one in journal")
+		}
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.LPop(key)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		log.Printf("Could not drop operation '%s' made at %s from journal '%s' due to error: %s", op.Type, op.Time, key, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) GetWalletOwners(w WalletId) ([]OwnerId, error) {
+	members, err := s.client.SMembers(keyWalletOwners(w)).Result()
+	if err != nil {
+		log.Printf("Could not get owners of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	owners := make([]OwnerId, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			log.Printf("Could not convert owner ID %s of wallet '%s' to integer, error: %s", m, w, err)
+			return nil, err
+		}
+		owners = append(owners, OwnerId(id))
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+	return owners, nil
+}
+
+func (s *RedisStorage) AttachOwnerToWallet(ownerId OwnerId, w WalletId, name string) error {
+	if exists, err := s.client.Exists(keyWallet(w)).Result(); err != nil || exists == 0 {
+		log.Printf("Wallet '%s' has not been found for attaching owner %d (error: %v)", w, ownerId, err)
+		return errors.New("No such wallet")
+	}
+	return s.addOwnerWallet(ownerId, w, name, true)
+}
+
+func (s *RedisStorage) DetachOwner(ownerId OwnerId, w WalletId) error {
+	key := keyOwner(ownerId)
+	wallets, err := s.client.HGetAll(keyOwnerWallets(ownerId)).Result()
+	if err != nil {
+		return err
+	}
+	name := ""
+	for n, id := range wallets {
+		if WalletId(id) == w {
+			name = n
+		}
+	}
+	if name == "" {
+		log.Printf("Owner %d has no wallet '%s' to be detached from", ownerId, w)
+		return errors.New("No such wallet for owner")
+	}
+	active, err := s.client.HGet(key, "wallet").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		if WalletId(active) == w {
+			pipe.HDel(key, "wallet")
+		}
+		pipe.HDel(keyOwnerWallets(ownerId), name)
+		pipe.SRem(keyWalletOwners(w), int64(ownerId))
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not detach owner %d from wallet '%s' due to error: %s", ownerId, w, err)
+	}
+	return err
+}
+
+func (s *RedisStorage) AddWalletInvite(w WalletId, code string, expires time.Time) error {
+	lifetime := expires.Sub(time.Now())
+	if lifetime <= 0 {
+		return errors.New("Invite has already expired")
+	}
+	added, err := s.client.SetNX(keyWalletInvite(code), string(w), lifetime).Result()
+	if err != nil {
+		log.Printf("Could not add invite to wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	if !added {
+		return errors.New("Invite code exists")
+	}
+	return nil
+}
+
+func (s *RedisStorage) GetWalletInvite(code string) (WalletId, time.Time, error) {
+	key := keyWalletInvite(code)
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		ttl = pipe.PTTL(key)
+		return nil
+	})
+	if err == redis.Nil {
+		log.Printf("Invite code '%s' is unknown or expired", code)
+		return "", time.Time{}, errors.New("Unknown invite code")
+	}
+	if err != nil {
+		log.Printf("Could not get invite code '%s' due to error: %s", code, err)
+		return "", time.Time{}, err
+	}
+	return WalletId(get.Val()), time.Now().Add(ttl.Val()), nil
+}
+
+func (s *RedisStorage) UseWalletInvite(code string) (WalletId, error) {
+	key := keyWalletInvite(code)
+	var get *redis.StringCmd
+	_, err := s.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(key)
+		pipe.Del(key)
+		return nil
+	})
+	if err == redis.Nil {
+		log.Printf("Invite code '%s' is unknown or expired", code)
+		return "", errors.New("Unknown invite code")
+	}
+	if err != nil {
+		log.Printf("Could not use invite code '%s' due to error: %s", code, err)
+		return "", err
+	}
+	return WalletId(get.Val()), nil
+}
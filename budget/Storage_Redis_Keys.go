@@ -6,18 +6,84 @@ func keyOwner(owner OwnerId) string {
 	return fmt.Sprintf("owner:%d", owner)
 }
 
-func keyActualTransaction(wId WalletId, operation string, tUnix int64) string {
-	return fmt.Sprintf("wallet:%s:%s:%d", wId, operation, tUnix)
+func keyActualTransaction(wId WalletId, operation string, tUnix int64, id TransactionId) string {
+	return fmt.Sprintf("wallet:%s:%s:%d:%s", wId, operation, tUnix, id)
 }
 
-func keyRegularTransaction(wId WalletId, operation string, regularDate int, addDateUnix int64) string {
-	return fmt.Sprintf("wallet:%s:monthly:%s:%d:%d", wId, operation, regularDate, addDateUnix)
+// keyActualTransactionsIndex is a sorted set of wallet's actual transaction keys scored by unix time
+func keyActualTransactionsIndex(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:actual", wId)
+}
+
+// keyActualTransactionIds is a hash of keys of wallet's actual transactions by their IDs
+func keyActualTransactionIds(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:actualById", wId)
+}
+
+// keyActualTransactionMessage keeps a key of actual transaction parsed from telegram message of the chat
+func keyActualTransactionMessage(wId WalletId, chatId int64, messageId int) string {
+	return fmt.Sprintf("wallet:%s:message:%d:%d", wId, chatId, messageId)
+}
+
+// keyJournal is a list of wallet's operations, the latest one goes first
+func keyJournal(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:journal", wId)
+}
+
+// keyWalletLimits is a hash of monthly spending limits by labels
+func keyWalletLimits(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:limits", wId)
+}
+
+// keyGoals is a hash of wallet's goals serialized into JSON by their IDs
+func keyGoals(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:goals", wId)
+}
+
+// keyWalletOwners is a set of owners sharing the wallet
+func keyWalletOwners(wId WalletId) string {
+	return fmt.Sprintf("wallet:%s:owners", wId)
+}
+
+// keyOwnerWallets is a hash of owner's wallet IDs by their names
+func keyOwnerWallets(owner OwnerId) string {
+	return fmt.Sprintf("wallets:%d", owner)
+}
+
+// keyWalletInvite keeps a wallet which could be joined using the code until the key expires
+func keyWalletInvite(code string) string {
+	return fmt.Sprintf("invite:%s", code)
+}
+
+// keyUserNames is a hash of telegram user names by their IDs
+func keyUserNames() string {
+	return "users:names"
+}
+
+func scannerActualTransactionMessages() string {
+	return "wallet:*:message:*"
+}
+
+func scannerOwners() string {
+	return "owner:*"
+}
+
+func keySchemaVersion() string {
+	return "budget:schemaVersion"
+}
+
+func keyRegularTransaction(wId WalletId, operation string, regularDate int, id TransactionId) string {
+	return fmt.Sprintf("wallet:%s:monthly:%s:%s:%s", wId, operation, FormatMonthDay(regularDate), id)
 }
 
 func keyWallet(wId WalletId) string {
 	return fmt.Sprintf("wallet:%s", wId)
 }
 
+func scannerActualTransactionsIndexes() string {
+	return "wallet:*:actual"
+}
+
 func scannerRegularTransactions(wId WalletId) string {
 	return fmt.Sprintf("wallet:%s:monthly:*", wId)
 }
@@ -0,0 +1,280 @@
+package budget
+
+import "os"
+import "fmt"
+import "time"
+import "testing"
+import "github.com/go-redis/redis"
+import "github.com/alicebob/miniredis"
+
+// testRedisServerEnv names an env variable with address of a real redis-server to be used instead of in-process fake.
+// Note that the selected DB is flushed before each test
+const testRedisServerEnv = "BUDGET_TEST_REDIS"
+
+func newTestRedisClient(t *testing.T) (*redis.Client, func()) {
+	if addr := os.Getenv(testRedisServerEnv); addr != "" {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		if err := client.FlushDB().Err(); err != nil {
+			t.Fatalf("Could not flush redis DB at %s: %s", addr, err)
+		}
+		return client, func() { client.Close() }
+	}
+
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("Could not start in-process redis: %s", err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return client, func() {
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestRedisStorage(t *testing.T) {
+	testStorageConformance(t, func(t *testing.T) (Storage, func()) {
+		client, release := newTestRedisClient(t)
+		return NewRedisStorage(client), release
+	})
+}
+
+func TestRedisStorage_ActualTransactionsIndexMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// transactions stored before indexes have been introduced
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	legacy := map[string]map[string]interface{}{
+		fmt.Sprintf("wallet:w1:out:%d", t1.Unix()):                  {"value": -100, "label": "food", "raw": "100 #food"},
+		fmt.Sprintf("wallet:w1:in:%d", t1.Add(time.Hour).Unix()):    {"value": 500, "label": "", "raw": "+500"},
+		fmt.Sprintf("wallet:w2:out:%d", t1.Add(time.Minute).Unix()): {"value": -7, "label": "", "raw": "7"},
+		fmt.Sprintf("wallet:w1:monthly:in:5:%d", t1.Unix()):         {"value": 1000, "label": "salary"},
+	}
+	for k, fields := range legacy {
+		if err := client.HMSet(k, fields).Err(); err != nil {
+			t.FailNow()
+		}
+	}
+
+	// only index migration is checked here
+	if err := redisMigrations[0](s); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	tMin, tMax := t1.AddDate(0, 0, -1), t1.AddDate(0, 0, 1)
+	if txs, err := s.GetActualTransactions("w1", tMin, tMax); err != nil || len(txs) != 2 {
+		t.Errorf("Unexpected transactions of first wallet after migration: %+v", txs)
+	}
+	if txs, err := s.GetActualTransactions("w2", tMin, tMax); err != nil || len(txs) != 1 || txs[0].Value != -7 {
+		t.Errorf("Unexpected transactions of second wallet after migration: %+v", txs)
+	}
+
+	if count := client.ZCard(keyActualTransactionsIndex("w1")).Val(); count != 2 {
+		t.Errorf("Index contains %d keys instead of 2", count)
+	}
+}
+
+func TestRedisStorage_TransactionIdsMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// keys without IDs which are indexed by the first migration
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	legacy := map[string]map[string]interface{}{
+		fmt.Sprintf("wallet:w1:out:%d", t1.Unix()):          {"value": -100, "label": "food", "raw": "100 #food"},
+		fmt.Sprintf("wallet:w1:in:%d", t1.Unix()+1):         {"value": 500, "label": "", "raw": "+500"},
+		fmt.Sprintf("wallet:w1:monthly:in:5:%d", t1.Unix()): {"value": 1000, "label": "salary"},
+	}
+	for k, fields := range legacy {
+		if err := client.HMSet(k, fields).Err(); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if err := s.migrate(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	txs, err := s.GetActualTransactions("w1", t1, t1.Add(time.Minute))
+	if err != nil || len(txs) != 2 || txs[0].ID == "" || txs[1].ID == "" || txs[0].ID == txs[1].ID {
+		t.Fatalf("Actual transactions have not got unique IDs: %+v", txs)
+	}
+	for _, k := range []string{fmt.Sprintf("wallet:w1:out:%d", t1.Unix()), fmt.Sprintf("wallet:w1:monthly:in:5:%d", t1.Unix())} {
+		if client.Exists(k).Val() != 0 {
+			t.Errorf("Legacy key '%s' has not been removed", k)
+		}
+	}
+
+	regulars, err := s.GetRegularTransactions("w1")
+	if err != nil || len(regulars) != 1 || regulars[0].ID == "" {
+		t.Fatalf("Regular transaction has not got an ID: %+v", regulars)
+	}
+	if err := s.RemoveRegularTransaction("w1", regulars[0]); err != nil {
+		t.Errorf("Migrated regular transaction could not be removed: %s", err)
+	}
+
+	if err := s.migrate(); err != nil {
+		t.Errorf("Repeated migration failed: %s", err)
+	}
+	if txs2, _ := s.GetActualTransactions("w1", t1, t1.Add(time.Minute)); len(txs2) != 2 {
+		t.Errorf("Repeated migration changed transactions: %+v", txs2)
+	}
+}
+
+func TestRedisStorage_MinorUnitsMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	if err := s.AddActualTransaction(w.ID, *NewActualTransaction(-100, t1, "food", "100 #food")); err != nil {
+		t.FailNow()
+	}
+	if err := s.AddRegularTransaction(w.ID, *NewRegularTransaction(1000, 5, "salary")); err != nil {
+		t.FailNow()
+	}
+	if err := s.PushOperation(w.ID, Operation{Type: OperationSetMonthStart, MonthStart: 1}); err != nil {
+		t.FailNow()
+	}
+
+	if err := s.migrateMinorUnits(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	// rerun of interrupted migration must not convert anything twice
+	if err := s.migrateMinorUnits(); err != nil {
+		t.Fatalf("Repeated migration failed: %s", err)
+	}
+	if txs, err := s.GetActualTransactions(w.ID, t1, t1); err != nil || len(txs) != 1 || txs[0].Value != -10000 {
+		t.Errorf("Actual transaction has not been converted: %+v", txs)
+	}
+	if regulars, err := s.GetRegularTransactions(w.ID); err != nil || len(regulars) != 1 || regulars[0].Value != 100000 {
+		t.Errorf("Regular transaction has not been converted: %+v", regulars)
+	}
+	if op, err := s.PopOperation(w.ID); err != nil || op != nil {
+		t.Errorf("Journal has not been dropped: %+v", op)
+	}
+	if stored, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || stored.Decimals != defaultDecimals {
+		t.Errorf("Unexpected wallet after migration: %+v", stored)
+	}
+}
+
+func TestRedisStorage_WalletOwnersMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// owners created before wallet sharing have no sets of wallet owners
+	client.HSet("owner:1", "wallet", "w1")
+	client.HSet("owner:2", "dailyNotifTime", "9h0m0s")
+	client.HSet("wallet:w1", "monthStart", 1)
+
+	if err := s.migrateWalletOwners(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	if owners, err := s.GetWalletOwners("w1"); err != nil || len(owners) != 1 || owners[0] != 1 {
+		t.Errorf("Unexpected owners after migration: %v", owners)
+	}
+}
+
+func TestRedisStorage_OwnerWalletsMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// owners created before named wallets have the only unnamed wallet
+	client.HSet("owner:1", "wallet", "w1")
+	client.HSet("owner:2", "dailyNotifTime", "9h0m0s")
+	client.HSet("wallet:w1", "monthStart", 1)
+	client.SAdd(keyWalletOwners("w1"), 1)
+
+	if err := s.migrateOwnerWallets(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	if wallets, err := s.GetOwnerWallets(OwnerId(1)); err != nil || len(wallets) != 1 || wallets[0].ID != "w1" || wallets[0].Name != defaultWalletName {
+		t.Errorf("Unexpected wallets after migration: %+v", wallets)
+	}
+	if wallets, err := s.GetOwnerWallets(OwnerId(2)); err != nil || len(wallets) != 0 {
+		t.Errorf("Owner without wallet got wallets after migration: %+v", wallets)
+	}
+	if _, err := s.AddOwnerWallet(OwnerId(1), defaultWalletName); err == nil {
+		t.Errorf("Default name of migrated wallet has been used twice")
+	}
+}
+
+func TestRedisStorage_MessageChatsMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// lookups created before chats have been stored are keyed by wallet and message only
+	client.HMSet("wallet:w1:actual:1528632000:t1", map[string]interface{}{"id": "t1", "value": -500, "raw": "500", "msgId": 42})
+	client.ZAdd(keyActualTransactionsIndex("w1"), redis.Z{Score: 1528632000, Member: "wallet:w1:actual:1528632000:t1"})
+	client.Set("wallet:w1:message:42", "wallet:w1:actual:1528632000:t1", 0)
+	client.SAdd(keyWalletOwners("w1"), 1)
+	client.HMSet("wallet:w2:actual:1528632000:t2", map[string]interface{}{"id": "t2", "value": -300, "raw": "300", "msgId": 42})
+	client.ZAdd(keyActualTransactionsIndex("w2"), redis.Z{Score: 1528632000, Member: "wallet:w2:actual:1528632000:t2"})
+	client.Set("wallet:w2:message:42", "wallet:w2:actual:1528632000:t2", 0)
+	client.SAdd(keyWalletOwners("w2"), 1, 2)
+
+	if err := s.migrateMessageChats(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	if tx, err := s.GetActualTransactionByMessage("w1", 1, 42); err != nil || tx.ID != "t1" || tx.ChatID != 1 {
+		t.Errorf("Unexpected transaction of single owner wallet after migration: %+v", tx)
+	}
+	for _, chatId := range []int64{1, 2} {
+		if tx, err := s.GetActualTransactionByMessage("w2", chatId, 42); err == nil {
+			t.Errorf("Transaction of shared wallet has been assigned to chat %d: %+v", chatId, tx)
+		}
+	}
+	if n, err := client.Exists("wallet:w1:message:42", "wallet:w2:message:42").Result(); err != nil || n != 0 {
+		t.Errorf("Old lookups have not been removed")
+	}
+}
+
+func TestRedisStorage_InvalidRegularTransaction(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client)
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	regular := NewRegularTransaction(100, 5, "salary")
+	if err := s.AddRegularTransaction(w.ID, *regular); err != nil {
+		t.FailNow()
+	}
+
+	// broken records must be reported instead of crashing the bot
+	client.HSet(keyRegularTransaction(w.ID, "in", 5, regular.ID), "recurrence", "FREQ=MONTHLY;INTERVAL=2")
+	if txs, err := s.GetRegularTransactions(w.ID); err == nil {
+		t.Errorf("Regular transaction with invalid rule has been loaded: %+v", txs)
+	}
+}
+
+func TestRedisStorage_ActualTransactionIdsMigration(t *testing.T) {
+	client, release := newTestRedisClient(t)
+	defer release()
+	s := NewRedisStorage(client).(*RedisStorage)
+
+	// transactions added before lookup by IDs are listed only in indexes
+	client.HMSet("wallet:w1:out:1528632000:t1", map[string]interface{}{"id": "t1", "value": -500, "raw": "500"})
+	client.ZAdd(keyActualTransactionsIndex("w1"), redis.Z{Score: 1528632000, Member: "wallet:w1:out:1528632000:t1"})
+
+	if err := s.migrateActualTransactionIds(); err != nil {
+		t.Fatalf("Migration failed: %s", err)
+	}
+	if tx, err := s.GetActualTransaction("w1", "t1"); err != nil || tx.Value != -500 {
+		t.Errorf("Unexpected transaction after migration: %+v", tx)
+	}
+	if err := s.RemoveActualTransaction("w1", "t1"); err != nil {
+		t.Errorf("Migrated transaction has not been removed: %s", err)
+	}
+	if n, err := client.HLen(keyActualTransactionIds("w1")).Result(); err != nil || n != 0 {
+		t.Errorf("Removed transaction is left in lookup by IDs")
+	}
+}
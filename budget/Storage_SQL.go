@@ -0,0 +1,120 @@
+package budget
+
+import "log"
+import "time"
+import "database/sql"
+
+// sqlRowScanner is implemented by both *sql.Row and *sql.Rows
+type sqlRowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlMigrate brings schema up to date by applying not yet applied migrations, each in its own transaction.
+// Applied migrations are tracked in schema_version table; setVersionQuery must update it with a single parameter
+func sqlMigrate(db *sql.DB, migrations []string, setVersionQuery string) error {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+	var version int
+	err := db.QueryRow("SELECT version FROM schema_version").Scan(&version)
+	if err == sql.ErrNoRows {
+		if _, err = db.Exec("INSERT INTO schema_version (version) VALUES (0)"); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		log.Printf("Applying schema migration %d", version+1)
+		err := sqlInTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[version]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(setVersionQuery, version+1)
+			return err
+		})
+		if err != nil {
+			log.Printf("Schema migration %d failed with error: %s", version+1, err)
+			return err
+		}
+	}
+	log.Printf("Schema is at version %d", version)
+	return nil
+}
+
+// sqlRescale converts amounts of wallet w from one number of decimals to another within tx. selectQuery should return
+// keys and amounts of rows of the wallet, updateQuery gets new amount, wallet and key of a row as parameters
+func sqlRescale(tx *sql.Tx, w WalletId, from, to int, selectQuery, updateQuery string) error {
+	rows, err := tx.Query(selectQuery, string(w))
+	if err != nil {
+		return err
+	}
+	amounts := make(map[string]int, 0)
+	for rows.Next() {
+		var key string
+		var amount int
+		if err := rows.Scan(&key, &amount); err != nil {
+			rows.Close()
+			return err
+		}
+		amounts[key] = amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for key, amount := range amounts {
+		if _, err := tx.Exec(updateQuery, rescaleAmount(amount, from, to), string(w), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlInTx runs f in a transaction which is committed only if f succeeds
+func sqlInTx(db *sql.DB, f func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqlDate keeps a date without time zone, e.g. '2026-10-02'; zero time is kept as an empty string
+func sqlDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// parseSQLDate is the opposite of sqlDate
+func parseSQLDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// scanGoal reads a goal selected as uid, name, target, percent, created and deadline
+func scanGoal(row sqlRowScanner) (*Goal, error) {
+	var id, name, created, deadline string
+	g := &Goal{}
+	if err := row.Scan(&id, &name, &g.Target, &g.Percent, &created, &deadline); err != nil {
+		return nil, err
+	}
+	g.ID, g.Name = GoalId(id), name
+	var err error
+	if g.Created, err = parseSQLDate(created); err != nil {
+		return nil, err
+	}
+	if g.Deadline, err = parseSQLDate(deadline); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
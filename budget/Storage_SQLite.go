@@ -0,0 +1,975 @@
+package budget
+
+import "log"
+import "fmt"
+import "time"
+import "errors"
+import "database/sql"
+import "encoding/json"
+import "github.com/satori/go.uuid"
+import _ "github.com/mattn/go-sqlite3"
+
+// sqliteMigrations contains schema changes; each element is applied exactly once, in order.
+// Never modify already released migrations - add new ones instead
+var sqliteMigrations = []string{
+	`CREATE TABLE wallets (
+		id          TEXT PRIMARY KEY,
+		created     INTEGER NOT NULL,
+		month_start INTEGER NOT NULL DEFAULT 1
+	);
+	CREATE TABLE owners (
+		id               INTEGER PRIMARY KEY,
+		wallet_id        TEXT REFERENCES wallets(id),
+		daily_notif_time INTEGER
+	);
+	CREATE TABLE actual_transactions (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		value     INTEGER NOT NULL,
+		time      INTEGER NOT NULL,
+		label     TEXT NOT NULL DEFAULT '',
+		raw       TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX actual_transactions_wallet_time ON actual_transactions(wallet_id, time);
+	CREATE TABLE regular_transactions (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		value     INTEGER NOT NULL,
+		date      INTEGER NOT NULL,
+		label     TEXT NOT NULL
+	);
+	CREATE INDEX regular_transactions_wallet ON regular_transactions(wallet_id);`,
+
+	`ALTER TABLE actual_transactions ADD COLUMN uid TEXT;
+	UPDATE actual_transactions SET uid = lower(hex(randomblob(16)));
+	CREATE UNIQUE INDEX actual_transactions_uid ON actual_transactions(uid);
+	ALTER TABLE regular_transactions ADD COLUMN uid TEXT;
+	UPDATE regular_transactions SET uid = lower(hex(randomblob(16)));
+	CREATE UNIQUE INDEX regular_transactions_uid ON regular_transactions(uid);`,
+
+	`ALTER TABLE actual_transactions ADD COLUMN message_id INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX actual_transactions_message ON actual_transactions(wallet_id, message_id);`,
+
+	`CREATE TABLE operations (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+		data      TEXT NOT NULL
+	);
+	CREATE INDEX operations_wallet ON operations(wallet_id, id);`,
+
+	`ALTER TABLE wallets ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE actual_transactions ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE regular_transactions ADD COLUMN currency TEXT NOT NULL DEFAULT '';`,
+
+	`UPDATE actual_transactions SET value = value * 100;
+	UPDATE regular_transactions SET value = value * 100;
+	DELETE FROM operations;
+	ALTER TABLE wallets ADD COLUMN decimals INTEGER NOT NULL DEFAULT 2;`,
+
+	`CREATE TABLE wallet_invites (
+		code      TEXT PRIMARY KEY,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		expires   INTEGER NOT NULL
+	);
+	ALTER TABLE actual_transactions ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;`,
+
+	`ALTER TABLE owners ADD COLUMN admins_only INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE users (
+		id   INTEGER PRIMARY KEY,
+		name TEXT NOT NULL
+	);`,
+
+	`CREATE TABLE owner_wallets (
+		owner_id  INTEGER NOT NULL,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		name      TEXT NOT NULL,
+		PRIMARY KEY (owner_id, wallet_id),
+		UNIQUE (owner_id, name)
+	);
+	INSERT INTO owner_wallets (owner_id, wallet_id, name) SELECT id, wallet_id, 'main' FROM owners WHERE wallet_id IS NOT NULL;`,
+
+	`ALTER TABLE owners ADD COLUMN timezone TEXT;`,
+
+	`ALTER TABLE regular_transactions ADD COLUMN shift TEXT NOT NULL DEFAULT '';`,
+
+	`ALTER TABLE regular_transactions ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';`,
+
+	`CREATE TABLE goals (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		uid       TEXT NOT NULL UNIQUE,
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		name      TEXT NOT NULL,
+		target    INTEGER NOT NULL DEFAULT 0,
+		percent   INTEGER NOT NULL DEFAULT 0,
+		created   TEXT NOT NULL,
+		deadline  TEXT NOT NULL DEFAULT '',
+		UNIQUE (wallet_id, name)
+	);`,
+
+	`CREATE TABLE wallet_limits (
+		wallet_id TEXT NOT NULL REFERENCES wallets(id),
+		label     TEXT NOT NULL,
+		amount    INTEGER NOT NULL,
+		PRIMARY KEY (wallet_id, label)
+	);`,
+
+	`ALTER TABLE wallets ADD COLUMN carry_over TEXT NOT NULL DEFAULT '';`,
+
+	// chat of earlier messages is known only for wallets having a single owner
+	`ALTER TABLE actual_transactions ADD COLUMN chat_id INTEGER NOT NULL DEFAULT 0;
+	UPDATE actual_transactions SET chat_id = (SELECT owner_id FROM owner_wallets WHERE owner_wallets.wallet_id = actual_transactions.wallet_id)
+		WHERE message_id <> 0 AND (SELECT COUNT(*) FROM owner_wallets WHERE owner_wallets.wallet_id = actual_transactions.wallet_id) = 1;
+	DROP INDEX actual_transactions_message;
+	CREATE INDEX actual_transactions_message ON actual_transactions(wallet_id, chat_id, message_id);`,
+}
+
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage opens (or creates) SQLite database at path and brings its schema up to date
+func NewSQLiteStorage(path string) (Storage, error) {
+	log.Printf("Opening SQLite storage at '%s'", path)
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000", path))
+	if err != nil {
+		log.Printf("Could not open SQLite database '%s' due to error: %s", path, err)
+		return nil, err
+	}
+	// SQLite doesn't allow concurrent writers, so all handlers share a single connection
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStorage{db: db}
+	if err := s.migrate(); err != nil {
+		log.Printf("Could not migrate SQLite database '%s' due to error: %s", path, err)
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStorage) migrate() error {
+	return sqlMigrate(s.db, sqliteMigrations, "UPDATE schema_version SET version = ?")
+}
+
+func (s *SQLiteStorage) GetWalletForOwner(ownerId OwnerId, createIfAbsent bool) (*Wallet, error) {
+	log.Printf("Getting wallet for owner %d", ownerId)
+	wallet, err := s.scanWallet(s.db.QueryRow(`SELECT w.id, w.month_start, w.currency, w.decimals, ow.name
+		FROM owners o JOIN wallets w ON w.id = o.wallet_id JOIN owner_wallets ow ON ow.owner_id = o.id AND ow.wallet_id = w.id
+		WHERE o.id = ?`, ownerId))
+	if err == sql.ErrNoRows {
+		log.Printf("No wallet found for owner %d", ownerId)
+		if !createIfAbsent {
+			return nil, errors.New("No wallet for owner")
+		}
+		return s.CreateWalletOwner(ownerId)
+	}
+	if err != nil {
+		log.Printf("Could not get wallet for owner %d due to error: %s", ownerId, err)
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (s *SQLiteStorage) scanWallet(row sqlRowScanner) (*Wallet, error) {
+	var walletId, currency, name string
+	var monthStart, decimals int
+	if err := row.Scan(&walletId, &monthStart, &currency, &decimals, &name); err != nil {
+		return nil, err
+	}
+	wallet := NewWalletFromStorage(walletId, monthStart, s)
+	wallet.Name = name
+	wallet.Currency = Currency(currency)
+	wallet.Decimals = decimals
+	return wallet, nil
+}
+
+func (s *SQLiteStorage) CreateWalletOwner(ownerId OwnerId) (*Wallet, error) {
+	log.Printf("Starting creation of owner %d", ownerId)
+	var wallet *Wallet
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var walletId sql.NullString
+		err := tx.QueryRow("SELECT wallet_id FROM owners WHERE id = ?", ownerId).Scan(&walletId)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if walletId.Valid {
+			log.Printf("Owner %d has been already created", ownerId)
+			return errors.New("Owner exists")
+		}
+
+		wallet, err = s.createWallet(tx, ownerId, defaultWalletName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO owners (id, wallet_id) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET wallet_id = excluded.wallet_id", ownerId, string(wallet.ID))
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not create wallet for owner %d with error: %s", ownerId, err)
+		return nil, err
+	}
+	log.Printf("Wallet %s has been created for owner %d", wallet.ID, ownerId)
+	return wallet, nil
+}
+
+// createWallet creates a new wallet and adds it to the owner's wallets under the name
+func (s *SQLiteStorage) createWallet(tx *sql.Tx, ownerId OwnerId, name string) (*Wallet, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		log.Printf("Could get new wallet UUID due to error: %s", err)
+		return nil, err
+	}
+	if _, err := tx.Exec("INSERT INTO wallets (id, created, month_start) VALUES (?, ?, ?)", id.String(), time.Now().Unix(), defaultMonthStart); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("INSERT INTO owner_wallets (owner_id, wallet_id, name) VALUES (?, ?, ?)", ownerId, id.String(), name); err != nil {
+		return nil, err
+	}
+	wallet := NewWalletFromStorage(id.String(), defaultMonthStart, s)
+	wallet.Name = name
+	return wallet, nil
+}
+
+func (s *SQLiteStorage) GetOwnerWallets(ownerId OwnerId) ([]*Wallet, error) {
+	rows, err := s.db.Query(`SELECT w.id, w.month_start, w.currency, w.decimals, ow.name
+		FROM owner_wallets ow JOIN wallets w ON w.id = ow.wallet_id WHERE ow.owner_id = ? ORDER BY ow.name`, ownerId)
+	if err != nil {
+		log.Printf("Could not get wallets of owner %d due to error: %s", ownerId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]*Wallet, 0, 1)
+	for rows.Next() {
+		wallet, err := s.scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+func (s *SQLiteStorage) AddOwnerWallet(ownerId OwnerId, name string) (*Wallet, error) {
+	var wallet *Wallet
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		wallet, err = s.createWallet(tx, ownerId, name)
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not create wallet '%s' for owner %d with error: %s", name, ownerId, err)
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (s *SQLiteStorage) SetActiveWallet(ownerId OwnerId, w WalletId) error {
+	return sqlInTx(s.db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM owner_wallets WHERE owner_id = ? AND wallet_id = ?)", ownerId, string(w)).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			log.Printf("Wallet '%s' doesn't belong to owner %d", w, ownerId)
+			return errors.New("No such wallet for owner")
+		}
+		_, err := tx.Exec("INSERT INTO owners (id, wallet_id) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET wallet_id = excluded.wallet_id", ownerId, string(w))
+		return err
+	})
+}
+
+func (s *SQLiteStorage) GetAllOwners() (map[OwnerId]OwnerData, error) {
+	rows, err := s.db.Query("SELECT id, wallet_id, timezone, daily_notif_time FROM owners")
+	if err != nil {
+		log.Printf("Could not get owners due to error: %s", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	resultMap := make(map[OwnerId]OwnerData, 0)
+	for rows.Next() {
+		var id int64
+		var walletId, tz sql.NullString
+		var notifTime sql.NullInt64
+		if err := rows.Scan(&id, &walletId, &tz, &notifTime); err != nil {
+			log.Printf("Could not parse owner row due to error: %s", err)
+			return nil, err
+		}
+		ownerData := OwnerData{RegularTxs: make(map[int][]RegularTransaction, 0)}
+		if walletId.Valid {
+			wId := walletId.String
+			ownerData.WalletId = &wId
+		}
+		if tz.Valid {
+			ownerData.Timezone = &tz.String
+		}
+		if notifTime.Valid {
+			dur := time.Duration(notifTime.Int64)
+			ownerData.DailyReminderTime = &dur
+		}
+		resultMap[OwnerId(id)] = ownerData
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for id, ownerData := range resultMap {
+		if ownerData.WalletId == nil {
+			continue
+		}
+		regularTxs, err := s.GetRegularTransactions(WalletId(*ownerData.WalletId))
+		if err != nil {
+			log.Printf("Could not get regular transactions for owner %d wallet '%s' due to error: %s", id, *ownerData.WalletId, err)
+			// let's move forward to complete at least what we have
+		}
+		for _, tx := range regularTxs {
+			ownerData.RegularTxs[tx.Date] = append(ownerData.RegularTxs[tx.Date], tx)
+		}
+	}
+	return resultMap, nil
+}
+
+func (s *SQLiteStorage) SetWalletCurrency(w WalletId, c Currency) error {
+	res, err := s.db.Exec("UPDATE wallets SET currency = ? WHERE id = ?", string(c), string(w))
+	if err != nil {
+		log.Printf("Could not set currency of wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("Wallet '%s' has not been found for setting currency", w)
+		return errors.New("No such wallet")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) RescaleWallet(w WalletId, from, to int) error {
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var text string
+		err := tx.QueryRow("SELECT carry_over FROM wallets WHERE id = ?", string(w)).Scan(&text)
+		if err == sql.ErrNoRows {
+			log.Printf("Wallet '%s' has not been found for setting decimals", w)
+			return errors.New("No such wallet")
+		}
+		if err != nil {
+			return err
+		}
+		carryOver, err := ParseCarryOver(text)
+		if err != nil {
+			return err
+		}
+		rescales := []struct{ selectQuery, updateQuery string }{
+			{"SELECT uid, value FROM actual_transactions WHERE wallet_id = ?", "UPDATE actual_transactions SET value = ? WHERE wallet_id = ? AND uid = ?"},
+			{"SELECT uid, value FROM regular_transactions WHERE wallet_id = ?", "UPDATE regular_transactions SET value = ? WHERE wallet_id = ? AND uid = ?"},
+			{"SELECT uid, target FROM goals WHERE wallet_id = ?", "UPDATE goals SET target = ? WHERE wallet_id = ? AND uid = ?"},
+			{"SELECT label, amount FROM wallet_limits WHERE wallet_id = ?", "UPDATE wallet_limits SET amount = ? WHERE wallet_id = ? AND label = ?"},
+		}
+		for _, r := range rescales {
+			if err := sqlRescale(tx, w, from, to, r.selectQuery, r.updateQuery); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM operations WHERE wallet_id = ?", string(w)); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE wallets SET decimals = ?, carry_over = ? WHERE id = ?", to, carryOver.rescaled(from, to).String(), string(w))
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not rescale wallet '%s' from %d to %d decimals due to error: %s", w, from, to, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) SetWalletCarryOver(w WalletId, c CarryOver) error {
+	res, err := s.db.Exec("UPDATE wallets SET carry_over = ? WHERE id = ?", c.String(), string(w))
+	if err != nil {
+		log.Printf("Could not set carry-over policy of wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("Wallet '%s' has not been found for setting carry-over policy", w)
+		return errors.New("No such wallet")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetWalletCarryOver(w WalletId) (CarryOver, error) {
+	var text string
+	err := s.db.QueryRow("SELECT carry_over FROM wallets WHERE id = ?", string(w)).Scan(&text)
+	if err == sql.ErrNoRows {
+		return CarryOver{}, nil
+	}
+	if err != nil {
+		log.Printf("Could not get carry-over policy of wallet '%s' due to error: %s", w, err)
+		return CarryOver{}, err
+	}
+	return ParseCarryOver(text)
+}
+
+func (s *SQLiteStorage) SetWalletLimit(w WalletId, label string, limit int) error {
+	var err error
+	if limit == 0 {
+		_, err = s.db.Exec("DELETE FROM wallet_limits WHERE wallet_id = ? AND label = ?", string(w), label)
+	} else {
+		_, err = s.db.Exec("INSERT INTO wallet_limits (wallet_id, label, amount) VALUES (?, ?, ?) ON CONFLICT(wallet_id, label) DO UPDATE SET amount = excluded.amount",
+			string(w), label, limit)
+	}
+	if err != nil {
+		log.Printf("Could not set limit of label '%s' of wallet '%s' due to error: %s", label, w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetWalletLimits(w WalletId) (map[string]int, error) {
+	rows, err := s.db.Query("SELECT label, amount FROM wallet_limits WHERE wallet_id = ?", string(w))
+	if err != nil {
+		log.Printf("Could not get limits of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	for rows.Next() {
+		var label string
+		var amount int
+		if err := rows.Scan(&label, &amount); err != nil {
+			log.Printf("Could not parse limit of wallet '%s' due to error: %s", w, err)
+			return nil, err
+		}
+		result[label] = amount
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStorage) GetOwnerDailyNotificationTime(id OwnerId) (*time.Duration, error) {
+	var notifTime sql.NullInt64
+	err := s.db.QueryRow("SELECT daily_notif_time FROM owners WHERE id = ?", id).Scan(&notifTime)
+	if err == sql.ErrNoRows || (err == nil && !notifTime.Valid) {
+		log.Printf("Assuming that daily notification is not set (disabled) for owner %d", id)
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Could not get notification time for owner %d due to error: %s", id, err)
+		return nil, err
+	}
+	dur := time.Duration(notifTime.Int64)
+	return &dur, nil
+}
+
+func (s *SQLiteStorage) SetOwnerDailyNotificationTime(id OwnerId, notifTime *time.Duration) error {
+	var value sql.NullInt64
+	if notifTime != nil {
+		log.Printf("Setting daily notification time for owner %d to '%s'", id, notifTime)
+		value = sql.NullInt64{Int64: int64(*notifTime), Valid: true}
+	} else {
+		log.Printf("Removing notification time for owner %d", id)
+	}
+	_, err := s.db.Exec("INSERT INTO owners (id, daily_notif_time) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET daily_notif_time = excluded.daily_notif_time", id, value)
+	return err
+}
+
+func (s *SQLiteStorage) GetOwnerTimezone(id OwnerId) (string, error) {
+	var tz sql.NullString
+	err := s.db.QueryRow("SELECT timezone FROM owners WHERE id = ?", id).Scan(&tz)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Could not get time zone for owner %d due to error: %s", id, err)
+		return "", err
+	}
+	return tz.String, nil
+}
+
+func (s *SQLiteStorage) SetOwnerTimezone(id OwnerId, tz string) error {
+	log.Printf("Setting time zone for owner %d to '%s'", id, tz)
+	_, err := s.db.Exec("INSERT INTO owners (id, timezone) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET timezone = excluded.timezone", id, tz)
+	return err
+}
+
+func (s *SQLiteStorage) GetOwnerAdminsOnly(id OwnerId) (bool, error) {
+	var adminsOnly bool
+	err := s.db.QueryRow("SELECT admins_only FROM owners WHERE id = ?", id).Scan(&adminsOnly)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Could not get admins only setting for owner %d due to error: %s", id, err)
+		return false, err
+	}
+	return adminsOnly, nil
+}
+
+func (s *SQLiteStorage) SetOwnerAdminsOnly(id OwnerId, adminsOnly bool) error {
+	log.Printf("Setting admins only mode for owner %d to %t", id, adminsOnly)
+	_, err := s.db.Exec("INSERT INTO owners (id, admins_only) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET admins_only = excluded.admins_only", id, adminsOnly)
+	return err
+}
+
+func (s *SQLiteStorage) SetUserName(userId int, name string) error {
+	_, err := s.db.Exec("INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET name = excluded.name", userId, name)
+	if err != nil {
+		log.Printf("Could not set name of user %d due to error: %s", userId, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetUserName(userId int) (string, error) {
+	var name string
+	err := s.db.QueryRow("SELECT name FROM users WHERE id = ?", userId).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Could not get name of user %d due to error: %s", userId, err)
+		return "", err
+	}
+	return name, nil
+}
+
+func (s *SQLiteStorage) SetWalletInfo(w WalletId, monthStart int) error {
+	res, err := s.db.Exec("UPDATE wallets SET month_start = ? WHERE id = ?", monthStart, string(w))
+	if err != nil {
+		log.Printf("Could not set month start for wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	if count, err := res.RowsAffected(); err == nil && count != 1 {
+		log.Printf("Wallet '%s' has not been found for setting month start", w)
+		return errors.New("No such wallet")
+	}
+	return nil
+}
+
+const sqliteActualTransactionColumns = "uid, value, currency, time, label, raw, message_id, chat_id, user_id"
+
+func scanSQLiteActualTransaction(row sqlRowScanner) (*ActualTransaction, error) {
+	var value, messageId, userId int
+	var tUnix, chatId int64
+	var id, currency, label, raw string
+	if err := row.Scan(&id, &value, &currency, &tUnix, &label, &raw, &messageId, &chatId, &userId); err != nil {
+		return nil, err
+	}
+	tx := NewActualTransaction(value, time.Unix(tUnix, 0), label, raw)
+	tx.ID = TransactionId(id)
+	tx.MessageID = messageId
+	tx.ChatID = chatId
+	tx.UserID = userId
+	tx.Currency = Currency(currency)
+	return tx, nil
+}
+
+func (s *SQLiteStorage) AddActualTransaction(w WalletId, val ActualTransaction) error {
+	if val.ID == "" {
+		val.ID = newTransactionId()
+	}
+	_, err := s.db.Exec("INSERT INTO actual_transactions (uid, wallet_id, value, currency, time, label, raw, message_id, chat_id, user_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		string(val.ID), string(w), val.Value, string(val.Currency), val.Time.Unix(), val.Label, val.RawText, val.MessageID, val.ChatID, val.UserID)
+	if err != nil {
+		log.Printf("Could not add actual transaction to wallet '%s' due to error: %s", w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetActualTransactions(w WalletId, t1, t2 time.Time) ([]ActualTransaction, error) {
+	if t2.Before(t1) {
+		panic("Time borders misaligned")
+	}
+
+	rows, err := s.db.Query("SELECT "+sqliteActualTransactionColumns+" FROM actual_transactions WHERE wallet_id = ? AND time >= ? AND time <= ? ORDER BY time",
+		string(w), unixCeil(t1), t2.Unix())
+	if err != nil {
+		log.Printf("Could not get actual transactions for wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]ActualTransaction, 0, 10)
+	for rows.Next() {
+		tx, err := scanSQLiteActualTransaction(rows)
+		if err != nil {
+			log.Printf("Could not parse actual transaction of wallet '%s' due to error: %s", w, err)
+			return nil, err
+		}
+		result = append(result, *tx)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStorage) GetActualTransaction(w WalletId, id TransactionId) (*ActualTransaction, error) {
+	return s.getActualTransaction(w, "uid = ?", string(id))
+}
+
+func (s *SQLiteStorage) GetActualTransactionByMessage(w WalletId, chatId int64, messageId int) (*ActualTransaction, error) {
+	return s.getActualTransaction(w, "chat_id = ? AND message_id = ?", chatId, messageId)
+}
+
+// getActualTransaction returns a single transaction of the wallet having column equal to value
+func (s *SQLiteStorage) getActualTransaction(w WalletId, condition string, args ...interface{}) (*ActualTransaction, error) {
+	row := s.db.QueryRow("SELECT "+sqliteActualTransactionColumns+" FROM actual_transactions WHERE wallet_id = ? AND "+condition,
+		append([]interface{}{string(w)}, args...)...)
+	tx, err := scanSQLiteActualTransaction(row)
+	if err == sql.ErrNoRows {
+		log.Printf("No actual transaction with '%s' %v found for wallet '%s'", condition, args, w)
+		return nil, errors.New("Specified transaction has not been found in DB")
+	}
+	if err != nil {
+		log.Printf("Could not get actual transaction with '%s' %v of wallet '%s' due to error: %s", condition, args, w, err)
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *SQLiteStorage) UpdateActualTransaction(w WalletId, t ActualTransaction) error {
+	res, err := s.db.Exec("UPDATE actual_transactions SET value = ?, currency = ?, time = ?, label = ?, raw = ?, message_id = ?, chat_id = ?, user_id = ? WHERE wallet_id = ? AND uid = ?",
+		t.Value, string(t.Currency), t.Time.Unix(), t.Label, t.RawText, t.MessageID, t.ChatID, t.UserID, string(w), string(t.ID))
+	if err != nil {
+		log.Printf("Could not update actual transaction '%s' of wallet '%s' due to error: %s", t.ID, w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("No actual transaction '%s' found for wallet '%s' for update", t.ID, w)
+		return errors.New("Specified transaction has not been found in DB")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) RemoveActualTransaction(w WalletId, id TransactionId) error {
+	res, err := s.db.Exec("DELETE FROM actual_transactions WHERE wallet_id = ? AND uid = ?", string(w), string(id))
+	if err != nil {
+		log.Printf("Could not remove actual transaction '%s' from wallet '%s' due to error: %s", id, w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("No actual transaction '%s' found for wallet '%s' for removal", id, w)
+		return errors.New("Specified transaction has not been found in DB")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) AddRegularTransaction(w WalletId, t RegularTransaction) error {
+	log.Printf("Adding regular monthly income/outcome with value '%d' to wallet '%s'", t.Value, w)
+	if t.ID == "" {
+		t.ID = newTransactionId()
+	}
+	_, err := s.db.Exec("INSERT INTO regular_transactions (uid, wallet_id, value, currency, date, shift, recurrence, label) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		string(t.ID), string(w), t.Value, string(t.Currency), t.Date, string(t.Shift), t.Recurrence.String(), t.Label)
+	if err != nil {
+		log.Printf("Could not add regular transaction to wallet '%s' due to error: %s", w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetRegularTransactions(w WalletId) ([]RegularTransaction, error) {
+	log.Printf("Getting regular wallet transactions for wallet '%s'", w)
+	rows, err := s.db.Query("SELECT uid, value, currency, date, shift, recurrence, label FROM regular_transactions WHERE wallet_id = ? ORDER BY id", string(w))
+	if err != nil {
+		log.Printf("Could not get regular transactions for wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]RegularTransaction, 0, 10)
+	for rows.Next() {
+		var value, date int
+		var id, currency, shift, recurrence, label string
+		if err := rows.Scan(&id, &value, &currency, &date, &shift, &recurrence, &label); err != nil {
+			log.Printf("Could not parse regular transaction of wallet '%s' due to error: %s", w, err)
+			return nil, err
+		}
+		rule, err := ParseRecurrence(recurrence)
+		if err != nil {
+			log.Printf("Could not parse recurrence rule '%s' of wallet '%s' due to error: %s", recurrence, w, err)
+			return nil, err
+		}
+		tx := RegularTransaction{
+			ID:         TransactionId(id),
+			Value:      value,
+			Date:       date,
+			Currency:   Currency(currency),
+			Shift:      BusinessDayShift(shift),
+			Recurrence: rule,
+			Label:      label}
+		if err := tx.validate(); err != nil {
+			log.Printf("Invalid regular transaction '%s' of wallet '%s': %s", id, w, err)
+			return nil, err
+		}
+		result = append(result, tx)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStorage) RemoveRegularTransaction(w WalletId, t RegularTransaction) error {
+	res, err := s.db.Exec("DELETE FROM regular_transactions WHERE wallet_id = ? AND uid = ?", string(w), string(t.ID))
+	if err != nil {
+		log.Printf("Could not remove regular transaction from wallet '%s' due to error: %s", w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("No transaction found for wallet '%s' for transaction removal", w)
+		return errors.New("Specified transaction has not been found in DB")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) AddGoal(w WalletId, g Goal) error {
+	if g.ID == "" {
+		g.ID = newGoalId()
+	}
+	_, err := s.db.Exec("INSERT INTO goals (uid, wallet_id, name, target, percent, created, deadline) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		string(g.ID), string(w), g.Name, g.Target, g.Percent, sqlDate(g.Created), sqlDate(g.Deadline))
+	if err != nil {
+		log.Printf("Could not add goal '%s' to wallet '%s' due to error: %s", g.Name, w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetGoals(w WalletId) ([]Goal, error) {
+	rows, err := s.db.Query("SELECT uid, name, target, percent, created, deadline FROM goals WHERE wallet_id = ? ORDER BY name", string(w))
+	if err != nil {
+		log.Printf("Could not get goals of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Goal, 0)
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			log.Printf("Could not parse goal of wallet '%s' due to error: %s", w, err)
+			return nil, err
+		}
+		result = append(result, *g)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStorage) RemoveGoal(w WalletId, id GoalId) error {
+	res, err := s.db.Exec("DELETE FROM goals WHERE wallet_id = ? AND uid = ?", string(w), string(id))
+	if err != nil {
+		log.Printf("Could not remove goal '%s' of wallet '%s' due to error: %s", id, w, err)
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		log.Printf("No goal '%s' found for wallet '%s' for removal", id, w)
+		return errors.New("Specified goal has not been found in DB")
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) PushOperation(w WalletId, op Operation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		log.Printf("Could not serialize operation %+v due to error: %s", op, err)
+		return err
+	}
+	err = sqlInTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO operations (wallet_id, data) VALUES (?, ?)", string(w), string(data)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM operations WHERE wallet_id = ? AND id NOT IN (
+			SELECT id FROM operations WHERE wallet_id = ? ORDER BY id DESC LIMIT ?)`, string(w), string(w), journalLimit)
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not add operation to journal of wallet '%s' due to error: %s", w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) PopOperation(w WalletId) (*Operation, error) {
+	var op *Operation
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var id int64
+		var data string
+		err := tx.QueryRow("SELECT id, data FROM operations WHERE wallet_id = ? ORDER BY id DESC LIMIT 1", string(w)).Scan(&id, &data)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		op = &Operation{}
+		if err := json.Unmarshal([]byte(data), op); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM operations WHERE id = ?", id)
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not get operation from journal of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *SQLiteStorage) LastOperation(w WalletId) (*Operation, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM operations WHERE wallet_id = ? ORDER BY id DESC LIMIT 1", string(w)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("Could not get latest operation from journal of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	op := &Operation{}
+	if err := json.Unmarshal([]byte(data), op); err != nil {
+		log.Printf("Could not parse operation '%s' from journal of wallet '%s' due to error: %s", data, w, err)
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *SQLiteStorage) DropOperation(w WalletId, op Operation) error {
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var id int64
+		var data string
+		err := tx.QueryRow("SELECT id, data FROM operations WHERE wallet_id = ? ORDER BY id DESC LIMIT 1", string(w)).Scan(&id, &data)
+		if err == sql.ErrNoRows {
+			return errors.New("Operation is not the latest one in journal")
+		}
+		if err != nil {
+			return err
+		}
+		latest := Operation{}
+		if err := json.Unmarshal([]byte(data), &latest); err != nil {
+			return err
+		}
+		if !latest.sameAs(op) {
+			return errors.New("Operation is not the latest one in journal")
+		}
+		_, err = tx.Exec("DELETE FROM operations WHERE id = ?", id)
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not drop operation '%s' made at %s from journal of wallet '%s' due to error: %s", op.Type, op.Time, w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetWalletOwners(w WalletId) ([]OwnerId, error) {
+	rows, err := s.db.Query("SELECT owner_id FROM owner_wallets WHERE wallet_id = ? ORDER BY owner_id", string(w))
+	if err != nil {
+		log.Printf("Could not get owners of wallet '%s' due to error: %s", w, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := make([]OwnerId, 0, 2)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owners = append(owners, OwnerId(id))
+	}
+	return owners, rows.Err()
+}
+
+func (s *SQLiteStorage) AttachOwnerToWallet(ownerId OwnerId, w WalletId, name string) error {
+	return sqlInTx(s.db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM wallets WHERE id = ?)", string(w)).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			log.Printf("Wallet '%s' has not been found for attaching owner %d", w, ownerId)
+			return errors.New("No such wallet")
+		}
+		if _, err := tx.Exec("INSERT INTO owner_wallets (owner_id, wallet_id, name) VALUES (?, ?, ?)", ownerId, string(w), name); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO owners (id, wallet_id) VALUES (?, ?) ON CONFLICT(id) DO UPDATE SET wallet_id = excluded.wallet_id", ownerId, string(w))
+		return err
+	})
+}
+
+func (s *SQLiteStorage) DetachOwner(ownerId OwnerId, w WalletId) error {
+	return sqlInTx(s.db, func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM owner_wallets WHERE owner_id = ? AND wallet_id = ?", ownerId, string(w))
+		if err != nil {
+			log.Printf("Could not detach owner %d due to error: %s", ownerId, err)
+			return err
+		}
+		if count, err := res.RowsAffected(); err == nil && count != 1 {
+			log.Printf("Owner %d has no wallet '%s' to be detached from", ownerId, w)
+			return errors.New("No such wallet for owner")
+		}
+		_, err = tx.Exec("UPDATE owners SET wallet_id = NULL WHERE id = ? AND wallet_id = ?", ownerId, string(w))
+		return err
+	})
+}
+
+func (s *SQLiteStorage) AddWalletInvite(w WalletId, code string, expires time.Time) error {
+	_, err := s.db.Exec("INSERT INTO wallet_invites (code, wallet_id, expires) VALUES (?, ?, ?)", code, string(w), expires.Unix())
+	if err != nil {
+		log.Printf("Could not add invite to wallet '%s' due to error: %s", w, err)
+	}
+	return err
+}
+
+func (s *SQLiteStorage) GetWalletInvite(code string) (WalletId, time.Time, error) {
+	var walletId string
+	var expires int64
+	err := s.db.QueryRow("SELECT wallet_id, expires FROM wallet_invites WHERE code = ?", code).Scan(&walletId, &expires)
+	if err == sql.ErrNoRows || (err == nil && !time.Unix(expires, 0).After(time.Now())) {
+		log.Printf("Invite code '%s' is unknown or expired", code)
+		return "", time.Time{}, errors.New("Unknown invite code")
+	}
+	if err != nil {
+		log.Printf("Could not get invite code '%s' due to error: %s", code, err)
+		return "", time.Time{}, err
+	}
+	return WalletId(walletId), time.Unix(expires, 0), nil
+}
+
+func (s *SQLiteStorage) UseWalletInvite(code string) (WalletId, error) {
+	var walletId string
+	err := sqlInTx(s.db, func(tx *sql.Tx) error {
+		var expires int64
+		err := tx.QueryRow("SELECT wallet_id, expires FROM wallet_invites WHERE code = ?", code).Scan(&walletId, &expires)
+		if err == sql.ErrNoRows {
+			return errors.New("Unknown invite code")
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM wallet_invites WHERE code = ?", code); err != nil {
+			return err
+		}
+		if !time.Unix(expires, 0).After(time.Now()) {
+			return errors.New("Unknown invite code")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Could not use invite code '%s' due to error: %s", code, err)
+		return "", err
+	}
+	return WalletId(walletId), nil
+}
@@ -0,0 +1,74 @@
+package budget
+
+import "os"
+import "testing"
+import "io/ioutil"
+import "path/filepath"
+
+func newTestSQLiteStorage(t *testing.T) (Storage, func()) {
+	dir, err := ioutil.TempDir("", "budget-sqlite")
+	if err != nil {
+		t.Fatalf("Could not create temporary dir: %s", err)
+	}
+	s, err := NewSQLiteStorage(filepath.Join(dir, "budget.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Could not open SQLite storage: %s", err)
+	}
+	return s, func() {
+		s.(*SQLiteStorage).db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	testStorageConformance(t, newTestSQLiteStorage)
+}
+
+func TestSQLiteStorage_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "budget-sqlite")
+	if err != nil {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "budget.db")
+
+	s, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("Could not open SQLite storage: %s", err)
+	}
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	s.(*SQLiteStorage).db.Close()
+
+	// migrations must not be applied twice and data must survive
+	s, err = NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("Could not reopen SQLite storage: %s", err)
+	}
+	defer s.(*SQLiteStorage).db.Close()
+	w2, err := s.GetWalletForOwner(OwnerId(1), false)
+	if err != nil || w2.ID != w.ID {
+		t.Errorf("Wallet has been lost after reopening the database")
+	}
+}
+
+func TestSQLiteStorage_InvalidRegularTransaction(t *testing.T) {
+	s, release := newTestSQLiteStorage(t)
+	defer release()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	// broken rows must be reported instead of crashing the bot
+	_, err = s.(*SQLiteStorage).db.Exec("INSERT INTO regular_transactions (uid, wallet_id, value, currency, date, shift, recurrence, label) VALUES ('r1', ?, 100, '', 40, '', '', 'salary')", string(w.ID))
+	if err != nil {
+		t.Fatalf("Could not insert regular transaction: %s", err)
+	}
+	if txs, err := s.GetRegularTransactions(w.ID); err == nil {
+		t.Errorf("Regular transaction with invalid date has been loaded: %+v", txs)
+	}
+}
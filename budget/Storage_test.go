@@ -0,0 +1,1049 @@
+package budget
+
+import "testing"
+import "time"
+
+// storageFactory returns a new empty storage for each call together with a function releasing its resources
+type storageFactory func(t *testing.T) (Storage, func())
+
+// testStorageConformance checks behaviour which every Storage implementation must follow
+func testStorageConformance(t *testing.T, newStorage storageFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Storage)
+	}{
+		{"OwnerCreation", testStorageOwnerCreation},
+		{"OwnerWithoutWallet", testStorageOwnerWithoutWallet},
+		{"MonthStart", testStorageMonthStart},
+		{"NotificationTime", testStorageNotificationTime},
+		{"RegularTransactions", testStorageRegularTransactions},
+		{"Goals", testStorageGoals},
+		{"ActualTransactions", testStorageActualTransactions},
+		{"ActualTransactionsTimeWindow", testStorageActualTransactionsTimeWindow},
+		{"SameSecondTransactions", testStorageSameSecondTransactions},
+		{"ActualTransactionModification", testStorageActualTransactionModification},
+		{"ActualTransactionByMessage", testStorageActualTransactionByMessage},
+		{"ActualTransactionByMessageOfSharedWallet", testStorageActualTransactionByMessageOfSharedWallet},
+		{"OperationJournal", testStorageOperationJournal},
+		{"Currencies", testStorageCurrencies},
+		{"Decimals", testStorageDecimals},
+		{"Limits", testStorageLimits},
+		{"CarryOver", testStorageCarryOver},
+		{"WalletSharing", testStorageWalletSharing},
+		{"NamedWallets", testStorageNamedWallets},
+		{"AdminsOnly", testStorageAdminsOnly},
+		{"Timezone", testStorageTimezone},
+		{"UserNames", testStorageUserNames},
+		{"AllOwners", testStorageAllOwners},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, release := newStorage(t)
+			defer release()
+			tc.test(t, s)
+		})
+	}
+}
+
+func testStorageOwnerCreation(t *testing.T, s Storage) {
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); w != nil || err == nil {
+		t.Errorf("Wallet must not be created without createIfAbsent")
+	}
+
+	w1, err := s.GetWalletForOwner(OwnerId(1), true)
+	if err != nil || w1 == nil {
+		t.Fatalf("Wallet has not been created: %s", err)
+	}
+	if w1.MonthStart != defaultMonthStart {
+		t.Errorf("Unexpected month start %d", w1.MonthStart)
+	}
+
+	w2, err := s.GetWalletForOwner(OwnerId(1), false)
+	if err != nil || w2 == nil || w2.ID != w1.ID {
+		t.Fatalf("Owner got another wallet: %+v instead of '%s'", w2, w1.ID)
+	}
+	w2, err = s.GetWalletForOwner(OwnerId(1), true)
+	if err != nil || w2 == nil || w2.ID != w1.ID {
+		t.Fatalf("Owner got another wallet: %+v instead of '%s'", w2, w1.ID)
+	}
+
+	if _, err := s.CreateWalletOwner(OwnerId(1)); err == nil {
+		t.Errorf("Owner has been created twice")
+	}
+
+	w3, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil || w3 == nil || w3.ID == w1.ID {
+		t.Errorf("Different owners must have different wallets")
+	}
+}
+
+func testStorageOwnerWithoutWallet(t *testing.T, s Storage) {
+	notifTime := 8 * time.Hour
+	if err := s.SetOwnerDailyNotificationTime(OwnerId(1), &notifTime); err != nil {
+		t.FailNow()
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); w != nil || err == nil {
+		t.Errorf("Owner with settings only must not have a wallet")
+	}
+
+	w, err := s.GetWalletForOwner(OwnerId(1), true)
+	if err != nil || w == nil {
+		t.Fatalf("Wallet has not been created for owner with settings: %s", err)
+	}
+	if notif, err := s.GetOwnerDailyNotificationTime(OwnerId(1)); notif == nil || *notif != notifTime || err != nil {
+		t.Errorf("Owner settings have been lost during wallet creation")
+	}
+}
+
+func testStorageMonthStart(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := s.SetWalletInfo(w.ID, 15); err != nil {
+		t.FailNow()
+	}
+	w, err = s.GetWalletForOwner(OwnerId(1), false)
+	if err != nil || w.MonthStart != 15 {
+		t.Errorf("Month start has not been stored")
+	}
+}
+
+func testStorageNotificationTime(t *testing.T, s Storage) {
+	if _, err := s.CreateWalletOwner(OwnerId(1)); err != nil {
+		t.FailNow()
+	}
+
+	if notif, err := s.GetOwnerDailyNotificationTime(OwnerId(1)); notif != nil || err != nil {
+		t.Errorf("Notification time must be absent by default")
+	}
+
+	notifTime := 9*time.Hour + 30*time.Minute
+	if err := s.SetOwnerDailyNotificationTime(OwnerId(1), &notifTime); err != nil {
+		t.FailNow()
+	}
+	if notif, err := s.GetOwnerDailyNotificationTime(OwnerId(1)); notif == nil || *notif != notifTime || err != nil {
+		t.Errorf("Notification time has not been stored")
+	}
+
+	if err := s.SetOwnerDailyNotificationTime(OwnerId(1), nil); err != nil {
+		t.FailNow()
+	}
+	if notif, err := s.GetOwnerDailyNotificationTime(OwnerId(1)); notif != nil || err != nil {
+		t.Errorf("Notification time has not been removed")
+	}
+}
+
+func testStorageRegularTransactions(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	txs, err := s.GetRegularTransactions(w.ID)
+	if err != nil || txs == nil || len(txs) != 0 {
+		t.Errorf("Expected empty non-nil list of regular transactions, got %#v", txs)
+	}
+
+	salary := *NewRegularTransaction(1000, 5, "salary")
+	salary.Shift = ShiftPrevious
+	rent := *NewRegularTransaction(-300, 20, "rent")
+	if s.AddRegularTransaction(w.ID, salary) != nil || s.AddRegularTransaction(w.ID, rent) != nil {
+		t.FailNow()
+	}
+
+	txs, err = s.GetRegularTransactions(w.ID)
+	if err != nil || len(txs) != 2 {
+		t.Fatalf("Unexpected regular transactions %+v", txs)
+	}
+	for _, expected := range []RegularTransaction{salary, rent} {
+		if findRegularTransactionExactMatch(txs, expected) == nil {
+			t.Errorf("Regular transaction %+v is absent in %+v", expected, txs)
+		}
+	}
+
+	if err := s.RemoveRegularTransaction(w.ID, *NewRegularTransaction(-301, 20, "rent")); err == nil {
+		t.Errorf("Regular transaction with another value has been removed")
+	}
+	if err := s.RemoveRegularTransaction(w.ID, *NewRegularTransaction(-300, 21, "rent")); err == nil {
+		t.Errorf("Regular transaction with another date has been removed")
+	}
+	if err := s.RemoveRegularTransaction(w.ID, rent); err != nil {
+		t.Errorf("Regular transaction has not been removed: %s", err)
+	}
+	if err := s.RemoveRegularTransaction(w.ID, rent); err == nil {
+		t.Errorf("Regular transaction has been removed twice")
+	}
+
+	txs, err = s.GetRegularTransactions(w.ID)
+	if err != nil || len(txs) != 1 || txs[0] != salary {
+		t.Errorf("Unexpected regular transactions after removal %+v", txs)
+	}
+
+	start := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		date int
+		rule Recurrence
+	}{
+		{31, Recurrence{}},
+		{LastDayOfMonth, Recurrence{}},
+		{0, Recurrence{Freq: FreqWeekly, Weekday: time.Friday}},
+		{0, Recurrence{Freq: FreqWeekly, Interval: 2, Weekday: time.Friday, From: start}},
+		{2, Recurrence{Freq: FreqMonthly, Interval: 3, From: start}},
+		{15, Recurrence{Freq: FreqYearly, Month: time.March}},
+		{5, Recurrence{From: start, Until: start.AddDate(1, 0, 3)}},
+	} {
+		bonus := *NewRecurringTransaction(500, c.date, c.rule, "bonus")
+		if err := s.AddRegularTransaction(w.ID, bonus); err != nil {
+			t.Fatalf("Regular transaction at %s has not been added: %s", bonus.Schedule(), err)
+		}
+		txs, err := s.GetRegularTransactions(w.ID)
+		if found := findRegularTransactionExactMatch(txs, bonus); err != nil || found == nil || found.Schedule() != bonus.Schedule() {
+			t.Errorf("Regular transaction at %s is absent in %+v", bonus.Schedule(), txs)
+		}
+		if err := s.RemoveRegularTransaction(w.ID, bonus); err != nil {
+			t.Errorf("Regular transaction at %s has not been removed: %s", bonus.Schedule(), err)
+		}
+	}
+
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+	if txs, err := s.GetRegularTransactions(other.ID); err != nil || len(txs) != 0 {
+		t.Errorf("Regular transactions leaked into another wallet: %+v", txs)
+	}
+}
+
+func testStorageGoals(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	created := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	bike := *NewTargetGoal("bike", 3000000, created, time.Date(2027, 3, 31, 0, 0, 0, 0, time.UTC))
+	reserve := *NewPercentGoal("reserve", 10, created)
+	for _, g := range []Goal{reserve, bike} {
+		if err := s.AddGoal(w.ID, g); err != nil {
+			t.Fatalf("Goal '%s' has not been added: %s", g.Name, err)
+		}
+	}
+
+	goals, err := s.GetGoals(w.ID)
+	if err != nil || len(goals) != 2 {
+		t.Fatalf("Unexpected goals %+v (error: %v)", goals, err)
+	}
+	for i, expected := range []Goal{bike, reserve} {
+		g := goals[i]
+		if g.ID == "" || g.Name != expected.Name || g.Target != expected.Target || g.Percent != expected.Percent ||
+			!g.Created.Equal(expected.Created) || !g.Deadline.Equal(expected.Deadline) {
+			t.Errorf("Goal %+v has been stored as %+v", expected, g)
+		}
+	}
+
+	if err := s.RemoveGoal(w.ID, goals[0].ID); err != nil {
+		t.Errorf("Goal has not been removed: %s", err)
+	}
+	if err := s.RemoveGoal(w.ID, goals[0].ID); err == nil {
+		t.Errorf("Removed goal has been removed once more")
+	}
+	if goals, err := s.GetGoals(w.ID); err != nil || len(goals) != 1 || goals[0].Name != "reserve" {
+		t.Errorf("Unexpected goals after removal: %+v", goals)
+	}
+
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+	if goals, err := s.GetGoals(other.ID); err != nil || len(goals) != 0 {
+		t.Errorf("Goals leaked into another wallet: %+v", goals)
+	}
+}
+
+func testStorageActualTransactions(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	tMin, tMax := t1.AddDate(0, 0, -5), t1.AddDate(0, 0, 5)
+
+	txs, err := s.GetActualTransactions(w.ID, tMin, tMax)
+	if err != nil || txs == nil || len(txs) != 0 {
+		t.Errorf("Expected empty non-nil list of actual transactions, got %#v", txs)
+	}
+
+	expense := *NewActualTransaction(-500, t1, "food", "500 #food")
+	income := *NewActualTransaction(1500, t1.Add(time.Hour), "", "+1500")
+	if s.AddActualTransaction(w.ID, expense) != nil || s.AddActualTransaction(w.ID, income) != nil {
+		t.FailNow()
+	}
+
+	txs, err = s.GetActualTransactions(w.ID, tMin, tMax)
+	if err != nil || len(txs) != 2 {
+		t.Fatalf("Unexpected actual transactions %+v", txs)
+	}
+	for _, expected := range []ActualTransaction{expense, income} {
+		found := false
+		for _, tx := range txs {
+			if tx.ID == expected.ID && tx.Value == expected.Value && tx.Time.Equal(expected.Time) && tx.Label == expected.Label && tx.RawText == expected.RawText {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Actual transaction %+v is absent in %+v", expected, txs)
+		}
+	}
+
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+	if txs, err := s.GetActualTransactions(other.ID, tMin, tMax); err != nil || len(txs) != 0 {
+		t.Errorf("Actual transactions leaked into another wallet: %+v", txs)
+	}
+}
+
+func testStorageActualTransactionsTimeWindow(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	tMin := time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+	tMax := time.Date(2018, 6, 30, 23, 59, 59, 0, time.UTC)
+	points := []struct {
+		t        time.Time
+		inWindow bool
+	}{
+		{tMin.Add(-time.Second), false},
+		{tMin, true},
+		{tMin.Add(time.Second), true},
+		{tMax.Add(-time.Second), true},
+		{tMax, true},
+		{tMax.Add(time.Second), false},
+	}
+	expected := 0
+	for i, p := range points {
+		if s.AddActualTransaction(w.ID, *NewActualTransaction(-(i + 1), p.t, "", "")) != nil {
+			t.FailNow()
+		}
+		if p.inWindow {
+			expected -= i + 1
+		}
+	}
+
+	txs, err := s.GetActualTransactions(w.ID, tMin, tMax)
+	if err != nil {
+		t.FailNow()
+	}
+	sum := 0
+	for _, tx := range txs {
+		sum += tx.Value
+	}
+	if sum != expected {
+		t.Errorf("Wrong transactions are in time window [%s; %s]: %+v", tMin, tMax, txs)
+	}
+
+	txs, err = s.GetActualTransactions(w.ID, tMin, tMin)
+	if err != nil || len(txs) != 1 || txs[0].Value != -2 {
+		t.Errorf("Zero-length time window must contain exactly one transaction, got %+v", txs)
+	}
+}
+
+func testStorageSameSecondTransactions(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if s.AddActualTransaction(w.ID, *NewActualTransaction(-100, t1, "food", "100 #food")) != nil {
+			t.FailNow()
+		}
+	}
+	if txs, err := s.GetActualTransactions(w.ID, t1, t1); err != nil || len(txs) != 2 || txs[0].ID == txs[1].ID {
+		t.Errorf("Identical actual transactions must be kept separately, got %+v", txs)
+	}
+
+	rent := *NewRegularTransaction(-300, 20, "rent")
+	sameRent := *NewRegularTransaction(-300, 20, "rent")
+	if s.AddRegularTransaction(w.ID, rent) != nil || s.AddRegularTransaction(w.ID, sameRent) != nil {
+		t.FailNow()
+	}
+	if err := s.RemoveRegularTransaction(w.ID, rent); err != nil {
+		t.Fatalf("Regular transaction has not been removed: %s", err)
+	}
+	if txs, err := s.GetRegularTransactions(w.ID); err != nil || len(txs) != 1 || txs[0].ID != sameRent.ID {
+		t.Errorf("Only the regular transaction with given ID must be removed, left %+v", txs)
+	}
+}
+
+func testStorageActualTransactionModification(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	tMin, tMax := t1.AddDate(0, 0, -5), t1.AddDate(0, 0, 5)
+	typo := *NewActualTransaction(-500, t1, "food", "500 #food")
+	other := *NewActualTransaction(-20, t1, "", "20")
+	if s.AddActualTransaction(w.ID, typo) != nil || s.AddActualTransaction(w.ID, other) != nil {
+		t.FailNow()
+	}
+
+	if tx, err := s.GetActualTransaction(w.ID, typo.ID); err != nil || tx == nil || tx.Value != typo.Value || !tx.Time.Equal(t1) || tx.RawText != typo.RawText {
+		t.Errorf("Unexpected transaction got by ID: %+v", tx)
+	}
+	if _, err := s.GetActualTransaction(w.ID, newTransactionId()); err == nil {
+		t.Errorf("Absent transaction has been found")
+	}
+
+	// changing sign and time as well, as some storages use them in keys
+	fixed := typo
+	fixed.Value = 50
+	fixed.Time = t1.Add(time.Hour)
+	fixed.Label = "refund"
+	fixed.RawText = "+50 #refund"
+	if err := s.UpdateActualTransaction(w.ID, fixed); err != nil {
+		t.Fatalf("Transaction has not been updated: %s", err)
+	}
+	if err := s.UpdateActualTransaction(w.ID, *NewActualTransaction(1, t1, "", "")); err == nil {
+		t.Errorf("Absent transaction has been updated")
+	}
+	txs, err := s.GetActualTransactions(w.ID, tMin, tMax)
+	if err != nil || len(txs) != 2 {
+		t.Fatalf("Unexpected transactions after update: %+v", txs)
+	}
+	if tx, err := s.GetActualTransaction(w.ID, typo.ID); err != nil || tx.Value != 50 || !tx.Time.Equal(fixed.Time) || tx.Label != "refund" || tx.RawText != "+50 #refund" {
+		t.Errorf("Transaction has not been updated properly: %+v", tx)
+	}
+	if txs, _ := s.GetActualTransactions(w.ID, t1, t1); len(txs) != 1 || txs[0].ID != other.ID {
+		t.Errorf("Updated transaction is still found at its old time: %+v", txs)
+	}
+
+	if err := s.RemoveActualTransaction(w.ID, typo.ID); err != nil {
+		t.Errorf("Transaction has not been removed: %s", err)
+	}
+	if err := s.RemoveActualTransaction(w.ID, typo.ID); err == nil {
+		t.Errorf("Transaction has been removed twice")
+	}
+	if txs, err := s.GetActualTransactions(w.ID, tMin, tMax); err != nil || len(txs) != 1 || txs[0].ID != other.ID {
+		t.Errorf("Unexpected transactions after removal: %+v", txs)
+	}
+
+	another, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := s.RemoveActualTransaction(another.ID, other.ID); err == nil {
+		t.Errorf("Transaction has been removed via another wallet")
+	}
+}
+
+func testStorageActualTransactionByMessage(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	fromMsg := *NewActualTransaction(-500, t1, "food", "500 #food")
+	fromMsg.MessageID = 42
+	fromMsg.ChatID = 1
+	fromMsg.UserID = 7
+	if s.AddActualTransaction(w.ID, fromMsg) != nil || s.AddActualTransaction(w.ID, *NewActualTransaction(-1, t1, "", "")) != nil {
+		t.FailNow()
+	}
+
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err != nil || tx.ID != fromMsg.ID || tx.MessageID != 42 || tx.ChatID != 1 || tx.UserID != 7 {
+		t.Errorf("Unexpected transaction got by message: %+v", tx)
+	}
+	if _, err := s.GetActualTransactionByMessage(w.ID, 1, 43); err == nil {
+		t.Errorf("Transaction has been found for another message")
+	}
+	if _, err := s.GetActualTransactionByMessage(other.ID, 1, 42); err == nil {
+		t.Errorf("Transaction has been found for message in another wallet")
+	}
+	if txs, err := s.GetActualTransactions(w.ID, t1, t1); err != nil || len(txs) != 2 {
+		t.Errorf("Unexpected transactions: %+v", txs)
+	}
+
+	// message ID is kept as is on update, even if the key changes
+	fromMsg.Value = 50
+	fromMsg.Time = t1.Add(time.Minute)
+	if err := s.UpdateActualTransaction(w.ID, fromMsg); err != nil {
+		t.Fatalf("Transaction has not been updated: %s", err)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err != nil || tx.ID != fromMsg.ID || tx.Value != 50 {
+		t.Errorf("Updated transaction is not found by message: %+v", tx)
+	}
+
+	if err := s.RemoveActualTransaction(w.ID, fromMsg.ID); err != nil {
+		t.Fatalf("Transaction has not been removed: %s", err)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err == nil {
+		t.Errorf("Removed transaction is found by message: %+v", tx)
+	}
+}
+
+func testStorageActualTransactionByMessageOfSharedWallet(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := s.AttachOwnerToWallet(OwnerId(2), w.ID, ""); err != nil {
+		t.Fatalf("Owner has not been attached: %s", err)
+	}
+
+	// both chats have their own message with the same ID
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	first := *NewActualTransaction(-500, t1, "food", "500 #food")
+	first.MessageID = 42
+	first.ChatID = 1
+	second := *NewActualTransaction(-300, t1, "taxi", "300 #taxi")
+	second.MessageID = 42
+	second.ChatID = 2
+	if s.AddActualTransaction(w.ID, first) != nil || s.AddActualTransaction(w.ID, second) != nil {
+		t.FailNow()
+	}
+
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err != nil || tx.ID != first.ID {
+		t.Errorf("Unexpected transaction got by message of the first chat: %+v", tx)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 2, 42); err != nil || tx.ID != second.ID {
+		t.Errorf("Unexpected transaction got by message of the second chat: %+v", tx)
+	}
+	if _, err := s.GetActualTransactionByMessage(w.ID, 3, 42); err == nil {
+		t.Errorf("Transaction has been found for message of another chat")
+	}
+
+	second.Value = -30
+	if err := s.UpdateActualTransaction(w.ID, second); err != nil {
+		t.Fatalf("Transaction has not been updated: %s", err)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err != nil || tx.ID != first.ID || tx.Value != -500 {
+		t.Errorf("Transaction of the first chat has been affected by update: %+v", tx)
+	}
+
+	if err := s.RemoveActualTransaction(w.ID, second.ID); err != nil {
+		t.Fatalf("Transaction has not been removed: %s", err)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 1, 42); err != nil || tx.ID != first.ID {
+		t.Errorf("Transaction of the first chat has been affected by removal: %+v", tx)
+	}
+	if tx, err := s.GetActualTransactionByMessage(w.ID, 2, 42); err == nil {
+		t.Errorf("Removed transaction is found by message: %+v", tx)
+	}
+}
+
+func testStorageOperationJournal(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+
+	if op, err := s.PopOperation(w.ID); op != nil || err != nil {
+		t.Errorf("Expected empty journal, got %+v", op)
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	actual := NewActualTransaction(-500, t1, "food", "500 #food")
+	regular := NewRegularTransaction(1000, 5, "salary")
+	ops := []Operation{
+		{Type: OperationAddActual, Time: t1, Actual: actual},
+		{Type: OperationRemoveRegular, Time: t1.Add(time.Second), Regular: regular},
+		{Type: OperationSetMonthStart, Time: t1.Add(time.Minute), MonthStart: 15},
+	}
+	for _, op := range ops {
+		if err := s.PushOperation(w.ID, op); err != nil {
+			t.Fatalf("Operation has not been added: %s", err)
+		}
+	}
+	if op, err := s.PopOperation(other.ID); op != nil || err != nil {
+		t.Errorf("Operation leaked into another wallet: %+v", op)
+	}
+
+	if op, err := s.LastOperation(w.ID); err != nil || op == nil || op.Type != OperationSetMonthStart || op.MonthStart != 15 {
+		t.Errorf("Unexpected latest operation %+v", op)
+	}
+	if op, err := s.LastOperation(other.ID); op != nil || err != nil {
+		t.Errorf("Operation leaked into another wallet: %+v", op)
+	}
+	if err := s.DropOperation(w.ID, ops[1]); err == nil {
+		t.Errorf("Operation which is not the latest one has been dropped")
+	}
+	if err := s.DropOperation(other.ID, ops[2]); err == nil {
+		t.Errorf("Operation has been dropped from another wallet")
+	}
+	if err := s.DropOperation(w.ID, ops[2]); err != nil {
+		t.Errorf("Latest operation has not been dropped: %s", err)
+	}
+	if op, err := s.LastOperation(w.ID); err != nil || op == nil || op.Type != ops[1].Type {
+		t.Errorf("Unexpected latest operation %+v after drop", op)
+	}
+	if err := s.PushOperation(w.ID, ops[2]); err != nil {
+		t.FailNow()
+	}
+	for i := len(ops) - 1; i >= 0; i-- {
+		op, err := s.PopOperation(w.ID)
+		if err != nil || op == nil || op.Type != ops[i].Type || !op.Time.Equal(ops[i].Time) || op.MonthStart != ops[i].MonthStart {
+			t.Fatalf("Unexpected operation %+v instead of %+v", op, ops[i])
+		}
+		if (ops[i].Actual != nil) != (op.Actual != nil) || (op.Actual != nil && (op.Actual.ID != actual.ID || op.Actual.Value != actual.Value)) {
+			t.Errorf("Actual transaction of operation has been changed: %+v", op.Actual)
+		}
+		if (ops[i].Regular != nil) != (op.Regular != nil) || (op.Regular != nil && *op.Regular != *regular) {
+			t.Errorf("Regular transaction of operation has been changed: %+v", op.Regular)
+		}
+	}
+	if op, err := s.PopOperation(w.ID); op != nil || err != nil {
+		t.Errorf("Journal must be empty after all operations are taken, got %+v", op)
+	}
+	if op, err := s.LastOperation(w.ID); op != nil || err != nil {
+		t.Errorf("Latest operation of empty journal is %+v", op)
+	}
+
+	for i := 0; i < journalLimit+5; i++ {
+		if s.PushOperation(w.ID, Operation{Type: OperationSetMonthStart, Time: t1, MonthStart: i}) != nil {
+			t.FailNow()
+		}
+	}
+	count := 0
+	for op, err := s.PopOperation(w.ID); op != nil; op, err = s.PopOperation(w.ID) {
+		if err != nil || op.MonthStart != journalLimit+4-count {
+			t.Fatalf("Unexpected operation %+v at position %d", op, count)
+		}
+		count++
+	}
+	if count != journalLimit {
+		t.Errorf("Journal keeps %d operations instead of %d", count, journalLimit)
+	}
+}
+
+func testStorageCurrencies(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if w.Currency != "" {
+		t.Errorf("New wallet must have no base currency, got %s", w.Currency)
+	}
+	if err := s.SetWalletCurrency(w.ID, "RUB"); err != nil {
+		t.Fatalf("Currency has not been set: %s", err)
+	}
+	if err := s.SetWalletInfo(w.ID, 10); err != nil {
+		t.FailNow()
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w.Currency != "RUB" || w.MonthStart != 10 {
+		t.Errorf("Unexpected wallet after currency change: %+v", w)
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	taxi := *NewActualTransaction(-20, t1, "taxi", "20 EUR #taxi")
+	taxi.Currency = "EUR"
+	rent := *NewRegularTransaction(-300, 20, "rent")
+	rent.Currency = "USD"
+	if s.AddActualTransaction(w.ID, taxi) != nil || s.AddRegularTransaction(w.ID, rent) != nil {
+		t.FailNow()
+	}
+	if txs, err := s.GetActualTransactions(w.ID, t1, t1); err != nil || len(txs) != 1 || txs[0].Currency != "EUR" {
+		t.Errorf("Currency of actual transaction has not been stored: %+v", txs)
+	}
+	if tx, err := s.GetActualTransaction(w.ID, taxi.ID); err != nil || tx.Currency != "EUR" {
+		t.Errorf("Currency of actual transaction has not been stored: %+v", tx)
+	}
+	if txs, err := s.GetRegularTransactions(w.ID); err != nil || len(txs) != 1 || txs[0].Currency != "USD" {
+		t.Errorf("Currency of regular transaction has not been stored: %+v", txs)
+	}
+}
+
+func testStorageDecimals(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if w.Decimals != defaultDecimals {
+		t.Errorf("New wallet must have %d decimals, got %d", defaultDecimals, w.Decimals)
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w.Decimals != defaultDecimals {
+		t.Errorf("Stored wallet must have %d decimals: %+v", defaultDecimals, w)
+	}
+
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	if err := s.AddActualTransaction(w.ID, *NewActualTransaction(-1250, t1, "food", "12.50 #food")); err != nil {
+		t.FailNow()
+	}
+	if err := s.AddRegularTransaction(w.ID, *NewRegularTransaction(1249, 5, "salary")); err != nil {
+		t.FailNow()
+	}
+	if err := s.AddGoal(w.ID, *NewTargetGoal("bike", 30050, t1, t1.AddDate(1, 0, 0))); err != nil {
+		t.FailNow()
+	}
+	if err := s.SetWalletLimit(w.ID, "food", 1500); err != nil {
+		t.FailNow()
+	}
+	if err := s.SetWalletCarryOver(w.ID, CarryOver{Mode: CarryOverCapped, Cap: 5000, Since: t1}); err != nil {
+		t.FailNow()
+	}
+	if err := s.PushOperation(w.ID, Operation{Type: OperationSetMonthStart, Time: t1, MonthStart: 1}); err != nil {
+		t.FailNow()
+	}
+
+	if err := s.RescaleWallet(w.ID, defaultDecimals, 0); err != nil {
+		t.Fatalf("Decimals have not been set: %s", err)
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w.Decimals != 0 || w.MonthStart != defaultMonthStart {
+		t.Errorf("Unexpected wallet after decimals change: %+v", w)
+	}
+	if txs, err := s.GetActualTransactions(w.ID, t1, t1); err != nil || len(txs) != 1 || txs[0].Value != -13 {
+		t.Errorf("Actual transaction has not been rescaled: %+v", txs)
+	}
+	if txs, err := s.GetRegularTransactions(w.ID); err != nil || len(txs) != 1 || txs[0].Value != 12 {
+		t.Errorf("Regular transaction has not been rescaled: %+v", txs)
+	}
+	if goals, err := s.GetGoals(w.ID); err != nil || len(goals) != 1 || goals[0].Target != 301 {
+		t.Errorf("Goal has not been rescaled: %+v", goals)
+	}
+	if limits, err := s.GetWalletLimits(w.ID); err != nil || limits["food"] != 15 {
+		t.Errorf("Limit has not been rescaled: %+v", limits)
+	}
+	if c, err := s.GetWalletCarryOver(w.ID); err != nil || c.Cap != 50 {
+		t.Errorf("Carry-over cap has not been rescaled: %+v", c)
+	}
+	if op, err := s.PopOperation(w.ID); err != nil || op != nil {
+		t.Errorf("Journal has not been dropped: %+v", op)
+	}
+	if err := s.RescaleWallet(WalletId("unknown"), defaultDecimals, 0); err == nil {
+		t.Errorf("Unknown wallet has been rescaled")
+	}
+}
+
+func testStorageLimits(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if limits, err := s.GetWalletLimits(w.ID); err != nil || len(limits) != 0 {
+		t.Errorf("New wallet must have no limits: %v (error: %v)", limits, err)
+	}
+	for _, l := range []struct {
+		label string
+		limit int
+	}{{"food", 1500000}, {"taxi", 300000}, {"food", 2000000}} {
+		if err := s.SetWalletLimit(w.ID, l.label, l.limit); err != nil {
+			t.Fatalf("Limit of '%s' has not been set: %s", l.label, err)
+		}
+	}
+	if limits, err := s.GetWalletLimits(w.ID); err != nil || len(limits) != 2 || limits["food"] != 2000000 || limits["taxi"] != 300000 {
+		t.Errorf("Unexpected limits %v (error: %v)", limits, err)
+	}
+	if err := s.SetWalletLimit(w.ID, "taxi", 0); err != nil {
+		t.Errorf("Limit has not been removed: %s", err)
+	}
+	if limits, err := s.GetWalletLimits(w.ID); err != nil || len(limits) != 1 || limits["food"] != 2000000 {
+		t.Errorf("Unexpected limits after removal %v (error: %v)", limits, err)
+	}
+
+	other, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+	if limits, err := s.GetWalletLimits(other.ID); err != nil || len(limits) != 0 {
+		t.Errorf("Limits leaked into another wallet: %v", limits)
+	}
+}
+
+func testStorageCarryOver(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if c, err := s.GetWalletCarryOver(w.ID); err != nil || c.Mode != CarryOverNone {
+		t.Errorf("New wallet must not carry leftovers: %+v (error: %v)", c, err)
+	}
+	policies := []CarryOver{
+		CarryOver{Mode: CarryOverCapped, Cap: 500000, Since: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		CarryOver{Mode: CarryOverGoal, Goal: "bike", Since: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		CarryOver{},
+	}
+	for _, policy := range policies {
+		if err := s.SetWalletCarryOver(w.ID, policy); err != nil {
+			t.Fatalf("Carry-over policy %+v has not been set: %s", policy, err)
+		}
+		if c, err := s.GetWalletCarryOver(w.ID); err != nil || c != policy {
+			t.Errorf("Unexpected carry-over policy %+v instead of %+v (error: %v)", c, policy, err)
+		}
+	}
+}
+
+func testStorageWalletSharing(t *testing.T, s Storage) {
+	w1, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	w2, err := s.CreateWalletOwner(OwnerId(2))
+	if err != nil {
+		t.FailNow()
+	}
+
+	if err := s.AddWalletInvite(w1.ID, "code1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Invite has not been added: %s", err)
+	}
+	if _, err := s.UseWalletInvite("unknown"); err == nil {
+		t.Errorf("Unknown invite code has been accepted")
+	}
+	if err := s.AddWalletInvite(w2.ID, "code1", time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("Invite code has been added twice")
+	}
+	if w, expires, err := s.GetWalletInvite("code1"); err != nil || w != w1.ID || expires.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("Invite code refers to '%s' expiring at %s instead of '%s' (error: %v)", w, expires, w1.ID, err)
+	}
+	if _, _, err := s.GetWalletInvite("unknown"); err == nil {
+		t.Errorf("Unknown invite code has been found")
+	}
+	if w, err := s.UseWalletInvite("code1"); err != nil || w != w1.ID {
+		t.Fatalf("Invite code refers to '%s' instead of '%s' (error: %v)", w, w1.ID, err)
+	}
+	if _, err := s.UseWalletInvite("code1"); err == nil {
+		t.Errorf("Invite code has been used twice")
+	}
+	if _, _, err := s.GetWalletInvite("code1"); err == nil {
+		t.Errorf("Used invite code has been found")
+	}
+
+	if err := s.AttachOwnerToWallet(OwnerId(2), w1.ID, "shared"); err != nil {
+		t.Fatalf("Owner has not been attached: %s", err)
+	}
+	if err := s.AttachOwnerToWallet(OwnerId(2), w1.ID, "again"); err == nil {
+		t.Errorf("Owner has been attached to the same wallet twice")
+	}
+	if err := s.AttachOwnerToWallet(OwnerId(3), WalletId("unknown"), "shared"); err == nil {
+		t.Errorf("Owner has been attached to unknown wallet")
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(2), false); err != nil || w.ID != w1.ID || w.Name != "shared" {
+		t.Errorf("Owner got wallet %+v instead of shared '%s'", w, w1.ID)
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w.Name != defaultWalletName {
+		t.Errorf("Wallet name must be kept for its first owner: %+v", w)
+	}
+	if owners, err := s.GetWalletOwners(w1.ID); err != nil || len(owners) != 2 || owners[0] != 1 || owners[1] != 2 {
+		t.Errorf("Unexpected owners of shared wallet: %v", owners)
+	}
+	if owners, err := s.GetWalletOwners(w2.ID); err != nil || len(owners) != 1 || owners[0] != 2 {
+		t.Errorf("Previous wallet must be kept for its owner: %v", owners)
+	}
+
+	notifTime := time.Hour * 9
+	if err := s.SetOwnerDailyNotificationTime(OwnerId(2), &notifTime); err != nil {
+		t.FailNow()
+	}
+	if err := s.DetachOwner(OwnerId(2), w1.ID); err != nil {
+		t.Fatalf("Owner has not been detached: %s", err)
+	}
+	if err := s.DetachOwner(OwnerId(2), w1.ID); err == nil {
+		t.Errorf("Owner has been detached twice")
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(2), false); err == nil {
+		t.Errorf("Detached owner still has an active wallet: %+v", w)
+	}
+	if owners, err := s.GetWalletOwners(w1.ID); err != nil || len(owners) != 1 || owners[0] != 1 {
+		t.Errorf("Unexpected owners after detaching: %v", owners)
+	}
+	if notif, err := s.GetOwnerDailyNotificationTime(OwnerId(2)); err != nil || notif == nil || *notif != notifTime {
+		t.Errorf("Owner settings have not been kept after detaching: %v", notif)
+	}
+	if wallets, err := s.GetOwnerWallets(OwnerId(2)); err != nil || len(wallets) != 1 || wallets[0].ID != w2.ID {
+		t.Errorf("Unexpected wallets of detached owner: %+v", wallets)
+	}
+	if err := s.DetachOwner(OwnerId(2), w2.ID); err != nil {
+		t.Fatalf("Owner has not been detached from the last wallet: %s", err)
+	}
+	if w, err := s.CreateWalletOwner(OwnerId(2)); err != nil || w.ID == w1.ID || w.ID == w2.ID {
+		t.Errorf("Detached owner could not get a new wallet: %+v (error: %v)", w, err)
+	}
+}
+
+func testStorageNamedWallets(t *testing.T, s Storage) {
+	if wallets, err := s.GetOwnerWallets(OwnerId(1)); err != nil || len(wallets) != 0 {
+		t.Errorf("Unknown owner has wallets: %+v", wallets)
+	}
+	main, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil || main.Name != defaultWalletName {
+		t.Fatalf("Owner has got wallet %+v (error: %v)", main, err)
+	}
+	vacation, err := s.AddOwnerWallet(OwnerId(1), "vacation")
+	if err != nil || vacation.Name != "vacation" || vacation.ID == main.ID {
+		t.Fatalf("Named wallet has not been created: %+v (error: %v)", vacation, err)
+	}
+	if _, err := s.AddOwnerWallet(OwnerId(1), "vacation"); err == nil {
+		t.Errorf("Wallet name has been used twice")
+	}
+	business, err := s.AddOwnerWallet(OwnerId(1), "business")
+	if err != nil {
+		t.FailNow()
+	}
+	if _, err := s.AddOwnerWallet(OwnerId(2), "vacation"); err != nil {
+		t.Errorf("Different owners must be able to use the same wallet name: %s", err)
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w.ID != main.ID {
+		t.Errorf("Active wallet has been changed by adding a new one: %+v", w)
+	}
+	if err := s.SetWalletCurrency(business.ID, "EUR"); err != nil {
+		t.FailNow()
+	}
+
+	wallets, err := s.GetOwnerWallets(OwnerId(1))
+	if err != nil || len(wallets) != 3 {
+		t.Fatalf("Unexpected wallets: %+v (error: %v)", wallets, err)
+	}
+	for i, expected := range []*Wallet{business, main, vacation} {
+		if wallets[i].ID != expected.ID || wallets[i].Name != expected.Name {
+			t.Errorf("Wallet %d is %+v instead of %+v", i, wallets[i], expected)
+		}
+	}
+	if wallets[0].Currency != "EUR" {
+		t.Errorf("Wallet details have not been loaded: %+v", wallets[0])
+	}
+
+	if err := s.SetActiveWallet(OwnerId(1), vacation.ID); err != nil {
+		t.Fatalf("Wallet has not been activated: %s", err)
+	}
+	if w, err := s.GetWalletForOwner(OwnerId(1), true); err != nil || w.ID != vacation.ID || w.Name != "vacation" {
+		t.Errorf("Owner got wallet %+v instead of activated '%s'", w, vacation.ID)
+	}
+	if err := s.SetActiveWallet(OwnerId(2), vacation.ID); err == nil {
+		t.Errorf("Wallet of another owner has been activated")
+	}
+	if owners, err := s.GetWalletOwners(business.ID); err != nil || len(owners) != 1 || owners[0] != 1 {
+		t.Errorf("Unexpected owners of named wallet: %v", owners)
+	}
+}
+
+func testStorageAdminsOnly(t *testing.T, s Storage) {
+	w, err := s.CreateWalletOwner(OwnerId(-100))
+	if err != nil {
+		t.FailNow()
+	}
+	if adminsOnly, err := s.GetOwnerAdminsOnly(OwnerId(-100)); err != nil || adminsOnly {
+		t.Errorf("Admins only mode must be disabled by default (error: %v)", err)
+	}
+	if adminsOnly, err := s.GetOwnerAdminsOnly(OwnerId(-200)); err != nil || adminsOnly {
+		t.Errorf("Admins only mode must be disabled for unknown owner (error: %v)", err)
+	}
+	if err := s.SetOwnerAdminsOnly(OwnerId(-100), true); err != nil {
+		t.Fatalf("Admins only mode has not been set: %s", err)
+	}
+	if adminsOnly, err := s.GetOwnerAdminsOnly(OwnerId(-100)); err != nil || !adminsOnly {
+		t.Errorf("Admins only mode has not been enabled (error: %v)", err)
+	}
+	if w2, err := s.GetWalletForOwner(OwnerId(-100), false); err != nil || w2.ID != w.ID {
+		t.Errorf("Owner wallet has changed after settings update: %+v", w2)
+	}
+	if err := s.SetOwnerAdminsOnly(OwnerId(-100), false); err != nil {
+		t.FailNow()
+	}
+	if adminsOnly, err := s.GetOwnerAdminsOnly(OwnerId(-100)); err != nil || adminsOnly {
+		t.Errorf("Admins only mode has not been disabled (error: %v)", err)
+	}
+}
+
+func testStorageTimezone(t *testing.T, s Storage) {
+	if tz, err := s.GetOwnerTimezone(OwnerId(1)); err != nil || tz != "" {
+		t.Errorf("Unknown owner got time zone '%s' (error: %v)", tz, err)
+	}
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if err := s.SetOwnerTimezone(OwnerId(1), "Europe/Berlin"); err != nil {
+		t.Fatalf("Time zone has not been set: %s", err)
+	}
+	if err := s.SetOwnerTimezone(OwnerId(2), "Pacific/Kiritimati"); err != nil {
+		t.Fatalf("Time zone has not been set for owner without wallet: %s", err)
+	}
+	if tz, err := s.GetOwnerTimezone(OwnerId(1)); err != nil || tz != "Europe/Berlin" {
+		t.Errorf("Owner got time zone '%s' instead of 'Europe/Berlin' (error: %v)", tz, err)
+	}
+	if w2, err := s.GetWalletForOwner(OwnerId(1), false); err != nil || w2.ID != w.ID {
+		t.Errorf("Owner wallet has changed after time zone update: %+v", w2)
+	}
+
+	owners, err := s.GetAllOwners()
+	if err != nil || len(owners) != 2 {
+		t.Fatalf("Unexpected owners: %+v", owners)
+	}
+	if tz := owners[OwnerId(2)].Timezone; tz == nil || *tz != "Pacific/Kiritimati" {
+		t.Errorf("Unexpected time zone in owner data: %v", tz)
+	}
+}
+
+func testStorageUserNames(t *testing.T, s Storage) {
+	if name, err := s.GetUserName(5); err != nil || name != "" {
+		t.Errorf("Unknown user got name '%s' (error: %v)", name, err)
+	}
+	if err := s.SetUserName(5, "Alice"); err != nil {
+		t.Fatalf("User name has not been set: %s", err)
+	}
+	if err := s.SetUserName(5, "@alice"); err != nil {
+		t.Fatalf("User name has not been updated: %s", err)
+	}
+	if name, err := s.GetUserName(5); err != nil || name != "@alice" {
+		t.Errorf("User got name '%s' instead of '@alice' (error: %v)", name, err)
+	}
+}
+
+func testStorageAllOwners(t *testing.T, s Storage) {
+	owners, err := s.GetAllOwners()
+	if err != nil || len(owners) != 0 {
+		t.Errorf("Unexpected owners in empty storage: %+v", owners)
+	}
+
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if _, err := s.CreateWalletOwner(OwnerId(2)); err != nil {
+		t.FailNow()
+	}
+	notifTime := 20 * time.Hour
+	if s.SetOwnerDailyNotificationTime(OwnerId(1), &notifTime) != nil {
+		t.FailNow()
+	}
+	if s.AddRegularTransaction(w.ID, *NewRegularTransaction(1000, 5, "salary")) != nil ||
+		s.AddRegularTransaction(w.ID, *NewRegularTransaction(-300, 5, "rent")) != nil ||
+		s.AddRegularTransaction(w.ID, *NewRegularTransaction(-100, 7, "phone")) != nil ||
+		s.AddRegularTransaction(w.ID, *NewRegularTransaction(200, LastDayOfMonth, "bonus")) != nil {
+		t.FailNow()
+	}
+
+	owners, err = s.GetAllOwners()
+	if err != nil || len(owners) != 2 {
+		t.Fatalf("Unexpected owners: %+v", owners)
+	}
+	data := owners[OwnerId(1)]
+	if data.WalletId == nil || WalletId(*data.WalletId) != w.ID {
+		t.Errorf("Wrong wallet for owner")
+	}
+	if data.DailyReminderTime == nil || *data.DailyReminderTime != notifTime {
+		t.Errorf("Wrong reminder time for owner")
+	}
+	if len(data.RegularTxs) != 3 || len(data.RegularTxs[5]) != 2 || len(data.RegularTxs[7]) != 1 || len(data.RegularTxs[LastDayOfMonth]) != 1 {
+		t.Errorf("Wrong regular transactions for owner: %+v", data.RegularTxs)
+	}
+	if data := owners[OwnerId(2)]; data.DailyReminderTime != nil || data.Timezone != nil || data.RegularTxs == nil || len(data.RegularTxs) != 0 {
+		t.Errorf("Unexpected data for second owner: %+v", data)
+	}
+}
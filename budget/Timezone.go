@@ -0,0 +1,68 @@
+package budget
+
+import "log"
+import "fmt"
+import "time"
+
+// LoadTimezone checks IANA time zone name like 'Europe/Berlin' and returns its location
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("Unknown time zone '%s'", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Could not load time zone '%s' due to error: %s", name, err)
+		return nil, fmt.Errorf("Unknown time zone '%s'", name)
+	}
+	return loc, nil
+}
+
+// OwnerLocation returns time zone of the owner; UTC if it has not been set or cannot be loaded
+func OwnerLocation(owner OwnerId, storageconn Storage) *time.Location {
+	name, err := storageconn.GetOwnerTimezone(owner)
+	if err != nil || name == "" {
+		return time.UTC
+	}
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Location returns time zone of the owner; UTC if it has not been set or cannot be loaded
+func (d OwnerData) Location() *time.Location {
+	if d.Timezone == nil || *d.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := LoadTimezone(*d.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// SetOwnerTimezone validates and stores time zone of the owner
+func SetOwnerTimezone(owner OwnerId, name string, storageconn Storage) (*time.Location, error) {
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := storageconn.SetOwnerTimezone(owner, loc.String()); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// Now returns current time in time zone of the wallet's owner, so that month borders and days are calculated for it
+func (w *Wallet) Now() time.Time {
+	return w.LocalTime(time.Now())
+}
+
+// LocalTime converts t into time zone of the wallet's owner
+func (w *Wallet) LocalTime(t time.Time) time.Time {
+	if w.Location == nil {
+		return t.In(time.UTC)
+	}
+	return t.In(w.Location)
+}
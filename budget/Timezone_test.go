@@ -0,0 +1,59 @@
+package budget
+
+import "time"
+import "testing"
+
+func TestLoadTimezone(t *testing.T) {
+	if loc, err := LoadTimezone("Europe/Berlin"); err != nil || loc.String() != "Europe/Berlin" {
+		t.Errorf("Time zone has not been loaded: %v (error: %v)", loc, err)
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := LoadTimezone(name); err == nil {
+			t.Errorf("Time zone '%s' has been accepted", name)
+		}
+	}
+}
+
+func TestMonthBordersAcrossDateLine(t *testing.T) {
+	s := NewRamStorage()
+	// the same moment is already in July east of the date line, while it is still June west of it
+	moment := time.Date(2018, 6, 30, 20, 0, 0, 0, time.UTC)
+	tx := time.Date(2018, 6, 30, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		owner      OwnerId
+		tz         string
+		monthStart time.Month
+		txIncluded bool
+	}{
+		{OwnerId(1), "Pacific/Kiritimati", time.July, false}, // UTC+14: transaction has been made on June 30, before current month
+		{OwnerId(2), "Pacific/Pago_Pago", time.June, true},   // UTC-11: transaction has been made on June 29
+	}
+	for _, c := range cases {
+		if _, err := SetOwnerTimezone(c.owner, c.tz, s); err != nil {
+			t.Fatalf("Time zone has not been set: %s", err)
+		}
+		w, err := GetWalletForOwner(c.owner, true, s)
+		if err != nil {
+			t.FailNow()
+		}
+		if w.Location == nil || w.Location.String() != c.tz {
+			t.Errorf("Wallet got location %v instead of %s", w.Location, c.tz)
+		}
+		if _, err := w.AddTransaction(*NewActualTransaction(-100, tx, "", "")); err != nil {
+			t.FailNow()
+		}
+
+		now := w.LocalTime(moment)
+		p := w.Period(now)
+		if p.Start.Month() != c.monthStart || p.Start.Day() != 1 || p.Start.Hour() != 0 || p.Start.Location() != w.Location || !p.End.After(now) {
+			t.Errorf("Owner in %s got month from %s to %s", c.tz, p.Start, p.End)
+		}
+		summary, err := w.GetMonthlySummary(now)
+		if err != nil {
+			t.FailNow()
+		}
+		if included := summary.ExpenseSummary[""] == -100; included != c.txIncluded {
+			t.Errorf("Owner in %s got summary %+v", c.tz, summary.ExpenseSummary)
+		}
+	}
+}
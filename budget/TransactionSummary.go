@@ -2,10 +2,19 @@ package budget
 
 import "time"
 
+// summaryLargestLimit is how many biggest expenses a summary keeps
+const summaryLargestLimit = 5
+
 type TransactionSummary struct {
 	TimeStart, TimeEnd time.Time
 
-	ExpenseSummary map[string]int
+	ExpenseSummary       map[string]int
+	MemberExpenseSummary map[int]int // expenses by telegram users who have added them; 0 is for unknown users
+	IncomeSummary        map[string]int
+	ExpenseCount         map[string]int // number of expenses by labels
+	IncomeCount          map[string]int // number of income transactions by labels
+
+	Largest []ActualTransaction // biggest expenses, the biggest goes first; up to summaryLargestLimit
 }
 
 func NewTransactionSummary(start, end time.Time) *TransactionSummary {
@@ -13,6 +22,76 @@ func NewTransactionSummary(start, end time.Time) *TransactionSummary {
 		TimeStart: start,
 		TimeEnd:   end}
 	result.ExpenseSummary = make(map[string]int, 0)
+	result.MemberExpenseSummary = make(map[int]int, 0)
+	result.IncomeSummary = make(map[string]int, 0)
+	result.ExpenseCount = make(map[string]int, 0)
+	result.IncomeCount = make(map[string]int, 0)
 
 	return result
 }
+
+// Add accounts transaction t which value should be in base currency of the wallet
+func (s *TransactionSummary) Add(t ActualTransaction) {
+	if t.Value == 0 {
+		return
+	}
+	if t.Value > 0 {
+		s.IncomeSummary[t.Label] += t.Value
+		s.IncomeCount[t.Label]++
+		return
+	}
+	s.ExpenseSummary[t.Label] += t.Value
+	s.MemberExpenseSummary[t.UserID] += t.Value
+	s.ExpenseCount[t.Label]++
+
+	i := len(s.Largest)
+	for i > 0 && s.Largest[i-1].Value > t.Value {
+		i--
+	}
+	if i < summaryLargestLimit {
+		s.Largest = append(s.Largest, ActualTransaction{})
+		copy(s.Largest[i+1:], s.Largest[i:])
+		s.Largest[i] = t
+		if len(s.Largest) > summaryLargestLimit {
+			s.Largest = s.Largest[:summaryLargestLimit]
+		}
+	}
+}
+
+// TotalExpense returns sum of all expenses, it is negative like values of ExpenseSummary
+func (s *TransactionSummary) TotalExpense() int {
+	return sumValues(s.ExpenseSummary)
+}
+
+// TotalIncome returns sum of all income
+func (s *TransactionSummary) TotalIncome() int {
+	return sumValues(s.IncomeSummary)
+}
+
+// ExpenseTransactions returns number of all expenses
+func (s *TransactionSummary) ExpenseTransactions() int {
+	return sumValues(s.ExpenseCount)
+}
+
+// IncomeTransactions returns number of all income transactions
+func (s *TransactionSummary) IncomeTransactions() int {
+	return sumValues(s.IncomeCount)
+}
+
+// Days returns how many calendar days the summary covers including its first and last days
+func (s *TransactionSummary) Days() int {
+	return daysBetween(s.TimeStart, s.TimeEnd) + 1
+}
+
+// AverageDailyExpense returns positive amount spent per day on average
+func (s *TransactionSummary) AverageDailyExpense() int {
+	return -s.TotalExpense() / s.Days()
+}
+
+func sumValues(values map[string]int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
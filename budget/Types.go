@@ -1,16 +1,31 @@
 package budget
 
 import "time"
+import "github.com/satori/go.uuid"
+
+// TransactionId uniquely identifies actual or regular transaction within storage
+type TransactionId string
+
+func newTransactionId() TransactionId {
+	return TransactionId(uuid.Must(uuid.NewV4()).String())
+}
 
 type ActualTransaction struct {
-	Value   int
-	Time    time.Time
-	Label   string
-	RawText string // raw text - might be needed, but not necessary
+	ID       TransactionId
+	Value    int
+	Currency Currency // empty for base currency of the wallet
+	Time     time.Time
+	Label    string
+	RawText  string // raw text - might be needed, but not necessary
+
+	MessageID int   // telegram message which transaction has been parsed from; 0 if unknown
+	ChatID    int64 // telegram chat of the message; message IDs are unique only within a chat
+	UserID    int   // telegram user who has added the transaction; 0 if unknown
 }
 
 func NewActualTransaction(value int, t time.Time, label, raw string) *ActualTransaction {
 	amount := &ActualTransaction{
+		ID:      newTransactionId(),
 		Value:   value,
 		Time:    t,
 		Label:   label,
@@ -19,25 +34,73 @@ func NewActualTransaction(value int, t time.Time, label, raw string) *ActualTran
 }
 
 type RegularTransaction struct {
-	Value, Date int
+	ID          TransactionId
+	Value, Date int              // Date is a day of month from 1 to 31 or LastDayOfMonth; 0 for weekly transactions
+	Currency    Currency         // empty for base currency of the wallet
+	Shift       BusinessDayShift // where the transaction is moved if its date is not a business day
+	Recurrence  Recurrence       // every month by default
 	Label       string
 }
 
 func NewRegularTransaction(value, date int, label string) *RegularTransaction {
-	if date < 1 || date > 28 {
+	return NewRecurringTransaction(value, date, Recurrence{}, label)
+}
+
+// NewRecurringTransaction creates a regular transaction repeated according to rule
+func NewRecurringTransaction(value, date int, rule Recurrence, label string) *RegularTransaction {
+	if rule.validate(date) != nil {
 		panic("Date for monthly change is out of borders")
 	}
 	transaction := &RegularTransaction{
-		Value: value,
-		Date:  date,
-		Label: label}
+		ID:         newTransactionId(),
+		Value:      value,
+		Date:       date,
+		Recurrence: rule,
+		Label:      label}
 	return transaction
 }
 
+// validate checks that date of the transaction suits its recurrence rule
+func (t RegularTransaction) validate() error {
+	return t.Recurrence.validate(t.Date)
+}
+
+// sameAs checks whether transactions are equal regardless of their IDs and end dates
+func (t RegularTransaction) sameAs(other RegularTransaction) bool {
+	rule, otherRule := t.Recurrence, other.Recurrence
+	rule.Until, otherRule.Until = time.Time{}, time.Time{}
+	return t.Value == other.Value && t.Currency == other.Currency && t.Date == other.Date && t.Label == other.Label &&
+		rule.String() == otherRule.String()
+}
+
+// OperationType names a change in a wallet which could be undone
+type OperationType string
+
+const (
+	OperationAddActual     OperationType = "addActual"
+	OperationAddRegular    OperationType = "addRegular"
+	OperationRemoveRegular OperationType = "removeRegular"
+	OperationSetMonthStart OperationType = "setMonthStart"
+)
+
+// Operation is a record of wallet's operation journal which keeps everything needed to revert it
+type Operation struct {
+	Type       OperationType
+	Time       time.Time
+	Actual     *ActualTransaction  `json:",omitempty"` // added transaction
+	Regular    *RegularTransaction `json:",omitempty"` // added or removed transaction
+	MonthStart int                 `json:",omitempty"` // month start before the change
+}
+
+// sameAs checks whether both records are about the same operation
+func (op Operation) sameAs(other Operation) bool {
+	return op.Type == other.Type && op.Time.Equal(other.Time)
+}
+
 type OwnerId int64
 type OwnerData struct {
-	WalletId *string `wallet`
-	//Timezone *string  `tz`
-	DailyReminderTime *time.Duration               `dailyNotifTime` // from UTC midnight
-	RegularTxs        map[int][]RegularTransaction // map 'dayOfMonth -> slice of RegularTransaction' used for reminding
+	WalletId          *string                      `redis:"wallet"`
+	Timezone          *string                      `redis:"tz"`             // IANA name; UTC is used if it is not set
+	DailyReminderTime *time.Duration               `redis:"dailyNotifTime"` // from midnight in owner's time zone
+	RegularTxs        map[int][]RegularTransaction // map 'dayOfMonth (or LastDayOfMonth; 0 for weekly) -> slice of RegularTransaction' used for reminding
 }
@@ -10,13 +10,20 @@ type WalletId string
 
 type Wallet struct {
 	ID         WalletId
+	Name       string // name given to the wallet by the owner it has been obtained for
 	MonthStart int
-	storage    Storage
+	Currency   Currency // base currency which balance is calculated in; empty if not set
+	Decimals   int      // all amounts of the wallet are kept in minor units with this number of decimals
+
+	Location *time.Location // time zone of the owner which the wallet has been obtained for; UTC if nil
+	storage  Storage
 }
 
 func NewWalletFromStorage(id string, monthStart int, storageconn Storage) *Wallet {
 	wallet := &Wallet{ID: WalletId(id),
 		MonthStart: monthStart,
+		Name:       defaultWalletName,
+		Decimals:   defaultDecimals,
 		storage:    storageconn}
 	return wallet
 }
@@ -29,11 +36,41 @@ func (w *Wallet) AddTransaction(t ActualTransaction) (matchesRegular bool, e err
 		e = err
 		return
 	}
+	if _, e = w.toBaseCurrency(t.Value, t.Currency); e != nil {
+		return
+	}
 	matchesRegular = checkRegularTransactionLabelExist(regular, t.Label)
+	if t.ID == "" {
+		t.ID = newTransactionId()
+	}
 	e = w.storage.AddActualTransaction(w.ID, t)
+	if e == nil {
+		w.journal(Operation{Type: OperationAddActual, Actual: &t})
+	}
+	return
+}
+
+// UpdateActualTransaction replaces stored transaction having the same ID, returns whether new values match a regular
+func (w *Wallet) UpdateActualTransaction(t ActualTransaction) (matchesRegular bool, e error) {
+	regular, err := w.storage.GetRegularTransactions(w.ID)
+	if err != nil {
+		log.Printf("Could not get regular transactions for wallet '%s' when updating a general transaction due to error: %s", w.ID, err)
+		e = err
+		return
+	}
+	if _, e = w.toBaseCurrency(t.Value, t.Currency); e != nil {
+		return
+	}
+	matchesRegular = checkRegularTransactionLabelExist(regular, t.Label)
+	e = w.storage.UpdateActualTransaction(w.ID, t)
 	return
 }
 
+func (w *Wallet) RemoveActualTransaction(id TransactionId) error {
+	log.Printf("Removing actual transaction '%s' from wallet '%s'", id, w.ID)
+	return w.storage.RemoveActualTransaction(w.ID, id)
+}
+
 func checkRegularTransactionLabelExist(transactions []RegularTransaction, label string) bool {
 	for _, t := range transactions {
 		if t.Label == label {
@@ -44,9 +81,15 @@ func checkRegularTransactionLabelExist(transactions []RegularTransaction, label
 }
 
 func (w *Wallet) AddRegularTransaction(t RegularTransaction) error {
-	date := t.Date
-	if date < 1 || date > 28 {
-		return errors.New("Only dates between 1 and 28 are allowed for regular income/outcome setting")
+	if err := t.Recurrence.validate(t.Date); err != nil {
+		return err
+	}
+	if err := ValidateShift(t.Shift); err != nil {
+		return err
+	}
+
+	if _, err := w.toBaseCurrency(t.Value, t.Currency); err != nil {
+		return err
 	}
 
 	transactions, err := w.storage.GetRegularTransactions(w.ID)
@@ -61,7 +104,14 @@ func (w *Wallet) AddRegularTransaction(t RegularTransaction) error {
 		return errors.New(fmt.Sprintf("Label '%s' already exists", t.Label))
 	}
 
-	return w.storage.AddRegularTransaction(w.ID, t)
+	if t.ID == "" {
+		t.ID = newTransactionId()
+	}
+	if err := w.storage.AddRegularTransaction(w.ID, t); err != nil {
+		return err
+	}
+	w.journal(Operation{Type: OperationAddRegular, Regular: &t})
+	return nil
 }
 
 func (w *Wallet) GetCorrectedMonthlyIncome(t time.Time) (int, int, error) {
@@ -80,24 +130,27 @@ func (w *Wallet) GetCorrectedMonthlyIncome(t time.Time) (int, int, error) {
 		return 0, 0, err
 	}
 
-	_, t2 := calcCurMonthBorders(w.MonthStart, t)
-	monthlyIncome := w.calcMonthlyIncomeTillDate(*txs, t2)
-	dailyIncome := monthlyIncome / daysInMonth[t.Month()]
+	period := w.Period(t)
+	monthlyIncome := w.calcMonthlyIncomeTillDate(*txs, period.Last())
+	dailyIncome := monthlyIncome / period.Days()
 
 	return monthlyIncome, dailyIncome, nil
 }
 
-func (w *Wallet) GetPlannedMonthlyIncome() (int, error) {
-	log.Printf("Calculating planned monthly income for wallet '%s'", w.ID)
-	transactions, err := w.storage.GetRegularTransactions(w.ID)
-	if err != nil {
+// GetPlannedMonthlyIncome returns planned income for the period which t belongs to
+func (w *Wallet) GetPlannedMonthlyIncome(t time.Time) (int, error) {
+	log.Printf("Calculating planned monthly income for wallet '%s' for month with time %s", w.ID, t)
+	txs := newTransactionCollection()
+	if err := w.loadRegularTransactions(txs); err != nil {
 		log.Printf("Could not get monthly transactions for wallet '%s', error: %s", w.ID, err)
 		return 0, err
 	}
+	transactions := txs.getRegularTransactions()
 
+	period := w.Period(t)
 	totalIncome := 0
 	for _, change := range transactions {
-		totalIncome += change.Value
+		totalIncome += change.plannedInPeriod(period)
 	}
 
 	log.Printf("Total income for wallet '%s' is %d", w.ID, totalIncome)
@@ -105,13 +158,14 @@ func (w *Wallet) GetPlannedMonthlyIncome() (int, error) {
 	return totalIncome, nil
 }
 
-func checkRegularTransactionExactMatchExist(transactions []RegularTransaction, t_checked RegularTransaction) bool {
+// findRegularTransactionExactMatch returns stored transaction which matches t_checked by value, date and label
+func findRegularTransactionExactMatch(transactions []RegularTransaction, t_checked RegularTransaction) *RegularTransaction {
 	for _, t := range transactions {
-		if t == t_checked {
-			return true
+		if t.sameAs(t_checked) {
+			return &t
 		}
 	}
-	return false
+	return nil
 }
 
 func (w *Wallet) RemoveRegularTransaction(t RegularTransaction) error {
@@ -121,29 +175,42 @@ func (w *Wallet) RemoveRegularTransaction(t RegularTransaction) error {
 		return err
 	}
 
-	exists := checkRegularTransactionExactMatchExist(transactions, t)
-	if !exists {
+	stored := findRegularTransactionExactMatch(transactions, t)
+	if stored == nil {
 		log.Printf("There are no exactly matched regular transaction for wallet '%s', cannot remove regular transaction", w.ID)
-		return errors.New(fmt.Sprintf("Label '%s' already exists", t.Label))
+		return errors.New(fmt.Sprintf("No regular transaction %s labeled '%s' at %s", FormatAmount(t.Value, w.Decimals), t.Label, t.Schedule()))
 	}
 
-	return w.storage.RemoveRegularTransaction(w.ID, t)
+	if err := w.storage.RemoveRegularTransaction(w.ID, *stored); err != nil {
+		return err
+	}
+	w.journal(Operation{Type: OperationRemoveRegular, Regular: stored})
+	return nil
 }
 
-func calcCurMonthBorders(walletMonthStartDay int, now time.Time) (time.Time, time.Time) {
-	if walletMonthStartDay < 1 || walletMonthStartDay > 28 {
-		panic("Date must be between 1 and 28")
+// RetireFinishedRegularTransactions removes regular transactions which have no occurrences in the period of t or later
+// and returns the removed ones
+func (w *Wallet) RetireFinishedRegularTransactions(t time.Time) ([]RegularTransaction, error) {
+	transactions, err := w.storage.GetRegularTransactions(w.ID)
+	if err != nil {
+		log.Printf("Could not retire regular transactions - unable to get a list of all current regulars for wallet '%s'; error: %s", w.ID, err)
+		return nil, err
 	}
 
-	monthStart := time.Date(now.Year(), now.Month(), walletMonthStartDay, 0, 0, 0, 0, time.Local) // TODO: check whether UTC or Local is needed
-	if now.Day() < walletMonthStartDay {
-		// we've switched the month already, monthStart should be at the previous month
-		monthStart = monthStart.AddDate(0, -1, 0)
+	start := w.Period(t).Start
+	retired := make([]RegularTransaction, 0)
+	for _, tx := range transactions {
+		if !tx.Recurrence.EndedBefore(start) {
+			continue
+		}
+		if err := w.storage.RemoveRegularTransaction(w.ID, tx); err != nil {
+			log.Printf("Could not retire regular transaction '%s' of wallet '%s' due to error: %s", tx.ID, w.ID, err)
+			return retired, err
+		}
+		log.Printf("Regular transaction '%s' of wallet '%s' has ended on %s and has been retired", tx.ID, w.ID, tx.Recurrence.Until.Format("2006-01-02"))
+		retired = append(retired, tx)
 	}
-	monthEnd := monthStart.AddDate(0, 1, 0)
-	monthEnd = monthEnd.Add(time.Nanosecond * -1)
-	log.Printf("Month borders are from %s to %s", monthStart, monthEnd)
-	return monthStart, monthEnd
+	return retired, nil
 }
 
 func (w *Wallet) loadRegularTransactions(txs *transactionCollection) error {
@@ -151,6 +218,11 @@ func (w *Wallet) loadRegularTransactions(txs *transactionCollection) error {
 	if err != nil {
 		return err
 	}
+	for i := range transactions {
+		if err := w.convertToBaseCurrency(&transactions[i].Value, &transactions[i].Currency); err != nil {
+			return err
+		}
+	}
 	txs.regular_txs = transactions
 	log.Printf("Loaded %d regular transactions for wallet '%s'", len(txs.regular_txs), w.ID)
 	return nil
@@ -158,41 +230,38 @@ func (w *Wallet) loadRegularTransactions(txs *transactionCollection) error {
 
 func (w *Wallet) loadActualTransactionsForCurrentMonthTillDate(t time.Time, txs *transactionCollection) error {
 	// TODO: cache results of actual transactions so we don't need to call it again
-	t1, _ := calcCurMonthBorders(w.MonthStart, t)
-	transactions, err := w.storage.GetActualTransactions(w.ID, t1, t)
+	period := w.Period(t)
+	shifted := make(map[string]RegularTransaction, 0)
+	for _, regular := range txs.regular_txs {
+		if regular.Shift != ShiftNone {
+			shifted[regular.Label] = regular
+		}
+	}
+	from := period.Start
+	if len(shifted) > 0 {
+		// regular transactions moved to a business day might have been paid before the period start
+		from = from.AddDate(0, 0, -maxShiftDays)
+	}
+	transactions, err := w.storage.GetActualTransactions(w.ID, from, t)
 	if err != nil {
 		return err
 	}
-	txs.actual_txs = transactions
-	log.Printf("Loaded %d actual transactions for wallet '%s'", len(txs.actual_txs), w.ID)
-	return nil
-}
-
-func SplitWalletMonth(t time.Time, walletMonthStart int) (result struct {
-	DaysInCurMonth int
-	DaysSpent      int
-	DaysRemaining  int
-}) {
-	if walletMonthStart < 1 || walletMonthStart > 28 {
-		panic("Incorrect wallet month start")
-	}
-	// TODO: use calcCurMonthBorders() ?
-	curDay := t.Day()
-	if curDay >= walletMonthStart {
-		result.DaysInCurMonth = daysInMonth[t.Month()]
-		result.DaysSpent = curDay - walletMonthStart
-		result.DaysRemaining = result.DaysInCurMonth - result.DaysSpent
-	} else {
-		// tricky code to calc how many days have passed if we've reached the end of the previous month
-		prevMonth := time.December
-		if t.Month() != time.January {
-			prevMonth = t.Month() - 1
+	txs.actual_txs = make([]ActualTransaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if regular, found := shifted[tx.Label]; found {
+			if !regular.paidInPeriod(tx, period) {
+				continue
+			}
+		} else if tx.Time.Before(period.Start) {
+			continue
+		}
+		if err := w.convertToBaseCurrency(&tx.Value, &tx.Currency); err != nil {
+			return err
 		}
-		result.DaysInCurMonth = daysInMonth[prevMonth]
-		result.DaysRemaining = walletMonthStart - curDay + 1
-		result.DaysSpent = result.DaysInCurMonth - result.DaysRemaining
+		txs.actual_txs = append(txs.actual_txs, tx)
 	}
-	return
+	log.Printf("Loaded %d actual transactions for wallet '%s'", len(txs.actual_txs), w.ID)
+	return nil
 }
 
 func (w *Wallet) calcMonthlyIncomeTillDate(txs transactionCollection, t time.Time) int {
@@ -200,6 +269,7 @@ func (w *Wallet) calcMonthlyIncomeTillDate(txs transactionCollection, t time.Tim
 	// If there are actual transaction which match planned via labels, final result differs depending on income/expense and its value
 	regular_txs := txs.getRegularTransactions()
 	matched_actual_txs := txs.getMatchedActualTransactions()
+	period := w.Period(t)
 	totalMonthlyIncome := 0
 	// calculating regular depending on their matched transactions amount
 	for _, tx := range regular_txs {
@@ -212,7 +282,16 @@ func (w *Wallet) calcMonthlyIncomeTillDate(txs transactionCollection, t time.Tim
 				log.Printf("Mismatched signs of regular and actual values - regular: %d; actual: %d", tx.Value, matched_amount)
 				panic("Mismatched signs for regular and its matched actual counterpart")
 			}
-			if tx.Value > 0 {
+			if !tx.Recurrence.isDefault() {
+				// such transactions are spread over periods, so only difference between actual and planned payments matters
+				due := tx.Value * len(tx.occurrences(period.Start, period.End))
+				difference := matched_amount - due
+				if tx.Value < 0 && difference > 0 {
+					difference = 0 // same rule as for monthly expenses below: planned value is used until it is reached
+				}
+				log.Printf("Monthly income calc: for label #%s adding %d with difference %d: not monthly case", tx.Label, tx.plannedInPeriod(period), difference)
+				totalMonthlyIncome += tx.plannedInPeriod(period) + difference
+			} else if tx.Value > 0 {
 				// no special rule. Let's use the value we've found in matched tx as general recommendation for income is '1 planned -> 1 actual'
 				log.Printf("Monthly income calc: for label #%s adding %d: general income case", tx.Label, matched_amount)
 				totalMonthlyIncome += matched_amount
@@ -228,8 +307,8 @@ func (w *Wallet) calcMonthlyIncomeTillDate(txs transactionCollection, t time.Tim
 				}
 			}
 		} else { // we have no matched transactions
-			log.Printf("Monthly income calc: for label #%s adding %d: no matched actual", tx.Label, tx.Value)
-			totalMonthlyIncome += tx.Value
+			log.Printf("Monthly income calc: for label #%s adding %d: no matched actual", tx.Label, tx.plannedInPeriod(period))
+			totalMonthlyIncome += tx.plannedInPeriod(period)
 		}
 	}
 	log.Printf("Monthly income calc: after matching regular and actual total income equals to %d", totalMonthlyIncome)
@@ -244,8 +323,7 @@ func (w *Wallet) calcMonthlyIncomeTillDate(txs transactionCollection, t time.Tim
 	}
 	log.Printf("Monthly income calc: total income equals to %d", totalMonthlyIncome)
 	// calculating result based on how many days have passed considering whether we've reached the end of prev month
-	monthSplit := SplitWalletMonth(t, w.MonthStart)
-	result := float32(totalMonthlyIncome) / float32(monthSplit.DaysInCurMonth) * float32(monthSplit.DaysSpent+1) // + 1 as we also add a portion of money for current day (which is not in daysSpent)
+	result := float64(totalMonthlyIncome) / float64(period.Days()) * float64(period.DaysSpent(t)+1) // + 1 as we also add a portion of money for current day (which is not in daysSpent)
 
 	log.Printf("Monthly income calc: till date %s it equals to %f", t, result)
 	return int(result)
@@ -266,7 +344,18 @@ func (w *Wallet) calcUnmatchedExpenseSum(txs transactionCollection) int {
 	return sum
 }
 
+// GetBalance returns money available at time t including leftover carried from previous periods
 func (w *Wallet) GetBalance(t time.Time) (int, error) {
+	carry, toGoals, err := w.calcCarry(t)
+	if err != nil {
+		log.Printf("Unable to calculate leftover carried into current period for wallet '%s' for time %s", w.ID, t)
+		return 0, err
+	}
+	return w.calcBalance(t, carry.Carried, toGoals)
+}
+
+// calcBalance returns money available at time t given the amount carried into its period and surplus put aside for goals by names
+func (w *Wallet) calcBalance(t time.Time, carried int, toGoals map[string]int) (int, error) {
 	log.Printf("Starting to calculate available amount for wallet '%s' for time %s", w.ID, t)
 
 	txs := newTransactionCollection()
@@ -283,16 +372,26 @@ func (w *Wallet) GetBalance(t time.Time) (int, error) {
 		return 0, err
 	}
 
+	goals, err := w.calcGoalStatuses(*txs, t, toGoals)
+	if err != nil {
+		log.Printf("Unable to calculate money reserved for goals of wallet '%s' till date %s", w.ID, t)
+		return 0, err
+	}
+	reserved := 0
+	for _, g := range goals {
+		reserved += g.Reserved
+	}
+
 	curAvailIncome := w.calcMonthlyIncomeTillDate(*txs, t)
 	unmatchedTrxSum := w.calcUnmatchedExpenseSum(*txs)
-	availMoney := curAvailIncome + unmatchedTrxSum
-	log.Printf("Currently available money for wallet '%s': %d (matched with regular: %d; unmatched: %d)", w.ID, availMoney, curAvailIncome, unmatchedTrxSum)
+	availMoney := curAvailIncome + unmatchedTrxSum - reserved + carried
+	log.Printf("Currently available money for wallet '%s': %d (matched with regular: %d; unmatched: %d; reserved for goals: %d; carried: %d)", w.ID, availMoney, curAvailIncome, unmatchedTrxSum, reserved, carried)
 	return availMoney, nil
 }
 
 func (w *Wallet) SetMonthStart(date int) error {
-	if date < 1 || date > 28 {
-		panic("Date is out of range")
+	if err := ValidateMonthDay(date); err != nil {
+		return err
 	}
 	oldDate := w.MonthStart
 	w.MonthStart = date
@@ -300,24 +399,151 @@ func (w *Wallet) SetMonthStart(date int) error {
 	if err != nil {
 		log.Printf("Could not update wallet '%s' date from %d to %d. Reverting to original value", w.ID, oldDate, date)
 		w.MonthStart = oldDate
+		return err
+	}
+	w.journal(Operation{Type: OperationSetMonthStart, MonthStart: oldDate})
+	return nil
+}
+
+// SetCurrency changes base currency of the wallet. Transactions without explicit currency are considered to be in the new one
+func (w *Wallet) SetCurrency(c Currency) error {
+	if err := w.storage.SetWalletCurrency(w.ID, c); err != nil {
+		log.Printf("Could not update wallet '%s' currency to %s due to error: %s", w.ID, c, err)
+		return err
+	}
+	w.Currency = c
+	return nil
+}
+
+// SetDecimals changes number of decimals of the wallet rescaling all its amounts; reducing it rounds amounts.
+// Journal is dropped as its records keep amounts in previous scale
+func (w *Wallet) SetDecimals(decimals int) error {
+	if err := validateDecimals(decimals); err != nil {
+		return err
+	}
+	if decimals == w.Decimals {
+		return nil
+	}
+	log.Printf("Changing decimals of wallet '%s' from %d to %d", w.ID, w.Decimals, decimals)
+	if err := w.storage.RescaleWallet(w.ID, w.Decimals, decimals); err != nil {
+		log.Printf("Could not update wallet '%s' decimals to %d due to error: %s", w.ID, decimals, err)
+		return err
+	}
+	w.Decimals = decimals
+	return nil
+}
+
+// toBaseCurrency converts value in currency c into base currency of the wallet
+func (w *Wallet) toBaseCurrency(value int, c Currency) (int, error) {
+	if c == "" || c == w.Currency {
+		return value, nil
+	}
+	return convertCurrency(value, c, w.Currency)
+}
+
+func (w *Wallet) convertToBaseCurrency(value *int, c *Currency) error {
+	converted, err := w.toBaseCurrency(*value, *c)
+	if err != nil {
+		log.Printf("Could not convert %d %s into base currency of wallet '%s' due to error: %s", *value, *c, w.ID, err)
+		return err
 	}
-	return err
+	*value = converted
+	*c = w.Currency
+	return nil
+}
+
+// journal records operation so that it could be undone later. Failures are only logged as the operation itself has already succeeded
+func (w *Wallet) journal(op Operation) {
+	op.Time = time.Now()
+	if err := w.storage.PushOperation(w.ID, op); err != nil {
+		log.Printf("Could not add operation '%s' to journal of wallet '%s' due to error: %s", op.Type, w.ID, err)
+	}
+}
+
+// LastOperation returns the operation which Undo would revert; nil is returned if there is nothing to undo
+func (w *Wallet) LastOperation() (*Operation, error) {
+	op, err := w.storage.LastOperation(w.ID)
+	if err != nil {
+		log.Printf("Could not get latest operation of wallet '%s' due to error: %s", w.ID, err)
+	}
+	return op, err
+}
+
+// Undo reverts the latest operation from wallet's journal and returns it; nil is returned if there is nothing to undo.
+// The operation is removed from journal only after it has been reverted, so that a failed undo could be retried
+func (w *Wallet) Undo() (*Operation, error) {
+	op, err := w.LastOperation()
+	if err != nil {
+		return nil, err
+	}
+	if op == nil {
+		log.Printf("Journal of wallet '%s' is empty, nothing to undo", w.ID)
+		return nil, nil
+	}
+
+	log.Printf("Undoing operation '%s' of wallet '%s' made at %s", op.Type, w.ID, op.Time)
+	switch op.Type {
+	case OperationAddActual:
+		err = w.storage.RemoveActualTransaction(w.ID, op.Actual.ID)
+	case OperationAddRegular:
+		err = w.storage.RemoveRegularTransaction(w.ID, *op.Regular)
+	case OperationRemoveRegular:
+		err = w.storage.AddRegularTransaction(w.ID, *op.Regular)
+	case OperationSetMonthStart:
+		err = w.storage.SetWalletInfo(w.ID, op.MonthStart)
+		if err == nil {
+			w.MonthStart = op.MonthStart
+		}
+	default:
+		err = fmt.Errorf("Unknown operation '%s'", op.Type)
+	}
+	if err != nil {
+		log.Printf("Could not undo operation '%s' of wallet '%s' due to error: %s", op.Type, w.ID, err)
+		return nil, err
+	}
+	if err := w.storage.DropOperation(w.ID, *op); err != nil {
+		log.Printf("Operation '%s' of wallet '%s' has been undone, but it stays in journal due to error: %s", op.Type, w.ID, err)
+		return nil, err
+	}
+	return op, nil
 }
 
 func (w *Wallet) GetMonthlySummary(t time.Time) (*TransactionSummary, error) {
-	t1, t2 := calcCurMonthBorders(w.MonthStart, t)
+	period := w.Period(t)
 	txs := newTransactionCollection()
-	err := w.loadActualTransactionsForCurrentMonthTillDate(t2, txs)
+	err := w.loadActualTransactionsForCurrentMonthTillDate(period.Last(), txs)
 	if err != nil {
-		log.Printf("Could not collect transactions for summary for wallet '%s' for month associated with date %s; error: %s", w.ID, t2, err)
+		log.Printf("Could not collect transactions for summary for wallet '%s' for month associated with date %s; error: %s", w.ID, period.Last(), err)
 		return nil, err
 	}
 
-	summary := NewTransactionSummary(t1, t2)
+	summary := NewTransactionSummary(period.Start, period.Last())
 
-	for _, tx := range txs.getActualExpenseTransactions() {
-		summary.ExpenseSummary[tx.Label] += tx.Value
+	for _, tx := range txs.getActualTransactions() {
+		summary.Add(tx)
 	}
 
 	return summary, nil
 }
+
+// GetSummary aggregates actual transactions made from tMin till tMax inclusive regardless of budget periods
+func (w *Wallet) GetSummary(tMin, tMax time.Time) (*TransactionSummary, error) {
+	if tMax.Before(tMin) {
+		return nil, errors.New("End of the range should not be before its start")
+	}
+	transactions, err := w.storage.GetActualTransactions(w.ID, tMin, tMax)
+	if err != nil {
+		log.Printf("Could not collect transactions for summary for wallet '%s' from %s till %s; error: %s", w.ID, tMin, tMax, err)
+		return nil, err
+	}
+
+	summary := NewTransactionSummary(tMin, tMax)
+	for _, tx := range transactions {
+		if err := w.convertToBaseCurrency(&tx.Value, &tx.Currency); err != nil {
+			return nil, err
+		}
+		summary.Add(tx)
+	}
+	log.Printf("Summary of wallet '%s' from %s till %s contains %d transactions", w.ID, tMin, tMax, len(transactions))
+	return summary, nil
+}
@@ -1,396 +1,671 @@
 package budget
 
-/*
 import "testing"
 import "strconv"
 import "time"
 import "math/rand"
 
 func TestEmptyWalletPlannedIncome(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-    val, err := w.GetPlannedMonthlyIncome()
-    if err != nil {
-        t.FailNow()
-    }
-    if val != 0 {
-        t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	val, err := w.GetPlannedMonthlyIncome(time.Now())
+	if err != nil {
+		t.FailNow()
+	}
+	if val != 0 {
+		t.FailNow()
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeOnly_NoActualCorrelation(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    incomeN := 1 + rand.Int() % 5
-    totalPlanned := 0
-    for i := 0; i < incomeN; i++ {
-        val := 1000 + rand.Int() % 10000
-        totalPlanned += val
-        regular := NewRegularTransaction(val, i + 1, strconv.Itoa(i + 1))
-        err := w.AddRegularTransaction(*regular)
-        if err != nil {
-            t.FailNow()
-        }
-    }
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-        t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	incomeN := 1 + rand.Int()%5
+	totalPlanned := 0
+	for i := 0; i < incomeN; i++ {
+		val := 1000 + rand.Int()%10000
+		totalPlanned += val
+		regular := NewRegularTransaction(val, i+1, strconv.Itoa(i+1))
+		err := w.AddRegularTransaction(*regular)
+		if err != nil {
+			t.FailNow()
+		}
+	}
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeAndExpense_NoActualCorrelation(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    transactionsN := 1 + rand.Int() % 5
-    totalPlanned := 0
-    for i := 0; i < transactionsN; i++ {
-        val_pos := 1000 + rand.Int() % 10000
-        totalPlanned += val_pos
-        regular_pos := NewRegularTransaction(val_pos, i + 1, strconv.Itoa(i + 1) + "pos")
-        err := w.AddRegularTransaction(*regular_pos)
-        if err != nil {
-            t.FailNow()
-        }
-
-        val_neg := -1 * (1000 + rand.Int() % 10000)
-        totalPlanned += val_neg
-        regular_neg := NewRegularTransaction(val_neg, i + 1, strconv.Itoa(i + 1) + "neg")
-        err = w.AddRegularTransaction(*regular_neg)
-        if err != nil {
-            t.FailNow()
-        }
-    }
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-        t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	transactionsN := 1 + rand.Int()%5
+	totalPlanned := 0
+	for i := 0; i < transactionsN; i++ {
+		val_pos := 1000 + rand.Int()%10000
+		totalPlanned += val_pos
+		regular_pos := NewRegularTransaction(val_pos, i+1, strconv.Itoa(i+1)+"pos")
+		err := w.AddRegularTransaction(*regular_pos)
+		if err != nil {
+			t.FailNow()
+		}
+
+		val_neg := -1 * (1000 + rand.Int()%10000)
+		totalPlanned += val_neg
+		regular_neg := NewRegularTransaction(val_neg, i+1, strconv.Itoa(i+1)+"neg")
+		err = w.AddRegularTransaction(*regular_neg)
+		if err != nil {
+			t.FailNow()
+		}
+	}
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeAndExpense_FullActualCorrelation(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := trRegPos.Value + trRegNeg.Value
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    trActualPos := NewActualTransaction(8800, time.Now(), "pos1", "")
-    trActualNeg := NewActualTransaction(-1200, time.Now(), "neg1", "")
-    totalActual := trActualPos.Value + trActualNeg.Value
-
-    if w.AddTransaction(*trActualPos) != nil ||
-       w.AddTransaction(*trActualNeg) != nil {
-           t.FailNow()
-       }
-
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-        t.FailNow()
-    }
-    if val, err := w.GetActualMonthlyIncome(); val != totalActual || err != nil {
-        t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := trRegPos.Value + trRegNeg.Value
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 20, 12, 0, 0, 0, time.UTC)
+	trActualPos := NewActualTransaction(9000, t1, "pos1", "")
+	trActualNeg := NewActualTransaction(-1200, t1, "neg1", "")
+	// actual expense is less than planned, so planned value is still used
+	totalActual := trActualPos.Value + trRegNeg.Value
+
+	if _, err := w.AddTransaction(*trActualPos); err != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*trActualNeg); err != nil {
+		t.FailNow()
+	}
+
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
+	if val, _, err := w.GetCorrectedMonthlyIncome(t1); val != totalActual || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, totalActual)
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeAndExpense_PartialActualCorrelation(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := trRegPos.Value + trRegNeg.Value
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    trActualPos := NewActualTransaction(8800, time.Now(), "pos1", "")
-    totalActual := trActualPos.Value + trRegNeg.Value
-    if w.AddTransaction(*trActualPos) != nil {
-           t.FailNow()
-       }
-
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-       t.FailNow()
-    }
-    if val, err := w.GetActualMonthlyIncome(); val != totalActual || err != nil {
-       t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := trRegPos.Value + trRegNeg.Value
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 20, 12, 0, 0, 0, time.UTC)
+	trActualPos := NewActualTransaction(9000, t1, "pos1", "")
+	totalActual := trActualPos.Value + trRegNeg.Value
+	if _, err := w.AddTransaction(*trActualPos); err != nil {
+		t.FailNow()
+	}
+
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
+	if val, _, err := w.GetCorrectedMonthlyIncome(t1); val != totalActual || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, totalActual)
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeAndExpense_FullActualCorrelation_AdditionalIncomeExpenseSameLabel(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := trRegPos.Value + trRegNeg.Value
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    trActualPos1 := NewActualTransaction(8800, time.Now(), "pos1", "")
-    trActualPos2 := NewActualTransaction(500, time.Now(), "pos1", "")
-    trActualNeg1 := NewActualTransaction(-1200, time.Now(), "neg1", "")
-    trActualNeg2 := NewActualTransaction(-200, time.Now(), "neg1", "")
-    totalActual := trActualPos1.Value + trActualPos2.Value + trActualNeg1.Value + trActualNeg2.Value
-
-    if w.AddTransaction(*trActualPos1) != nil ||
-       w.AddTransaction(*trActualPos2) != nil ||
-       w.AddTransaction(*trActualNeg1) != nil ||
-       w.AddTransaction(*trActualNeg2) != nil {
-           t.FailNow()
-       }
-
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-      t.FailNow()
-    }
-    if val, err := w.GetActualMonthlyIncome(); val != totalActual || err != nil {
-      t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := trRegPos.Value + trRegNeg.Value
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 20, 12, 0, 0, 0, time.UTC)
+	trActualPos1 := NewActualTransaction(8800, t1, "pos1", "")
+	trActualPos2 := NewActualTransaction(500, t1, "pos1", "")
+	trActualNeg1 := NewActualTransaction(-1200, t1, "neg1", "")
+	trActualNeg2 := NewActualTransaction(-200, t1, "neg1", "")
+	totalActual := trActualPos1.Value + trActualPos2.Value + trRegNeg.Value // actual expenses sum is still less than planned
+
+	for _, tx := range []*ActualTransaction{trActualPos1, trActualPos2, trActualNeg1, trActualNeg2} {
+		if _, err := w.AddTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
+	if val, _, err := w.GetCorrectedMonthlyIncome(t1); val != totalActual || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, totalActual)
+	}
 }
 
 func TestPlannedMonthlyIncome_IncomeAndExpense_FullActualCorrelation_AdditionalIncomeOtherLabel(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := trRegPos.Value + trRegNeg.Value
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    trActualPos1 := NewActualTransaction(8800, time.Now(), "pos1", "")
-    trActualPos2 := NewActualTransaction(500, time.Now(), "pos2", "")
-    trActualNeg1 := NewActualTransaction(-1200, time.Now(), "neg1", "")
-    trActualNeg2 := NewActualTransaction(-200, time.Now(), "neg2", "")
-    totalActual := trActualPos1.Value + trActualPos2.Value + trActualNeg1.Value // trActualNeg2.Value is not used for income calc
-
-    if w.AddTransaction(*trActualPos1) != nil ||
-       w.AddTransaction(*trActualPos2) != nil ||
-       w.AddTransaction(*trActualNeg1) != nil ||
-       w.AddTransaction(*trActualNeg2) != nil {
-           t.FailNow()
-       }
-
-    if val, err := w.GetPlannedMonthlyIncome(); val != totalPlanned || err != nil {
-        t.FailNow()
-    }
-    if val, err := w.GetActualMonthlyIncome(); val != totalActual || err != nil {
-        t.FailNow()
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := trRegPos.Value + trRegNeg.Value
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 6, 20, 12, 0, 0, 0, time.UTC)
+	trActualPos1 := NewActualTransaction(8800, t1, "pos1", "")
+	trActualPos2 := NewActualTransaction(500, t1, "pos2", "")
+	trActualNeg1 := NewActualTransaction(-1200, t1, "neg1", "")
+	trActualNeg2 := NewActualTransaction(-200, t1, "neg2", "")
+	totalActual := trActualPos1.Value + trActualPos2.Value + trRegNeg.Value // trActualNeg2.Value is not used for income calc
+
+	for _, tx := range []*ActualTransaction{trActualPos1, trActualPos2, trActualNeg1, trActualNeg2} {
+		if _, err := w.AddTransaction(*tx); err != nil {
+			t.FailNow()
+		}
+	}
+
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != totalPlanned || err != nil {
+		t.FailNow()
+	}
+	if val, _, err := w.GetCorrectedMonthlyIncome(t1); val != totalActual || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, totalActual)
+	}
 }
 
-func TestAvailableAmount_NoTransactions(t * testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    val, err := w.GetActualMonthlyIncomeTillDate(time.Now())
-    if err != nil {
-        t.FailNow()
-    }
-    if val != 0 {
-        t.Error(val)
-    }
+func TestAvailableAmount_NoTransactions(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	val, err := w.GetBalance(time.Now())
+	if err != nil {
+		t.FailNow()
+	}
+	if val != 0 {
+		t.Error(val)
+	}
 }
 
 func TestAvailableAmount_OnlyRegularTransactions(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(1))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := trRegPos.Value + trRegNeg.Value
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    t.Log("HERE STARTS a test for 31 days")
-    t_days31 := time.Date(2018, 1, 10, 0, 0, 0, 0, time.UTC)
-    val31, err := w.GetActualMonthlyIncomeTillDate(t_days31)
-    if err != nil {
-        t.FailNow()
-    }
-    expected_val31 := int(float32(totalPlanned) / 31 * 10) // 10 days from 1 (default monthStart) to t_days31
-    if val31 != expected_val31 {
-        t.Errorf("31 days: actual=%d; expected=%d", val31, expected_val31)
-    }
-
-    t.Log("HERE STARTS a test for 30 days")
-    t_days30 := time.Date(2018, 4, 3, 0, 0, 0, 0, time.UTC)
-    val30, err := w.GetActualMonthlyIncomeTillDate(t_days30)
-    if err != nil {
-        t.FailNow()
-    }
-    expected_val30 := int(float32(totalPlanned) / 30 * 3)
-    if val30 != expected_val30 {
-        t.Errorf("30 days: actual=%d; expected=%d", val30, expected_val30)
-    }
-
-    t.Log("HERE STARTS a test for 28 days")
-    t_days28 := time.Date(2018, 2, 20, 0, 0, 0, 0, time.UTC)
-    val28, err := w.GetActualMonthlyIncomeTillDate(t_days28)
-    if err != nil {
-        t.FailNow()
-    }
-    expected_val28 := int(float32(totalPlanned) / 28 * 20)
-    if val28 != expected_val28 {
-        t.Errorf("28 days: actual=%d; expected=%d", val28, expected_val28)
-    }
-
-    // TODO: correct this test when leap year is handled correctly
-    t.Log("HERE STARTS a test for 29 (leap year) days")
-    t_days29 := time.Date(2004, 2, 20, 0, 0, 0, 0, time.UTC)
-    val29, err := w.GetActualMonthlyIncomeTillDate(t_days29)
-    if err != nil {
-        t.FailNow()
-    }
-    expected_val29 := expected_val28 // to be corrected when leap year is handled correctly
-    if val29 != expected_val29 {
-        t.Errorf("29 days: actual=%d; expected=%d", val29, expected_val29)
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := trRegPos.Value + trRegNeg.Value
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t.Log("HERE STARTS a test for 31 days")
+	t_days31 := time.Date(2018, 1, 10, 0, 0, 0, 0, time.UTC)
+	val31, err := w.GetBalance(t_days31)
+	if err != nil {
+		t.FailNow()
+	}
+	expected_val31 := int(float32(totalPlanned) / 31 * 10) // 10 days from 1 (default monthStart) to t_days31
+	if val31 != expected_val31 {
+		t.Errorf("31 days: actual=%d; expected=%d", val31, expected_val31)
+	}
+
+	t.Log("HERE STARTS a test for 30 days")
+	t_days30 := time.Date(2018, 4, 3, 0, 0, 0, 0, time.UTC)
+	val30, err := w.GetBalance(t_days30)
+	if err != nil {
+		t.FailNow()
+	}
+	expected_val30 := int(float32(totalPlanned) / 30 * 3)
+	if val30 != expected_val30 {
+		t.Errorf("30 days: actual=%d; expected=%d", val30, expected_val30)
+	}
+
+	t.Log("HERE STARTS a test for 28 days")
+	t_days28 := time.Date(2018, 2, 20, 0, 0, 0, 0, time.UTC)
+	val28, err := w.GetBalance(t_days28)
+	if err != nil {
+		t.FailNow()
+	}
+	expected_val28 := int(float32(totalPlanned) / 28 * 20)
+	if val28 != expected_val28 {
+		t.Errorf("28 days: actual=%d; expected=%d", val28, expected_val28)
+	}
+
+	t.Log("HERE STARTS a test for 29 (leap year) days")
+	t_days29 := time.Date(2004, 2, 20, 0, 0, 0, 0, time.UTC)
+	val29, err := w.GetBalance(t_days29)
+	if err != nil {
+		t.FailNow()
+	}
+	expected_val29 := int(float32(totalPlanned) / 29 * 20)
+	if val29 != expected_val29 {
+		t.Errorf("29 days: actual=%d; expected=%d", val29, expected_val29)
+	}
 }
 
 func TestAvailableAmount_RegularThenActual(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(time.Now().Unix()))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(10000, 1, "pos1")
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned := float32(trRegPos.Value + trRegNeg.Value)
-
-    if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
-    var daysInJune float32 = 30
-    trActual1 := NewActualTransaction(-500, t1, "food", "")
-    if w.AddTransaction(*trActual1) != nil {
-        t.FailNow()
-    }
-
-    tBefore := t1.Add(time.Duration(time.Hour * (-5)))
-    valBefore, err := w.GetBalance(tBefore)
-    if err != nil {
-        t.FailNow()
-    }
-    expectedValBefore := int(totalPlanned / daysInJune * float32(tBefore.Day()))
-    if valBefore != expectedValBefore {
-        t.Errorf("BEFORE mismatch: actual=%d; expected=%d", valBefore, expectedValBefore)
-    }
-
-    tAfter := t1.Add(time.Duration(time.Hour * 2))
-    valAfter, err := w.GetBalance(tAfter)
-    if err != nil {
-        t.FailNow()
-    }
-    expectedValAfter := int(totalPlanned / daysInJune * float32(tAfter.Day())) - 500
-    if valAfter != expectedValAfter {
-        t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
-    }
-
-    tExact := t1
-    valExactTime, err := w.GetBalance(tExact)
-    if err != nil {
-        t.FailNow()
-    }
-    expectedValExact := expectedValAfter
-    if valExactTime != expectedValExact {
-        t.Errorf("EXACT mismatch: actual=%d; expected=%d", valExactTime, expectedValExact)
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(time.Now().Unix()))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(10000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned := float32(trRegPos.Value + trRegNeg.Value)
+
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	var daysInJune float32 = 30
+	trActual1 := NewActualTransaction(-500, t1, "food", "")
+	if _, err := w.AddTransaction(*trActual1); err != nil {
+		t.FailNow()
+	}
+
+	tBefore := t1.Add(time.Duration(time.Hour * (-5)))
+	valBefore, err := w.GetBalance(tBefore)
+	if err != nil {
+		t.FailNow()
+	}
+	expectedValBefore := int(totalPlanned / daysInJune * float32(tBefore.Day()))
+	if valBefore != expectedValBefore {
+		t.Errorf("BEFORE mismatch: actual=%d; expected=%d", valBefore, expectedValBefore)
+	}
+
+	tAfter := t1.Add(time.Duration(time.Hour * 2))
+	valAfter, err := w.GetBalance(tAfter)
+	if err != nil {
+		t.FailNow()
+	}
+	expectedValAfter := int(totalPlanned/daysInJune*float32(tAfter.Day())) - 500
+	if valAfter != expectedValAfter {
+		t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
+	}
+
+	tExact := t1
+	valExactTime, err := w.GetBalance(tExact)
+	if err != nil {
+		t.FailNow()
+	}
+	expectedValExact := expectedValAfter
+	if valExactTime != expectedValExact {
+		t.Errorf("EXACT mismatch: actual=%d; expected=%d", valExactTime, expectedValExact)
+	}
 }
 
 func TestAvailableAmount_CorrectionAfterNewRegular(t *testing.T) {
-    s := NewRamStorage()
-    w, err := s.CreateWalletOwner(OwnerId(time.Now().Unix()))
-    if err != nil {
-        t.FailNow()
-    }
-
-    trRegPos := NewRegularTransaction(3000, 1, "pos1")
-    totalPlanned := float32(trRegPos.Value)
-
-    if w.AddRegularTransaction(*trRegPos) != nil {
-        t.FailNow()
-    }
-
-    t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
-    var daysInJune float32 = 30
-    trActual1 := NewActualTransaction(-800, t1, "food", "")
-    if w.AddTransaction(*trActual1) != nil {
-        t.FailNow()
-    }
-
-    tAfter := t1.Add(time.Duration(time.Hour * 2))
-    valAfter, err := w.GetBalance(tAfter)
-    if err != nil {
-        t.FailNow()
-    }
-    expectedValAfter := int(totalPlanned / daysInJune * float32(tAfter.Day())) + trActual1.Value
-    if valAfter != expectedValAfter {
-        t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
-    }
-
-    trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
-    totalPlanned += float32(trRegNeg.Value)
-
-    if w.AddRegularTransaction(*trRegNeg) != nil {
-        t.FailNow()
-    }
-
-    valAfter, err = w.GetBalance(tAfter)
-    if err != nil {
-        t.FailNow()
-    }
-    expectedValAfter = int(totalPlanned / daysInJune * float32(tAfter.Day())) + trActual1.Value
-    if valAfter != expectedValAfter {
-        t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
-    }
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(time.Now().Unix()))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(3000, 1, "pos1")
+	totalPlanned := float32(trRegPos.Value)
+
+	if w.AddRegularTransaction(*trRegPos) != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	var daysInJune float32 = 30
+	trActual1 := NewActualTransaction(-800, t1, "food", "")
+	if _, err := w.AddTransaction(*trActual1); err != nil {
+		t.FailNow()
+	}
+
+	tAfter := t1.Add(time.Duration(time.Hour * 2))
+	valAfter, err := w.GetBalance(tAfter)
+	if err != nil {
+		t.FailNow()
+	}
+	expectedValAfter := int(totalPlanned/daysInJune*float32(tAfter.Day())) + trActual1.Value
+	if valAfter != expectedValAfter {
+		t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
+	}
+
+	trRegNeg := NewRegularTransaction(-3300, 5, "neg1")
+	totalPlanned += float32(trRegNeg.Value)
+
+	if w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	valAfter, err = w.GetBalance(tAfter)
+	if err != nil {
+		t.FailNow()
+	}
+	expectedValAfter = int(totalPlanned/daysInJune*float32(tAfter.Day())) + trActual1.Value
+	if valAfter != expectedValAfter {
+		t.Errorf("AFTER mismatch: actual=%d; expected=%d", valAfter, expectedValAfter)
+	}
+}
+
+func TestAvailableAmount_RemovedRegular(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(3000, 1, "pos1")
+	trRegNeg := NewRegularTransaction(-1500, 5, "neg1")
+	if w.AddRegularTransaction(*trRegPos) != nil || w.AddRegularTransaction(*trRegNeg) != nil {
+		t.FailNow()
+	}
+
+	if err := w.RemoveRegularTransaction(*trRegNeg); err != nil {
+		t.Errorf("Could not remove regular transaction: %s", err)
+	}
+	if err := w.RemoveRegularTransaction(*trRegNeg); err == nil {
+		t.Errorf("Regular transaction has been removed twice")
+	}
+	if val, err := w.GetPlannedMonthlyIncome(time.Now()); val != trRegPos.Value || err != nil {
+		t.Errorf("actual=%d; expected=%d", val, trRegPos.Value)
+	}
+}
+
+func TestAvailableAmount_ModifiedActual(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	typo := NewActualTransaction(-500, t1, "food", "500 #food")
+	if _, err := w.AddTransaction(*typo); err != nil {
+		t.FailNow()
+	}
+
+	fixed := *typo
+	fixed.Value = -50
+	if _, err := w.UpdateActualTransaction(fixed); err != nil {
+		t.Errorf("Could not update actual transaction: %s", err)
+	}
+	if val, err := w.GetBalance(t1); val != -50 || err != nil {
+		t.Errorf("After update: actual=%d; expected=%d", val, -50)
+	}
+
+	if err := w.RemoveActualTransaction(typo.ID); err != nil {
+		t.Errorf("Could not remove actual transaction: %s", err)
+	}
+	if val, err := w.GetBalance(t1); val != 0 || err != nil {
+		t.Errorf("After removal: actual=%d; expected=%d", val, 0)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if op, err := w.Undo(); op != nil || err != nil {
+		t.Errorf("Nothing must be undone in a new wallet, got %+v", op)
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	salary := NewRegularTransaction(3000, 1, "salary")
+	rent := NewRegularTransaction(-1500, 5, "rent")
+	if w.AddRegularTransaction(*salary) != nil || w.AddRegularTransaction(*rent) != nil || w.RemoveRegularTransaction(*rent) != nil {
+		t.FailNow()
+	}
+	if _, err := w.AddTransaction(*NewActualTransaction(-500, t1, "", "500")); err != nil {
+		t.FailNow()
+	}
+	if w.SetMonthStart(20) != nil {
+		t.FailNow()
+	}
+
+	expectedUndo := []OperationType{OperationSetMonthStart, OperationAddActual, OperationRemoveRegular, OperationAddRegular, OperationAddRegular}
+	expectedBalance := []int{2000 - 500, 2000, 1000, 2000, 0} // balance after each undo; June 20th is the 20th day of 30
+	for i, expected := range expectedUndo {
+		op, err := w.Undo()
+		if err != nil || op == nil || op.Type != expected {
+			t.Fatalf("Step %d: undone %+v instead of '%s' (error: %v)", i, op, expected, err)
+		}
+		if i == 0 && w.MonthStart != defaultMonthStart {
+			t.Errorf("Month start has not been reverted: %d", w.MonthStart)
+		}
+		if val, err := w.GetBalance(t1); val != expectedBalance[i] || err != nil {
+			t.Errorf("Step %d: actual=%d; expected=%d", i, val, expectedBalance[i])
+		}
+	}
+	if op, err := w.Undo(); op != nil || err != nil {
+		t.Errorf("Journal must be empty, got %+v", op)
+	}
+
+	// operation which could not be reverted stays in journal
+	missing := NewActualTransaction(-100, t1, "", "100")
+	if s.PushOperation(w.ID, Operation{Type: OperationAddActual, Time: t1, Actual: missing}) != nil {
+		t.FailNow()
+	}
+	if op, err := w.Undo(); err == nil {
+		t.Errorf("Removal of unknown transaction has been undone: %+v", op)
+	}
+	if op, err := w.LastOperation(); err != nil || op == nil || op.Actual.ID != missing.ID {
+		t.Errorf("Failed operation has been removed from journal, latest is %+v", op)
+	}
+}
+
+func TestAvailableAmount_ModifiedMonthStart(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+
+	trRegPos := NewRegularTransaction(3000, 10, "pos1")
+	if w.AddRegularTransaction(*trRegPos) != nil {
+		t.FailNow()
+	}
+	if w.SetMonthStart(10) != nil {
+		t.FailNow()
+	}
+
+	// month start should be kept by storage
+	w, err = s.GetWalletForOwner(OwnerId(1), false)
+	if err != nil || w.MonthStart != 10 {
+		t.FailNow()
+	}
+
+	t1 := time.Date(2018, 06, 20, 0, 0, 0, 0, time.UTC)
+	val, err := w.GetBalance(t1)
+	if err != nil {
+		t.FailNow()
+	}
+	expected := int(float32(trRegPos.Value) / 30 * 11) // from 10th till 20th inclusive
+	if val != expected {
+		t.Errorf("actual=%d; expected=%d", val, expected)
+	}
 }
 
 func TestAvailableAmount_CorrectionAfterNewRegularWithLabelMatch(t *testing.T) {
-    t.Skip("TODO: impleminent (add new regular transaction after actual, and new regular matches label of an actual transaction)")
+	t.Skip("TODO: impleminent (add new regular transaction after actual, and new regular matches label of an actual transaction)")
 }
 
 func TestAvailableAmount_ModifiedMonthStart_January(t *testing.T) {
-    t.Skip("TODO: implement. There's a hack for january, try monthStart 10, transactions and get amount on dates 1-10")
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	if w.AddRegularTransaction(*NewRegularTransaction(3100, 10, "pos1")) != nil || w.SetMonthStart(10) != nil {
+		t.FailNow()
+	}
+
+	for day := 1; day < 10; day++ {
+		t1 := time.Date(2019, 1, day, 12, 0, 0, 0, time.UTC)
+		val, err := w.GetBalance(t1)
+		if err != nil {
+			t.FailNow()
+		}
+		expected := 100 * (22 + day) // 31 days from December 10th, 22 of them are in December
+		if val != expected {
+			t.Errorf("January %d: actual=%d; expected=%d", day, val, expected)
+		}
+	}
+}
+
+func TestSetDecimals(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	if _, err := w.AddTransaction(*NewActualTransaction(-1250, t1, "", "12.50")); err != nil {
+		t.FailNow()
+	}
+	if err := w.AddRegularTransaction(*NewRegularTransaction(-1251, 5, "rent")); err != nil {
+		t.FailNow()
+	}
+
+	if err := w.SetDecimals(maxDecimals + 1); err == nil {
+		t.Errorf("Too many decimals have been accepted")
+	}
+	if err := w.SetDecimals(0); err != nil {
+		t.Fatalf("Decimals have not been changed: %s", err)
+	}
+	if actual, _ := s.GetActualTransactions(w.ID, t1, t1); len(actual) != 1 || actual[0].Value != -13 {
+		t.Errorf("Actual transaction has not been rescaled: %+v", actual)
+	}
+	if regular, _ := s.GetRegularTransactions(w.ID); len(regular) != 1 || regular[0].Value != -13 {
+		t.Errorf("Regular transaction has not been rescaled: %+v", regular)
+	}
+	if op, _ := s.PopOperation(w.ID); op != nil {
+		t.Errorf("Journal must be dropped after rescaling, got %+v", op)
+	}
+	if stored, _ := s.GetWalletForOwner(OwnerId(1), false); stored.Decimals != 0 {
+		t.Errorf("Decimals have not been stored: %+v", stored)
+	}
+
+	if err := w.SetDecimals(2); err != nil {
+		t.FailNow()
+	}
+	if actual, _ := s.GetActualTransactions(w.ID, t1, t1); len(actual) != 1 || actual[0].Value != -1300 {
+		t.Errorf("Actual transaction has not been rescaled back: %+v", actual)
+	}
+}
+
+func TestMonthlySummary_Members(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(-100))
+	if err != nil {
+		t.FailNow()
+	}
+	t1 := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	for _, tx := range []struct {
+		value, user int
+	}{{-100, 1}, {-50, 2}, {-20, 1}, {-7, 0}, {300, 2}} {
+		actual := NewActualTransaction(tx.value, t1, "", "")
+		actual.UserID = tx.user
+		if _, err := w.AddTransaction(*actual); err != nil {
+			t.FailNow()
+		}
+	}
+
+	summary, err := w.GetMonthlySummary(t1)
+	if err != nil {
+		t.Fatalf("Summary has not been prepared: %s", err)
+	}
+	expected := map[int]int{1: -120, 2: -50, 0: -7}
+	if len(summary.MemberExpenseSummary) != len(expected) {
+		t.Errorf("Unexpected expenses by members: %v", summary.MemberExpenseSummary)
+	}
+	for user, value := range expected {
+		if summary.MemberExpenseSummary[user] != value {
+			t.Errorf("User %d spent %d instead of %d", user, summary.MemberExpenseSummary[user], value)
+		}
+	}
+}
+
+func TestSummary_Range(t *testing.T) {
+	s := NewRamStorage()
+	w, err := s.CreateWalletOwner(OwnerId(1))
+	if err != nil {
+		t.FailNow()
+	}
+	for i, tx := range []struct {
+		value int
+		label string
+	}{{3100, "salary"}, {-100, "food"}, {-700, "rent"}, {-50, "food"}, {-300, ""}, {-20, "food"}, {-400, "taxi"}, {-10, "food"}, {200, ""}} {
+		if _, err := w.AddTransaction(*NewActualTransaction(tx.value, time.Date(2026, 1, 1+i*5, 12, 0, 0, 0, time.UTC), tx.label, "")); err != nil {
+			t.FailNow()
+		}
+	}
+	// the last transaction of February 10 is out of range
+	summary, err := w.GetSummary(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 5, 23, 59, 59, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Summary has not been prepared: %s", err)
+	}
+	if summary.TotalIncome() != 3100 || summary.TotalExpense() != -1580 || summary.IncomeCount["salary"] != 1 {
+		t.Errorf("Unexpected totals: income %d, expense %d", summary.TotalIncome(), summary.TotalExpense())
+	}
+	if summary.ExpenseSummary["food"] != -180 || summary.ExpenseCount["food"] != 4 || summary.ExpenseCount[""] != 1 {
+		t.Errorf("Unexpected expenses by labels: %v, counts %v", summary.ExpenseSummary, summary.ExpenseCount)
+	}
+	if summary.Days() != 36 || summary.AverageDailyExpense() != 1580/36 {
+		t.Errorf("Unexpected average daily expense %d over %d days", summary.AverageDailyExpense(), summary.Days())
+	}
+	largest := make([]int, 0, len(summary.Largest))
+	for _, tx := range summary.Largest {
+		largest = append(largest, tx.Value)
+	}
+	if len(largest) != summaryLargestLimit || largest[0] != -700 || largest[1] != -400 || largest[4] != -50 {
+		t.Errorf("Unexpected largest expenses %v", largest)
+	}
+
+	if _, err := w.GetSummary(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Errorf("Summary for reversed range has been prepared")
+	}
 }
-*/